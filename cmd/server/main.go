@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -13,8 +14,8 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/konstantin/dmn-engine-go/internal/api"
 	"github.com/konstantin/dmn-engine-go/internal/config"
-	"github.com/konstantin/dmn-engine-go/internal/engine"
 	"github.com/konstantin/dmn-engine-go/internal/storage"
+	"github.com/konstantin/dmn-engine-go/pkg/dmn"
 )
 
 func main() {
@@ -64,14 +65,41 @@ func main() {
 	// Repository
 	repo := storage.NewPostgresRepository(pool)
 
-	// Engine
-	engine := &EngineAdapter{repo: repo}
+	// Evaluation history
+	cleanupCtx, stopCleanup := context.WithCancel(ctx)
+	defer stopCleanup()
+
+	var history storage.HistoryRepository
+	if cfg.History.Enabled {
+		history = storage.NewPostgresHistoryRepository(pool)
+		if cfg.History.Retention > 0 {
+			go runHistoryCleanup(cleanupCtx, history, cfg.History, logger)
+		}
+		logger.Info("evaluation history enabled", "retention", cfg.History.Retention.String())
+	}
+
+	// Engine: a single instance shared by all requests, so compiled
+	// definitions are cached across evaluations
+	engine := &EngineAdapter{engine: dmn.NewWithOptions(repositorySource{repo: repo}, dmn.Options{
+		CacheSize:        cfg.Engine.CacheSize,
+		LatestTTL:        cfg.Engine.CacheLatestTTL,
+		Timeout:          cfg.Engine.EvaluationTimeout,
+		DisableRuleIndex: !cfg.Engine.RuleIndex,
+	})}
 
 	// HTTP Server
+	// The body limit leaves room for the multipart and JSON envelopes
+	// around a document of DMN_MAX_BYTES; the handler checks the document
+	bodyLimit := 4 * 1024 * 1024
+	if maxBytes := int(cfg.Validation.Limits.MaxBytes); 2*maxBytes > bodyLimit {
+		bodyLimit = 2 * maxBytes
+	}
 	app := fiber.New(fiber.Config{
 		AppName:               "DMN Engine Go",
 		ReadTimeout:           cfg.Server.ReadTimeout,
 		WriteTimeout:          cfg.Server.WriteTimeout,
+		BodyLimit:             bodyLimit,
+		StreamRequestBody:     true,
 		DisableStartupMessage: false,
 	})
 
@@ -83,10 +111,11 @@ func main() {
 		AllowHeaders: "Origin, Content-Type, Accept, X-Tenant-ID, X-Request-ID",
 	}))
 	app.Use(api.RequestIDMiddleware())
+	app.Use(api.BodyLimitMiddleware(bodyLimit))
 	app.Use(api.LoggerMiddleware(logger))
 
 	// Routes
-	handler := api.NewHandler(repo, engine, logger)
+	handler := api.NewHandler(repo, history, engine, cfg.Validation, cfg.Engine, logger)
 	api.SetupRoutes(app, handler)
 
 	// Graceful shutdown
@@ -116,36 +145,222 @@ func main() {
 	logger.Info("server stopped")
 }
 
-// EngineAdapter adapts engine.Engine to api.EngineInterface
-type EngineAdapter struct {
+// runHistoryCleanup removes evaluations older than the retention period at
+// every cleanup interval until ctx is done
+func runHistoryCleanup(ctx context.Context, history storage.HistoryRepository, cfg config.HistoryConfig, logger *slog.Logger) {
+	interval := cfg.CleanupInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := history.DeleteBefore(ctx, time.Now().Add(-cfg.Retention))
+		if err != nil {
+			logger.Error("history cleanup failed", "error", err)
+		} else if removed > 0 {
+			logger.Info("history cleaned up", "removed", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// repositorySource serves the deployed definitions of a repository as
+// models to the engine
+type repositorySource struct {
 	repo storage.DefinitionRepository
 }
 
+func (s repositorySource) Decision(ctx context.Context, tenantID, decisionID string, version int) (*dmn.Model, error) {
+	if version > 0 {
+		return toModel(s.repo.GetByDecisionIDAndVersion(ctx, decisionID, version, tenantID))
+	}
+	return toModel(s.repo.GetByDecisionID(ctx, decisionID, tenantID))
+}
+
+func (s repositorySource) Namespace(ctx context.Context, tenantID, namespace string) (*dmn.Model, error) {
+	return toModel(s.repo.GetByNamespace(ctx, namespace, tenantID))
+}
+
+func toModel(def *storage.Definition, err error) (*dmn.Model, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &dmn.Model{
+		ID:          def.ID,
+		Key:         def.Key,
+		Name:        def.Name,
+		Version:     def.Version,
+		Namespace:   def.Namespace,
+		TenantID:    def.TenantID,
+		DecisionIDs: def.DecisionIDs,
+		Definitions: def.ParsedModel,
+	}, nil
+}
+
+// EngineAdapter adapts dmn.Engine to api.EngineInterface
+type EngineAdapter struct {
+	engine *dmn.Engine
+}
+
 func (a *EngineAdapter) Evaluate(ctx context.Context, req *api.EvaluateRequest) (*api.EvaluateResult, error) {
-	eng := engine.NewEngine(a.repo)
-	
 	// Convert API request to engine request
-	engineReq := &engine.EvaluateRequest{
+	engineReq := &dmn.EvaluateRequest{
 		DecisionKey: req.DecisionKey,
 		Version:     req.Version,
 		Variables:   req.Variables,
 		TenantID:    req.TenantID,
+		Explain:     req.Explain,
 	}
 	
 	// Evaluate
-	result, err := eng.Evaluate(ctx, engineReq)
+	result, err := a.engine.Evaluate(ctx, engineReq)
+	var evalErr *dmn.EvaluationError
+	if errors.As(err, &evalErr) {
+		return nil, &api.EvaluationError{Err: err, Trace: toAPITrace(evalErr.Trace)}
+	}
 	if err != nil {
 		return nil, err
 	}
 	
 	// Convert engine result to API result
+	return toAPIResult(result), nil
+}
+
+func (a *EngineAdapter) EvaluateBatch(ctx context.Context, req *api.BatchEvaluateRequest) (*api.BatchEvaluateResult, error) {
+	result, err := a.engine.EvaluateBatch(ctx, &dmn.BatchRequest{
+		DecisionKey: req.DecisionKey,
+		Version:     req.Version,
+		TenantID:    req.TenantID,
+		Items:       req.Items,
+		Workers:     req.Workers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &api.BatchEvaluateResult{
+		DecisionKey: result.DecisionKey,
+		Version:     result.Version,
+		Results:     make([]api.BatchItemResult, len(result.Items)),
+		Succeeded:   len(result.Items) - result.Failed,
+		Failed:      result.Failed,
+		DurationNs:  result.DurationNs,
+	}
+	for i, item := range result.Items {
+		batch.Results[i].Index = item.Index
+		if item.Err != nil {
+			batch.Results[i].Error = item.Err.Error()
+		} else {
+			batch.Results[i].Result = toAPIResult(item.Result)
+		}
+	}
+	return batch, nil
+}
+
+func (a *EngineAdapter) DefinitionDeployed(tenantID string) {
+	a.engine.Deployed(tenantID)
+}
+
+func (a *EngineAdapter) CompileDefinition(ctx context.Context, def *storage.Definition) {
+	// The handler validated the document before deploying it
+	model, _ := toModel(def, nil)
+	a.engine.CompileValidated(model)
+}
+
+func (a *EngineAdapter) DefinitionDeleted(tenantID, key string) {
+	a.engine.Deleted(tenantID, key)
+}
+
+func (a *EngineAdapter) CacheStats() api.CacheStats {
+	stats := a.engine.CacheStats()
+	return api.CacheStats{
+		Size:          stats.Size,
+		Capacity:      stats.Capacity,
+		Hits:          stats.Hits,
+		Misses:        stats.Misses,
+		Evictions:     stats.Evictions,
+		Invalidations: stats.Invalidations,
+	}
+}
+
+func (a *EngineAdapter) Functions(tenantID string) map[string]*dmn.Function {
+	return a.engine.Functions().Functions(tenantID)
+}
+
+func (a *EngineAdapter) HitPolicies() []string {
+	return a.engine.HitPolicies()
+}
+
+func toAPIResult(result *dmn.EvaluateResult) *api.EvaluateResult {
 	return &api.EvaluateResult{
+		DefinitionID: result.DefinitionID,
 		DecisionKey:  result.DecisionKey,
 		DecisionName: result.DecisionName,
 		Version:      result.Version,
 		Outputs:      result.Outputs,
-		MatchedRules: result.MatchedRules,
+		MatchedRules: toAPIRuleMatches(result.MatchedRules),
+		Defaulted:    result.Defaulted,
 		EvaluatedAt:  result.EvaluatedAt,
 		DurationNs:   result.DurationNs,
-	}, nil
+		Trace:        toAPITrace(result.Trace),
+	}
+}
+
+func toAPITrace(trace *dmn.Trace) *api.Trace {
+	if trace == nil {
+		return nil
+	}
+	result := &api.Trace{Decisions: make([]api.DecisionTrace, len(trace.Decisions))}
+	for i, d := range trace.Decisions {
+		dt := api.DecisionTrace{
+			ID:         d.ID,
+			Name:       d.Name,
+			Kind:       d.Kind,
+			Namespace:  d.Namespace,
+			Expression: d.Expression,
+			Result:     d.Result,
+			Error:      d.Error,
+		}
+		for _, in := range d.Inputs {
+			dt.Inputs = append(dt.Inputs, api.InputTrace(in))
+		}
+		for _, r := range d.Rules {
+			rt := api.RuleTrace{RuleID: r.RuleID, RuleIndex: r.RuleIndex, Matched: r.Matched}
+			for _, e := range r.Entries {
+				rt.Entries = append(rt.Entries, api.EntryTrace(e))
+			}
+			dt.Rules = append(dt.Rules, rt)
+		}
+		if d.HitPolicy != nil {
+			hp := api.HitPolicyTrace(*d.HitPolicy)
+			dt.HitPolicy = &hp
+		}
+		result.Decisions[i] = dt
+	}
+	return result
+}
+
+func toAPIRuleMatches(matches []dmn.RuleMatch) []api.RuleMatch {
+	result := make([]api.RuleMatch, len(matches))
+	for i, m := range matches {
+		result[i] = api.RuleMatch{
+			RuleID:      m.RuleID,
+			RuleIndex:   m.RuleIndex,
+			Description: m.Description,
+		}
+		for _, a := range m.Annotations {
+			result[i].Annotations = append(result[i].Annotations, api.RuleAnnotation{
+				Name: a.Name,
+				Text: a.Text,
+			})
+		}
+	}
+	return result
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/konstantin/dmn-engine-go/internal/api"
+	"github.com/konstantin/dmn-engine-go/internal/config"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+	"github.com/konstantin/dmn-engine-go/pkg/dmn"
+)
+
+// newTestServer serves the API over a memory repository and the engine,
+// wired as main wires them
+func newTestServer(evaluation config.EngineConfig) *fiber.App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := storage.NewMemoryRepository()
+	engine := &EngineAdapter{engine: dmn.NewWithOptions(repositorySource{repo: repo}, dmn.Options{CacheSize: 16})}
+	app := fiber.New()
+	api.SetupRoutes(app, api.NewHandler(repo, nil, engine, config.ValidationConfig{}, evaluation, logger))
+	return app
+}
+
+// call sends a request to the server and decodes its JSON response into
+// out. It returns the status
+func call(t *testing.T, app *fiber.App, method, path, contentType, body string, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, path, err, data)
+		}
+	}
+	return resp.StatusCode
+}
+
+// deployXML deploys a DMN document and fails the test unless it succeeds
+func deployXML(t *testing.T, app *fiber.App, xml string) {
+	t.Helper()
+	var resp map[string]interface{}
+	if status := call(t, app, "POST", "/api/v1/definitions/", "application/xml", xml, &resp); status != 201 {
+		t.Fatalf("deploy status = %d: %v", status, resp)
+	}
+}
+
+// countModel is a decision "count" whose cost grows with its input n, so
+// items of a batch finish out of order
+const countModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Count" namespace="http://example.org/count">
+  <inputData id="in_n" name="n"><variable name="n" typeRef="number"/></inputData>
+  <decision id="count" name="count">
+    <variable name="count"/>
+    <informationRequirement><requiredInput href="#in_n"/></informationRequirement>
+    <decisionTable>
+      <input><inputExpression typeRef="number"><text>n</text></inputExpression></input>
+      <output name="count"/>
+      <rule><inputEntry><text>-</text></inputEntry><outputEntry><text>count(for i in 1..n return i)</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+
+func TestEvaluateBatch(t *testing.T) {
+	app := newTestServer(config.EngineConfig{BatchWorkers: 4, BatchMaxItems: 6})
+	deployXML(t, app, countModel)
+
+	// Earlier items take longest
+	items := []interface{}{200000, 100000, "many", 1000, 10, "few"}
+	body, _ := json.Marshal(map[string]interface{}{
+		"decisionKey": "count",
+		"items":       []map[string]interface{}{{"n": items[0]}, {"n": items[1]}, {"n": items[2]}, {"n": items[3]}, {"n": items[4]}, {"n": items[5]}},
+	})
+	var res api.BatchEvaluateResult
+	if status := call(t, app, "POST", "/api/v1/evaluate/batch", "application/json", string(body), &res); status != 200 {
+		t.Fatalf("status = %d", status)
+	}
+
+	if res.Succeeded != 4 || res.Failed != 2 || len(res.Results) != len(items) {
+		t.Fatalf("succeeded %d, failed %d, %d results", res.Succeeded, res.Failed, len(res.Results))
+	}
+	for i, item := range res.Results {
+		if item.Index != i {
+			t.Errorf("result %d has index %d", i, item.Index)
+		}
+		if n, ok := items[i].(int); ok {
+			if item.Result == nil || item.Result.Outputs[0]["count"] != float64(n) {
+				t.Errorf("item %d = %+v, want count %d", i, item, n)
+			}
+		} else if !strings.Contains(item.Error, "expected number, got string") || item.Result != nil {
+			t.Errorf("item %d = %+v, want a type error", i, item)
+		}
+	}
+
+	// A batch over BatchMaxItems is rejected before any item is evaluated
+	body, _ = json.Marshal(map[string]interface{}{"decisionKey": "count", "items": make([]map[string]interface{}, 7)})
+	var errResp api.ErrorResponse
+	if status := call(t, app, "POST", "/api/v1/evaluate/batch", "application/json", string(body), &errResp); status != 400 {
+		t.Errorf("status over the limit = %d, want 400", status)
+	}
+	if errResp.Error != "batch has 7 items, at most 6 are allowed" {
+		t.Errorf("error = %q", errResp.Error)
+	}
+}
+
+// annotatedModel is a decision "risk" with a described and annotated rule
+// and a plain one
+const annotatedModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Annotated" namespace="http://example.org/annotated">
+  <inputData id="in_age" name="age"><variable name="age"/></inputData>
+  <decision id="risk" name="risk">
+    <variable name="risk"/>
+    <informationRequirement><requiredInput href="#in_age"/></informationRequirement>
+    <decisionTable hitPolicy="RULE ORDER">
+      <input><inputExpression typeRef="number"><text>age</text></inputExpression></input>
+      <output name="risk"/>
+      <annotation name="source"/>
+      <rule id="minor">
+        <description>Minors are always high risk</description>
+        <inputEntry><text>&lt; 18</text></inputEntry>
+        <outputEntry><text>"HIGH"</text></outputEntry>
+        <annotationEntry><text>policy 4.2</text></annotationEntry>
+      </rule>
+      <rule id="any">
+        <inputEntry><text>-</text></inputEntry>
+        <outputEntry><text>"LOW"</text></outputEntry>
+      </rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+
+func TestEvaluateRuleAnnotations(t *testing.T) {
+	app := newTestServer(config.EngineConfig{})
+	deployXML(t, app, annotatedModel)
+
+	var res api.EvaluateResult
+	if status := call(t, app, "POST", "/api/v1/evaluate", "application/json", `{"decisionKey": "risk", "variables": {"age": 10}}`, &res); status != 200 {
+		t.Fatalf("status = %d", status)
+	}
+	want := []api.RuleMatch{
+		{RuleID: "minor", RuleIndex: 0, Description: "Minors are always high risk", Annotations: []api.RuleAnnotation{{Name: "source", Text: "policy 4.2"}}},
+		{RuleID: "any", RuleIndex: 1},
+	}
+	if !reflect.DeepEqual(res.MatchedRules, want) {
+		t.Errorf("matched rules = %+v, want %+v", res.MatchedRules, want)
+	}
+}
@@ -13,6 +13,9 @@ CREATE TABLE IF NOT EXISTS dmn_definitions (
     source      TEXT NOT NULL,
     parsed_model JSONB,
     checksum    VARCHAR(64),
+    decision_ids TEXT[] NOT NULL DEFAULT '{}',
+    namespace   VARCHAR(1024),
+    version_tag VARCHAR(255),
     tenant_id   VARCHAR(64),
     created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     
@@ -24,6 +27,29 @@ CREATE INDEX IF NOT EXISTS idx_dmn_def_key ON dmn_definitions(key);
 CREATE INDEX IF NOT EXISTS idx_dmn_def_tenant ON dmn_definitions(tenant_id) WHERE tenant_id IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_dmn_def_key_version ON dmn_definitions(key, version DESC);
 CREATE INDEX IF NOT EXISTS idx_dmn_def_created ON dmn_definitions(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_dmn_def_decision_ids ON dmn_definitions USING GIN (decision_ids);
+CREATE INDEX IF NOT EXISTS idx_dmn_def_namespace ON dmn_definitions(namespace, created_at DESC) WHERE namespace IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_dmn_def_version_tag ON dmn_definitions(key, version_tag) WHERE version_tag IS NOT NULL;
+
+-- Evaluation history
+CREATE TABLE IF NOT EXISTS dmn_evaluation_history (
+    id            UUID PRIMARY KEY,
+    definition_id VARCHAR(64) NOT NULL,
+    decision_key  VARCHAR(255) NOT NULL,
+    decision_name VARCHAR(255),
+    version       INT NOT NULL,
+    tenant_id     VARCHAR(64),
+    inputs        JSONB NOT NULL,
+    outputs       JSONB NOT NULL,
+    matched_rules JSONB NOT NULL,
+    duration_ns   BIGINT NOT NULL,
+    request_id    VARCHAR(255),
+    evaluated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_dmn_history_key ON dmn_evaluation_history(decision_key, evaluated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_dmn_history_evaluated_at ON dmn_evaluation_history(evaluated_at);
+CREATE INDEX IF NOT EXISTS idx_dmn_history_rules ON dmn_evaluation_history USING GIN (matched_rules jsonb_path_ops);
 
 -- Grant permissions
 GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA public TO dmn;
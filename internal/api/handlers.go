@@ -3,25 +3,58 @@ package api
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/konstantin/dmn-engine-go/internal/config"
 	"github.com/konstantin/dmn-engine-go/internal/dmn"
+	"github.com/konstantin/dmn-engine-go/internal/feel"
 	"github.com/konstantin/dmn-engine-go/internal/storage"
+	dmnlib "github.com/konstantin/dmn-engine-go/pkg/dmn"
 )
 
 // Handler contains all HTTP handlers
 type Handler struct {
-	repo   storage.DefinitionRepository
-	engine EngineInterface
-	logger *slog.Logger
+	repo       storage.DefinitionRepository
+	history    storage.HistoryRepository // nil when history is disabled
+	engine     EngineInterface
+	validation config.ValidationConfig
+	evaluation config.EngineConfig
+	logger     *slog.Logger
 }
 
 // EngineInterface is the interface for the evaluation engine
 type EngineInterface interface {
 	Evaluate(ctx context.Context, req *EvaluateRequest) (*EvaluateResult, error)
+
+	// EvaluateBatch evaluates one decision for every item of the request
+	EvaluateBatch(ctx context.Context, req *BatchEvaluateRequest) (*BatchEvaluateResult, error)
+
+	// DefinitionDeployed is called after a definition is deployed
+	DefinitionDeployed(tenantID string)
+
+	// CompileDefinition is called after a definition is deployed, so its
+	// first evaluation does not pay for compiling it. The definition has
+	// been validated already and is not validated again
+	CompileDefinition(ctx context.Context, def *storage.Definition)
+
+	// DefinitionDeleted is called after all versions of a definition are deleted
+	DefinitionDeleted(tenantID, key string)
+
+	// CacheStats returns statistics of the compiled-definition cache
+	CacheStats() CacheStats
+
+	// Functions returns the Go functions FEEL expressions of the tenant's
+	// models may call
+	Functions(tenantID string) map[string]*feel.Function
+
+	// HitPolicies returns the names of the custom hit policies decision
+	// tables may use
+	HitPolicies() []string
 }
 
 // EvaluateRequest mirrors engine.EvaluateRequest for API
@@ -30,25 +63,149 @@ type EvaluateRequest struct {
 	Version     *int                   `json:"version,omitempty"`
 	Variables   map[string]interface{} `json:"variables"`
 	TenantID    string                 `json:"tenantId,omitempty"`
+	Explain     bool                   `json:"explain,omitempty"`
 }
 
 // EvaluateResult mirrors engine.EvaluateResult for API
 type EvaluateResult struct {
+	DefinitionID string                   `json:"definitionId"`
 	DecisionKey  string                   `json:"decisionKey"`
 	DecisionName string                   `json:"decisionName"`
 	Version      int                      `json:"version"`
 	Outputs      []map[string]interface{} `json:"outputs"`
-	MatchedRules []string                 `json:"matchedRules"`
+	MatchedRules []RuleMatch              `json:"matchedRules"`
+	Defaulted    bool                     `json:"defaulted,omitempty"`
 	EvaluatedAt  time.Time                `json:"evaluatedAt"`
 	DurationNs   int64                    `json:"durationNs"`
+	Trace        *Trace                   `json:"trace,omitempty"`
+}
+
+// RuleMatch mirrors engine.RuleMatch for API
+type RuleMatch struct {
+	RuleID      string           `json:"ruleId"`
+	RuleIndex   int              `json:"ruleIndex"`
+	Description string           `json:"description,omitempty"`
+	Annotations []RuleAnnotation `json:"annotations,omitempty"`
+}
+
+// RuleAnnotation mirrors engine.RuleAnnotation for API
+type RuleAnnotation struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
+}
+
+// Trace mirrors engine.Trace for API
+type Trace struct {
+	Decisions []DecisionTrace `json:"decisions"`
+}
+
+// DecisionTrace mirrors engine.DecisionTrace for API
+type DecisionTrace struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Kind       string          `json:"kind"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Expression string          `json:"expression,omitempty"`
+	Inputs     []InputTrace    `json:"inputs,omitempty"`
+	Rules      []RuleTrace     `json:"rules,omitempty"`
+	HitPolicy  *HitPolicyTrace `json:"hitPolicy,omitempty"`
+	Result     interface{}     `json:"result"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// InputTrace mirrors engine.InputTrace for API
+type InputTrace struct {
+	Index      int         `json:"index"`
+	Label      string      `json:"label,omitempty"`
+	Expression string      `json:"expression"`
+	Value      interface{} `json:"value"`
+}
+
+// RuleTrace mirrors engine.RuleTrace for API
+type RuleTrace struct {
+	RuleID    string       `json:"ruleId"`
+	RuleIndex int          `json:"ruleIndex"`
+	Matched   bool         `json:"matched"`
+	Entries   []EntryTrace `json:"entries"`
+}
+
+// EntryTrace mirrors engine.EntryTrace for API
+type EntryTrace struct {
+	Input   int         `json:"input"`
+	Text    string      `json:"text"`
+	Value   interface{} `json:"value"`
+	Matched bool        `json:"matched"`
+	Skipped bool        `json:"skipped,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// HitPolicyTrace mirrors engine.HitPolicyTrace for API
+type HitPolicyTrace struct {
+	HitPolicy     string `json:"hitPolicy"`
+	Aggregation   string `json:"aggregation,omitempty"`
+	MatchedRules  []int  `json:"matchedRules"`
+	SelectedRules []int  `json:"selectedRules"`
+	StoppedEarly  bool   `json:"stoppedEarly,omitempty"`
+	Defaulted     bool   `json:"defaulted,omitempty"`
+}
+
+// EvaluationError mirrors engine.EvaluationError for API: a failed
+// evaluation with the trace up to the failure
+type EvaluationError struct {
+	Err   error
+	Trace *Trace
+}
+
+func (e *EvaluationError) Error() string { return e.Err.Error() }
+
+func (e *EvaluationError) Unwrap() error { return e.Err }
+
+// BatchEvaluateRequest is a request to evaluate one decision for several
+// sets of variables
+type BatchEvaluateRequest struct {
+	DecisionKey string                   `json:"decisionKey"`
+	Version     *int                     `json:"version,omitempty"`
+	Items       []map[string]interface{} `json:"items"`
+	TenantID    string                   `json:"tenantId,omitempty"`
+	Workers     int                      `json:"-"`
+}
+
+// BatchEvaluateResult mirrors engine.BatchResult for API
+type BatchEvaluateResult struct {
+	DecisionKey string            `json:"decisionKey"`
+	Version     int               `json:"version"`
+	Results     []BatchItemResult `json:"results"`
+	Succeeded   int               `json:"succeeded"`
+	Failed      int               `json:"failed"`
+	DurationNs  int64             `json:"durationNs"`
+}
+
+// BatchItemResult is the result or the error of one item of a batch
+type BatchItemResult struct {
+	Index  int             `json:"index"`
+	Result *EvaluateResult `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// CacheStats mirrors engine.CacheStats for API
+type CacheStats struct {
+	Size          int    `json:"size"`
+	Capacity      int    `json:"capacity"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
 }
 
 // NewHandler creates a new handler
-func NewHandler(repo storage.DefinitionRepository, engine EngineInterface, logger *slog.Logger) *Handler {
+func NewHandler(repo storage.DefinitionRepository, history storage.HistoryRepository, engine EngineInterface, validation config.ValidationConfig, evaluation config.EngineConfig, logger *slog.Logger) *Handler {
 	return &Handler{
-		repo:   repo,
-		engine: engine,
-		logger: logger,
+		repo:       repo,
+		history:    history,
+		engine:     engine,
+		validation: validation,
+		evaluation: evaluation,
+		logger:     logger,
 	}
 }
 
@@ -56,6 +213,18 @@ func NewHandler(repo storage.DefinitionRepository, engine EngineInterface, logge
 type ErrorResponse struct {
 	Error   string                `json:"error"`
 	Details []dmn.ValidationError `json:"details,omitempty"`
+	Trace   *Trace                `json:"trace,omitempty"` // explained evaluation up to the failure
+}
+
+// evaluationErrorResponse reports a failed evaluation together with its
+// trace, if the evaluation was explained
+func evaluationErrorResponse(message string, err error) ErrorResponse {
+	resp := ErrorResponse{Error: message}
+	var evalErr *EvaluationError
+	if errors.As(err, &evalErr) {
+		resp.Trace = evalErr.Trace
+	}
+	return resp
 }
 
 // DeployRequest is a deploy request (for JSON body)
@@ -66,24 +235,37 @@ type DeployRequest struct {
 
 // DefinitionResponse is a simplified response without parsed model
 type DefinitionResponse struct {
-	ID        string `json:"id"`
-	Key       string `json:"key"`
-	Version   int    `json:"version"`
-	Name      string `json:"name"`
-	Checksum  string `json:"checksum"`
-	TenantID  string `json:"tenantId,omitempty"`
-	CreatedAt string `json:"createdAt"`
+	ID          string   `json:"id"`
+	Key         string   `json:"key"`
+	Version     int      `json:"version"`
+	Name        string   `json:"name"`
+	Checksum    string   `json:"checksum"`
+	DecisionIDs []string `json:"decisionIds"`
+	DMNVersion  string   `json:"dmnVersion,omitempty"`
+	VersionTag  string   `json:"versionTag,omitempty"`
+	TenantID    string   `json:"tenantId,omitempty"`
+	CreatedAt   string   `json:"createdAt"`
+
+	// Warnings are the non-blocking findings of a deploy
+	Warnings []dmn.ValidationError `json:"warnings,omitempty"`
 }
 
 func toDefinitionResponse(def *storage.Definition) *DefinitionResponse {
+	dmnVersion := ""
+	if def.ParsedModel != nil {
+		dmnVersion = def.ParsedModel.ModelVersion()
+	}
 	return &DefinitionResponse{
-		ID:        def.ID,
-		Key:       def.Key,
-		Version:   def.Version,
-		Name:      def.Name,
-		Checksum:  def.Checksum,
-		TenantID:  def.TenantID,
-		CreatedAt: def.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:          def.ID,
+		Key:         def.Key,
+		Version:     def.Version,
+		Name:        def.Name,
+		Checksum:    def.Checksum,
+		DecisionIDs: def.DecisionIDs,
+		DMNVersion:  dmnVersion,
+		VersionTag:  def.VersionTag,
+		TenantID:    def.TenantID,
+		CreatedAt:   def.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
 
@@ -95,20 +277,32 @@ func toDefinitionResponses(defs []*storage.Definition) []*DefinitionResponse {
 	return result
 }
 
-// DeployDefinition handles POST /api/v1/definitions
-func (h *Handler) DeployDefinition(c *fiber.Ctx) error {
+// errContentTooLarge is returned by readDMNContent for uploads above the
+// configured size limit
+var errContentTooLarge = errors.New("DMN document is too large")
+
+// readDMNContent reads the DMN XML of a deploy or validate request from a
+// multipart file, a JSON body or a raw XML body. Raw bodies are read from
+// the request stream and multipart files from the form the server parsed,
+// in both cases no further than one byte past maxBytes
+func readDMNContent(c *fiber.Ctx, maxBytes int64) ([]byte, string, error) {
 	var xmlContent []byte
 	var name string
 
 	// Try to get file from multipart form
 	file, err := c.FormFile("file")
 	if err == nil {
+		if maxBytes > 0 && file.Size > maxBytes {
+			return nil, "", errContentTooLarge
+		}
 		f, err := file.Open()
 		if err != nil {
-			return c.Status(400).JSON(ErrorResponse{Error: "failed to open file"})
+			return nil, "", errors.New("failed to open file")
 		}
 		defer f.Close()
-		xmlContent, _ = io.ReadAll(f)
+		if xmlContent, err = readLimited(f, maxBytes); err != nil {
+			return nil, "", err
+		}
 		name = c.FormValue("name", file.Filename)
 	} else {
 		// Try JSON body
@@ -116,59 +310,174 @@ func (h *Handler) DeployDefinition(c *fiber.Ctx) error {
 		if contentType == "application/json" {
 			var req DeployRequest
 			if err := c.BodyParser(&req); err != nil {
-				return c.Status(400).JSON(ErrorResponse{Error: "invalid JSON body"})
+				return nil, "", errors.New("invalid JSON body")
 			}
 			xmlContent = []byte(req.XML)
 			name = req.Name
 		} else {
 			// Assume raw XML
-			xmlContent = c.Body()
+			if xmlContent, err = readLimited(requestBody(c), maxBytes); err != nil {
+				return nil, "", err
+			}
 		}
 	}
 
 	if len(xmlContent) == 0 {
-		return c.Status(400).JSON(ErrorResponse{Error: "no DMN content provided"})
+		return nil, "", errors.New("no DMN content provided")
 	}
+	if maxBytes > 0 && int64(len(xmlContent)) > maxBytes {
+		return nil, "", errContentTooLarge
+	}
+	return xmlContent, name, nil
+}
 
-	// Parse DMN
-	parser := dmn.NewParser()
-	defs, err := parser.Parse(bytes.NewReader(xmlContent))
+// requestBody returns the request body as a stream. Bodies the server did
+// not stream, or that were already read into memory, come from memory
+func requestBody(c *fiber.Ctx) io.Reader {
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		return stream
+	}
+	return bytes.NewReader(c.Body())
+}
+
+// readLimited reads r to the end, failing with errContentTooLarge past
+// maxBytes. Zero means no limit
+func readLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	if maxBytes > 0 {
+		r = io.LimitReader(r, maxBytes+1)
+	}
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return c.Status(400).JSON(ErrorResponse{Error: "invalid DMN XML: " + err.Error()})
+		return nil, errors.New("failed to read request body")
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, errContentTooLarge
 	}
+	return data, nil
+}
 
-	// Validate
-	validator := dmn.NewValidator()
-	if errors := validator.Validate(defs); len(errors) > 0 {
-		return c.Status(400).JSON(ErrorResponse{
-			Error:   "DMN validation failed",
-			Details: errors,
-		})
+// readDefinitionRequest reads and parses the DMN XML of a deploy or validate
+// request. On failure it returns the status and body of the error response
+func (h *Handler) readDefinitionRequest(c *fiber.Ctx) ([]byte, string, *dmn.Definitions, int, *ErrorResponse) {
+	limits := h.validation.Limits
+	xmlContent, name, err := readDMNContent(c, limits.MaxBytes)
+	if errors.Is(err, errContentTooLarge) {
+		return nil, "", nil, 413, &ErrorResponse{Error: fmt.Sprintf("DMN document exceeds maxBytes of %d", limits.MaxBytes)}
+	}
+	if err != nil {
+		return nil, "", nil, 400, &ErrorResponse{Error: err.Error()}
 	}
 
-	// Determine key and name
-	key := ""
-	if len(defs.Decisions) > 0 {
-		key = defs.Decisions[0].ID
-	} else {
-		key = defs.ID
+	defs, status, errResp := h.parseDefinitions(c, xmlContent)
+	if errResp != nil {
+		return nil, "", nil, status, errResp
+	}
+	return xmlContent, name, defs, 0, nil
+}
+
+// parseDefinitions parses the DMN XML of a request within the configured
+// limits. The "strict" query parameter enables the schema check and
+// defaults to the configured mode
+func (h *Handler) parseDefinitions(c *fiber.Ctx, xmlContent []byte) (*dmn.Definitions, int, *ErrorResponse) {
+	limits := h.validation.Limits
+	defs, err := dmnlib.ParseWithOptions(xmlContent, dmn.ParseOptions{
+		Strict: c.QueryBool("strict", h.validation.StrictParse),
+		Limits: dmn.Limits{
+			MaxBytes:         limits.MaxBytes,
+			MaxDecisions:     limits.MaxDecisions,
+			MaxRulesPerTable: limits.MaxRulesPerTable,
+			MaxCellLength:    limits.MaxCellLength,
+			MaxDepth:         limits.MaxDepth,
+		},
+	})
+	if err == nil {
+		return defs, 0, nil
+	}
+
+	var limitErr *dmn.LimitError
+	if errors.As(err, &limitErr) {
+		status := 400
+		if limitErr.Limit == dmn.LimitBytes {
+			status = 413
+		}
+		return nil, status, &ErrorResponse{Error: limitErr.Error()}
+	}
+
+	var schemaErr *dmn.SchemaError
+	if errors.As(err, &schemaErr) {
+		return nil, 400, &ErrorResponse{
+			Error:   "DMN schema validation failed",
+			Details: schemaErr.Findings,
+		}
+	}
+	return nil, 400, &ErrorResponse{Error: "invalid DMN XML: " + err.Error()}
+}
+
+// validateDefinitions runs all deploy checks on a parsed model. Imported
+// models must already be deployed for the same tenant, and functions the
+// model calls must be registered for it
+func (h *Handler) validateDefinitions(ctx context.Context, defs *dmn.Definitions, tenantID string) []dmn.ValidationError {
+	opts := dmnlib.ValidateOptions{
+		Imports: func(namespace string) (*dmn.Definitions, error) {
+			imported, err := h.repo.GetByNamespace(ctx, namespace, tenantID)
+			if err != nil {
+				return nil, err
+			}
+			return imported.ParsedModel, nil
+		},
+	}
+	if h.engine != nil {
+		opts.Functions = h.engine.Functions(tenantID)
+		opts.HitPolicies = h.engine.HitPolicies()
 	}
-	if name == "" {
-		name = defs.Name
+	return dmnlib.Validate(defs, opts)
+}
+
+// versionTag returns the camunda:versionTag of the decision a definition is
+// deployed under, falling back to the first decision that has one
+func versionTag(defs *dmn.Definitions, key string) string {
+	if d := defs.GetDecision(key); d != nil && d.VersionTag != "" {
+		return d.VersionTag
 	}
-	if name == "" {
-		name = key
+	for _, d := range defs.Decisions {
+		if d.VersionTag != "" {
+			return d.VersionTag
+		}
+	}
+	return ""
+}
+
+// DeployDefinition handles POST /api/v1/definitions
+func (h *Handler) DeployDefinition(c *fiber.Ctx) error {
+	// Read and parse DMN
+	xmlContent, name, defs, status, errResp := h.readDefinitionRequest(c)
+	if errResp != nil {
+		return c.Status(status).JSON(errResp)
 	}
 
 	// Get tenant ID from header
 	tenantID := c.Get("X-Tenant-ID")
 
+	// Validate
+	findings := h.validateDefinitions(c.Context(), defs, tenantID)
+	if blocking := dmnlib.Blocking(findings, h.validation.BlockingCodesFor(tenantID)); len(blocking) > 0 {
+		return c.Status(400).JSON(ErrorResponse{
+			Error:   "DMN validation failed",
+			Details: findings,
+		})
+	}
+
+	model := dmnlib.NewModel(defs, name)
+
 	// Create definition
 	def := &storage.Definition{
-		Key:         key,
-		Name:        name,
+		Key:         model.Key,
+		Name:        model.Name,
 		Source:      string(xmlContent),
 		ParsedModel: defs,
+		DecisionIDs: model.DecisionIDs,
+		Namespace:   model.Namespace,
+		VersionTag:  versionTag(defs, model.Key),
 		TenantID:    tenantID,
 	}
 
@@ -177,6 +486,10 @@ func (h *Handler) DeployDefinition(c *fiber.Ctx) error {
 		h.logger.Error("failed to deploy definition", "error", err)
 		return c.Status(500).JSON(ErrorResponse{Error: "failed to deploy definition: " + err.Error()})
 	}
+	if h.engine != nil {
+		h.engine.DefinitionDeployed(tenantID)
+		h.engine.CompileDefinition(c.Context(), def)
+	}
 
 	h.logger.Info("definition deployed",
 		"key", def.Key,
@@ -184,16 +497,64 @@ func (h *Handler) DeployDefinition(c *fiber.Ctx) error {
 		"tenantId", def.TenantID,
 	)
 
-	return c.Status(201).JSON(toDefinitionResponse(def))
+	resp := toDefinitionResponse(def)
+	resp.Warnings = findings
+	return c.Status(201).JSON(resp)
+}
+
+// ValidateResponse is the result of a dry-run validation
+type ValidateResponse struct {
+	Valid       bool                        `json:"valid"`
+	Key         string                      `json:"key"`
+	Name        string                      `json:"name"`
+	Namespace   string                      `json:"namespace,omitempty"`
+	DMNVersion  string                      `json:"dmnVersion,omitempty"`
+	VersionTag  string                      `json:"versionTag,omitempty"`
+	DecisionIDs []string                    `json:"decisionIds"`
+	Findings    []dmn.ValidationError       `json:"findings"`
+	Blocking    []dmn.ValidationError       `json:"blocking,omitempty"`
+	Analysis    []dmn.DecisionTableAnalysis `json:"analysis,omitempty"`
+}
+
+// ValidateDefinition handles POST /api/v1/definitions/validate. It runs the
+// same checks as a deploy and reports what would be deployed, without
+// creating a version
+func (h *Handler) ValidateDefinition(c *fiber.Ctx) error {
+	_, name, defs, status, errResp := h.readDefinitionRequest(c)
+	if errResp != nil {
+		return c.Status(status).JSON(errResp)
+	}
+
+	tenantID := c.Get("X-Tenant-ID")
+	findings := h.validateDefinitions(c.Context(), defs, tenantID)
+	blocking := dmnlib.Blocking(findings, h.validation.BlockingCodesFor(tenantID))
+	model := dmnlib.NewModel(defs, name)
+
+	if findings == nil {
+		findings = []dmn.ValidationError{}
+	}
+	return c.JSON(ValidateResponse{
+		Valid:       len(blocking) == 0,
+		Key:         model.Key,
+		Name:        model.Name,
+		Namespace:   model.Namespace,
+		DMNVersion:  defs.DMNVersion,
+		VersionTag:  versionTag(defs, model.Key),
+		DecisionIDs: model.DecisionIDs,
+		Findings:    findings,
+		Blocking:    blocking,
+		Analysis:    dmn.AnalyzeDefinitions(defs),
+	})
 }
 
 // ListDefinitions handles GET /api/v1/definitions
 func (h *Handler) ListDefinitions(c *fiber.Ctx) error {
 	filter := &storage.ListFilter{
-		Key:      c.Query("key"),
-		TenantID: c.Get("X-Tenant-ID"),
-		Limit:    c.QueryInt("limit", 100),
-		Offset:   c.QueryInt("offset", 0),
+		Key:        c.Query("key"),
+		VersionTag: c.Query("versionTag"),
+		TenantID:   c.Get("X-Tenant-ID"),
+		Limit:      c.QueryInt("limit", 100),
+		Offset:     c.QueryInt("offset", 0),
 	}
 
 	defs, err := h.repo.List(c.Context(), filter)
@@ -216,6 +577,8 @@ func (h *Handler) GetDefinition(c *fiber.Ctx) error {
 
 	if version > 0 {
 		def, err = h.repo.GetByKeyAndVersion(c.Context(), key, version, tenantID)
+	} else if tag := c.Query("versionTag"); tag != "" {
+		def, err = h.repo.GetByKeyAndVersionTag(c.Context(), key, tag, tenantID)
 	} else {
 		def, err = h.repo.GetByKey(c.Context(), key, tenantID)
 	}
@@ -246,9 +609,19 @@ func (h *Handler) GetDefinitionXML(c *fiber.Ctx) error {
 		return c.Status(404).JSON(ErrorResponse{Error: "definition not found"})
 	}
 
+	// ?source=model serializes the parsed model instead of returning the
+	// deployed document
+	source := []byte(def.Source)
+	if c.Query("source") == "model" {
+		source, err = dmn.NewWriter().WriteBytes(def.ParsedModel)
+		if err != nil {
+			return c.Status(500).JSON(ErrorResponse{Error: "failed to write DMN XML: " + err.Error()})
+		}
+	}
+
 	c.Set("Content-Type", "application/xml")
 	c.Set("Content-Disposition", "attachment; filename="+def.Key+".dmn")
-	return c.SendString(def.Source)
+	return c.Send(source)
 }
 
 // GetDefinitionParsed handles GET /api/v1/definitions/:key/parsed
@@ -273,6 +646,32 @@ func (h *Handler) GetDefinitionParsed(c *fiber.Ctx) error {
 	return c.JSON(def.ParsedModel)
 }
 
+// GetDefinitionAnalysis handles GET /api/v1/definitions/:key/analysis
+func (h *Handler) GetDefinitionAnalysis(c *fiber.Ctx) error {
+	key := c.Params("key")
+	tenantID := c.Get("X-Tenant-ID")
+	version := c.QueryInt("version", 0)
+
+	var def *storage.Definition
+	var err error
+
+	if version > 0 {
+		def, err = h.repo.GetByKeyAndVersion(c.Context(), key, version, tenantID)
+	} else {
+		def, err = h.repo.GetByKey(c.Context(), key, tenantID)
+	}
+
+	if err != nil {
+		return c.Status(404).JSON(ErrorResponse{Error: "definition not found"})
+	}
+
+	return c.JSON(fiber.Map{
+		"key":     def.Key,
+		"version": def.Version,
+		"tables":  dmn.AnalyzeDefinitions(def.ParsedModel),
+	})
+}
+
 // GetDefinitionVersions handles GET /api/v1/definitions/:key/versions
 func (h *Handler) GetDefinitionVersions(c *fiber.Ctx) error {
 	key := c.Params("key")
@@ -295,17 +694,19 @@ func (h *Handler) GetDefinitionVersions(c *fiber.Ctx) error {
 
 	// Возвращаем список версий
 	type VersionInfo struct {
-		Version   int    `json:"version"`
-		CreatedAt string `json:"createdAt"`
-		Checksum  string `json:"checksum"`
+		Version    int    `json:"version"`
+		VersionTag string `json:"versionTag,omitempty"`
+		CreatedAt  string `json:"createdAt"`
+		Checksum   string `json:"checksum"`
 	}
 
 	versions := make([]VersionInfo, len(defs))
 	for i, def := range defs {
 		versions[i] = VersionInfo{
-			Version:   def.Version,
-			CreatedAt: def.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			Checksum:  def.Checksum,
+			Version:    def.Version,
+			VersionTag: def.VersionTag,
+			CreatedAt:  def.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			Checksum:   def.Checksum,
 		}
 	}
 
@@ -323,6 +724,9 @@ func (h *Handler) DeleteDefinition(c *fiber.Ctx) error {
 	if err := h.repo.Delete(c.Context(), key, tenantID); err != nil {
 		return c.Status(404).JSON(ErrorResponse{Error: "definition not found"})
 	}
+	if h.engine != nil {
+		h.engine.DefinitionDeleted(tenantID, key)
+	}
 
 	h.logger.Info("definition deleted", "key", key, "tenantId", tenantID)
 	return c.SendStatus(204)
@@ -345,20 +749,34 @@ func (h *Handler) Ready(c *fiber.Ctx) error {
 
 // Info handles GET /api/v1/info
 func (h *Handler) Info(c *fiber.Ctx) error {
+	hitPolicies := []string{"UNIQUE", "FIRST", "ANY", "PRIORITY", "COLLECT", "RULE ORDER", "OUTPUT ORDER"}
+	if h.engine != nil {
+		hitPolicies = append(hitPolicies, h.engine.HitPolicies()...)
+	}
+
 	return c.JSON(fiber.Map{
 		"name":    "DMN Engine Go",
 		"version": "0.1.0-pre-mvp",
 		"features": fiber.Map{
-			"dmn_version":   "1.3",
+			"dmn_version":   "1.1-1.5",
 			"feel_support":  "basic",
 			"storage":       "postgresql",
 			"multi_tenancy": true,
-			"hit_policies":  []string{"UNIQUE", "FIRST", "ANY", "PRIORITY", "COLLECT", "RULE ORDER", "OUTPUT ORDER"},
+			"hit_policies":  hitPolicies,
 			"evaluation":    true, // Базовое выполнение реализовано
 		},
+		"lintRules": dmn.LintRules(),
 	})
 }
 
+// CacheStats handles GET /api/v1/cache/stats
+func (h *Handler) CacheStats(c *fiber.Ctx) error {
+	if h.engine == nil {
+		return c.Status(503).JSON(ErrorResponse{Error: "evaluation engine not available"})
+	}
+	return c.JSON(h.engine.CacheStats())
+}
+
 // Evaluate handles POST /api/v1/evaluate
 func (h *Handler) Evaluate(c *fiber.Ctx) error {
 	var req EvaluateRequest
@@ -385,12 +803,26 @@ func (h *Handler) Evaluate(c *fiber.Ctx) error {
 	}
 
 	result, err := h.engine.Evaluate(c.Context(), &req)
+	var importErr *dmnlib.ImportError
+	if errors.As(err, &importErr) && errors.Is(err, storage.ErrNotFound) {
+		return c.Status(422).JSON(evaluationErrorResponse("imported model not found: "+importErr.Namespace, err))
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(404).JSON(ErrorResponse{Error: "decision not found: " + req.DecisionKey})
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		h.logger.Warn("evaluation timed out",
+			"decisionKey", req.DecisionKey,
+			"error", err,
+		)
+		return c.Status(504).JSON(evaluationErrorResponse(err.Error(), err))
+	}
 	if err != nil {
 		h.logger.Error("evaluation failed",
 			"decisionKey", req.DecisionKey,
 			"error", err,
 		)
-		return c.Status(500).JSON(ErrorResponse{Error: "evaluation failed: " + err.Error()})
+		return c.Status(500).JSON(evaluationErrorResponse("evaluation failed: "+err.Error(), err))
 	}
 
 	h.logger.Info("decision evaluated",
@@ -400,5 +832,73 @@ func (h *Handler) Evaluate(c *fiber.Ctx) error {
 		"durationMs", result.DurationNs/1000000,
 	)
 
+	recordHistory(c.Context(), h.history, h.logger, []*storage.HistoryEntry{
+		newHistoryEntry(req.TenantID, req.Variables, result, requestID(c)),
+	})
+
+	return c.JSON(result)
+}
+
+// EvaluateBatch handles POST /api/v1/evaluate/batch
+func (h *Handler) EvaluateBatch(c *fiber.Ctx) error {
+	var req BatchEvaluateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(ErrorResponse{Error: "invalid request body: " + err.Error()})
+	}
+
+	// Validate request
+	if req.DecisionKey == "" {
+		return c.Status(400).JSON(ErrorResponse{Error: "decisionKey is required"})
+	}
+	if len(req.Items) == 0 {
+		return c.Status(400).JSON(ErrorResponse{Error: "items must not be empty"})
+	}
+	if limit := h.evaluation.BatchMaxItems; limit > 0 && len(req.Items) > limit {
+		return c.Status(400).JSON(ErrorResponse{Error: fmt.Sprintf("batch has %d items, at most %d are allowed", len(req.Items), limit)})
+	}
+
+	// Get tenant ID from header if not in body
+	if req.TenantID == "" {
+		req.TenantID = c.Get("X-Tenant-ID")
+	}
+	req.Workers = h.evaluation.BatchWorkers
+
+	if h.engine == nil {
+		return c.Status(503).JSON(ErrorResponse{Error: "evaluation engine not available"})
+	}
+
+	result, err := h.engine.EvaluateBatch(c.Context(), &req)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(404).JSON(ErrorResponse{Error: "decision not found: " + req.DecisionKey})
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return c.Status(504).JSON(ErrorResponse{Error: err.Error()})
+	}
+	if err != nil {
+		h.logger.Error("batch evaluation failed",
+			"decisionKey", req.DecisionKey,
+			"error", err,
+		)
+		return c.Status(500).JSON(ErrorResponse{Error: "evaluation failed: " + err.Error()})
+	}
+
+	h.logger.Info("batch evaluated",
+		"decisionKey", result.DecisionKey,
+		"version", result.Version,
+		"items", len(result.Results),
+		"failed", result.Failed,
+		"durationMs", result.DurationNs/1000000,
+	)
+
+	if h.history != nil {
+		entries := make([]*storage.HistoryEntry, 0, result.Succeeded)
+		for _, item := range result.Results {
+			if item.Result != nil {
+				entries = append(entries, newHistoryEntry(req.TenantID, req.Items[item.Index], item.Result, requestID(c)))
+			}
+		}
+		recordHistory(c.Context(), h.history, h.logger, entries)
+	}
+
 	return c.JSON(result)
 }
@@ -0,0 +1,443 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/konstantin/dmn-engine-go/internal/config"
+	"github.com/konstantin/dmn-engine-go/internal/dmn"
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+	dmnlib "github.com/konstantin/dmn-engine-go/pkg/dmn"
+)
+
+// stubEngine answers evaluations with a fixed result or error. Methods the
+// tests do not need panic through the nil embedded interface
+type stubEngine struct {
+	EngineInterface
+	result *EvaluateResult
+	err    error
+}
+
+func (e *stubEngine) Evaluate(ctx context.Context, req *EvaluateRequest) (*EvaluateResult, error) {
+	return e.result, e.err
+}
+
+func newTestApp(engine EngineInterface) *fiber.App {
+	return newTestAppWithValidation(engine, config.ValidationConfig{})
+}
+
+func newTestAppWithValidation(engine EngineInterface, validation config.ValidationConfig) *fiber.App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(storage.NewMemoryRepository(), nil, engine, validation, config.EngineConfig{}, logger)
+	app := fiber.New()
+	SetupRoutes(app, h)
+	return app
+}
+
+func TestEvaluateStatus(t *testing.T) {
+	missingImport := &dmnlib.ImportError{Name: "lib", Namespace: "http://example.org/lib", Err: storage.ErrNotFound}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "evaluated",
+			wantStatus: 200,
+		},
+		{
+			name:       "decision not found",
+			err:        fmt.Errorf("decision approval not found: %w", storage.ErrNotFound),
+			wantStatus: 404,
+			wantError:  "decision not found: approval",
+		},
+		{
+			name:       "imported model not found",
+			err:        &EvaluationError{Err: fmt.Errorf("evaluation failed: %w", missingImport)},
+			wantStatus: 422,
+			wantError:  "imported model not found: http://example.org/lib",
+		},
+		{
+			name:       "imported model unreadable",
+			err:        &dmnlib.ImportError{Name: "lib", Namespace: "http://example.org/lib", Err: fmt.Errorf("connection refused")},
+			wantStatus: 500,
+			wantError:  "evaluation failed: import lib (http://example.org/lib): connection refused",
+		},
+		{
+			name:       "timed out",
+			err:        fmt.Errorf("%w: %w", dmnlib.ErrTimeout, context.DeadlineExceeded),
+			wantStatus: 504,
+			wantError:  "evaluation timed out: context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := &stubEngine{err: tt.err}
+			if tt.err == nil {
+				engine.result = &EvaluateResult{DecisionKey: "approval", Outputs: []map[string]interface{}{{"approved": true}}}
+			}
+
+			req := httptest.NewRequest("POST", "/api/v1/evaluate", strings.NewReader(`{"decisionKey": "approval"}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := newTestApp(engine).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantError == "" {
+				return
+			}
+			var body ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+// limitsModel has two decisions with two rules each, nested six elements deep
+const limitsModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Limits" namespace="http://example.org/limits">
+  <inputData id="in_x" name="x"><variable name="x"/></inputData>
+  <decision id="a" name="a">
+    <informationRequirement><requiredInput href="#in_x"/></informationRequirement>
+    <decisionTable hitPolicy="FIRST">
+      <input><inputExpression><text>x</text></inputExpression></input><output name="r"/>
+      <rule id="a1"><inputEntry><text>&lt;= 10</text></inputEntry><outputEntry><text>1</text></outputEntry></rule>
+      <rule id="a2"><inputEntry><text>-</text></inputEntry><outputEntry><text>2</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+  <decision id="b" name="b">
+    <informationRequirement><requiredInput href="#in_x"/></informationRequirement>
+    <decisionTable hitPolicy="FIRST">
+      <input><inputExpression><text>x</text></inputExpression></input><output name="r"/>
+      <rule id="b1"><inputEntry><text>-</text></inputEntry><outputEntry><text>1</text></outputEntry></rule>
+      <rule id="b2"><inputEntry><text>-</text></inputEntry><outputEntry><text>2</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+
+// multipartBody returns a multipart form with the document as its file field
+func multipartBody(t *testing.T, doc string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "model.dmn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(doc))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, w.FormDataContentType()
+}
+
+func TestDeployLimits(t *testing.T) {
+	size := int64(len(limitsModel))
+
+	tests := []struct {
+		name       string
+		limits     config.ParseLimitsConfig
+		wantStatus int
+		wantError  string
+	}{
+		{"no limits", config.ParseLimitsConfig{}, 200, ""},
+		{"bytes at limit", config.ParseLimitsConfig{MaxBytes: size}, 200, ""},
+		{"bytes over limit", config.ParseLimitsConfig{MaxBytes: size - 1}, 413, fmt.Sprintf("DMN document exceeds maxBytes of %d", size-1)},
+		{"decisions at limit", config.ParseLimitsConfig{MaxDecisions: 2}, 200, ""},
+		{"decisions over limit", config.ParseLimitsConfig{MaxDecisions: 1}, 400, "DMN document exceeds maxDecisions of 1 (line 11, column 29)"},
+		{"rules at limit", config.ParseLimitsConfig{MaxRulesPerTable: 2}, 200, ""},
+		{"rules over limit", config.ParseLimitsConfig{MaxRulesPerTable: 1}, 400, "DMN document exceeds maxRulesPerTable of 1 (line 8, column 21)"},
+		{"cell at limit", config.ParseLimitsConfig{MaxCellLength: 5}, 200, ""},
+		{"cell over limit", config.ParseLimitsConfig{MaxCellLength: 4}, 400, "DMN document exceeds maxCellLength of 4 (line 7, column 47)"},
+		{"depth at limit", config.ParseLimitsConfig{MaxDepth: 6}, 200, ""},
+		{"depth over limit", config.ParseLimitsConfig{MaxDepth: 5}, 400, "DMN document exceeds maxDepth of 5 (line 6, column 37)"},
+	}
+
+	for _, tt := range tests {
+		for _, form := range []string{"raw", "multipart"} {
+			t.Run(tt.name+"/"+form, func(t *testing.T) {
+				var req *http.Request
+				if form == "raw" {
+					req = httptest.NewRequest("POST", "/api/v1/definitions/validate", strings.NewReader(limitsModel))
+					req.Header.Set("Content-Type", "application/xml")
+				} else {
+					body, contentType := multipartBody(t, limitsModel)
+					req = httptest.NewRequest("POST", "/api/v1/definitions/validate", body)
+					req.Header.Set("Content-Type", contentType)
+				}
+
+				app := newTestAppWithValidation(nil, config.ValidationConfig{Limits: tt.limits})
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+
+				if resp.StatusCode != tt.wantStatus {
+					t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+				if tt.wantError == "" {
+					return
+				}
+				var body ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Error != tt.wantError {
+					t.Errorf("error = %q, want %q", body.Error, tt.wantError)
+				}
+			})
+		}
+	}
+}
+
+// deployEngine records the definitions the handler deploys and compiles
+type deployEngine struct {
+	stubEngine
+	deployed []string
+	compiled []*storage.Definition
+}
+
+func (e *deployEngine) Functions(tenantID string) map[string]*feel.Function { return nil }
+
+func (e *deployEngine) HitPolicies() []string { return nil }
+
+func (e *deployEngine) DefinitionDeployed(tenantID string) {
+	e.deployed = append(e.deployed, tenantID)
+}
+
+func (e *deployEngine) CompileDefinition(ctx context.Context, def *storage.Definition) {
+	e.compiled = append(e.compiled, def)
+}
+
+const deployModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Deploy" namespace="http://example.org/deploy">
+  <decision id="a" name="a">
+    <variable name="a"/>
+    <literalExpression><text>1</text></literalExpression>
+  </decision>
+</definitions>`
+
+func TestDeployCompiles(t *testing.T) {
+	engine := &deployEngine{}
+	req := httptest.NewRequest("POST", "/api/v1/definitions/", strings.NewReader(deployModel))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("X-Tenant-ID", "acme")
+	resp, err := newTestApp(engine).Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != 201 {
+		t.Fatalf("status = %d, want 201: %s", resp.StatusCode, data)
+	}
+	if len(engine.deployed) != 1 || engine.deployed[0] != "acme" {
+		t.Errorf("deployed for tenants %q", engine.deployed)
+	}
+	if len(engine.compiled) != 1 {
+		t.Fatalf("compiled %d definitions, want 1", len(engine.compiled))
+	}
+	if def := engine.compiled[0]; def.Key != "a" || def.Version != 1 || def.TenantID != "acme" || def.ParsedModel == nil {
+		t.Errorf("compiled %+v", def)
+	}
+}
+
+// warningModel deploys, but its second rule repeats the first one
+const warningModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Warnings" namespace="http://example.org/warnings">
+  <inputData id="in_x" name="x"><variable name="x"/></inputData>
+  <decision id="w" name="w">
+    <variable name="w"/>
+    <informationRequirement><requiredInput href="#in_x"/></informationRequirement>
+    <decisionTable id="wt" hitPolicy="FIRST">
+      <input><inputExpression><text>x</text></inputExpression></input><output name="r"/>
+      <rule id="w1"><inputEntry><text>-</text></inputEntry><outputEntry><text>1</text></outputEntry></rule>
+      <rule id="w2"><inputEntry><text>-</text></inputEntry><outputEntry><text>2</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+
+func TestValidateDefinition(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		valid       bool
+		decisionIDs []string
+		findings    []string // codes
+		blocking    []string
+	}{
+		{"valid", deployModel, true, []string{"a"}, nil, nil},
+		{"warnings", warningModel, true, []string{"w"}, []string{"unreachable-rule", "duplicate-rule"}, nil},
+		{
+			"blocking", strings.Replace(warningModel, "<text>2</text>", "<text>bonus</text>", 1), false, []string{"w"},
+			[]string{"unknown-name", "unreachable-rule", "duplicate-rule"}, []string{"unknown-name"},
+		},
+		{"blocking in every decision", limitsModel, false, []string{"a", "b"}, []string{"missing-variable", "missing-variable", "unreachable-rule", "duplicate-rule"}, []string{"missing-variable", "missing-variable"}},
+	}
+
+	codes := func(findings []dmn.ValidationError) []string {
+		var result []string
+		for _, f := range findings {
+			result = append(result, f.Code)
+		}
+		return result
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(nil)
+			req := httptest.NewRequest("POST", "/api/v1/definitions/validate", strings.NewReader(tt.doc))
+			req.Header.Set("Content-Type", "application/xml")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != 200 {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+
+			var body ValidateResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Valid != tt.valid {
+				t.Errorf("valid = %v, want %v", body.Valid, tt.valid)
+			}
+			if !reflect.DeepEqual(body.DecisionIDs, tt.decisionIDs) {
+				t.Errorf("decisionIds = %v, want %v", body.DecisionIDs, tt.decisionIDs)
+			}
+			if body.Findings == nil {
+				t.Error("findings are not a list")
+			}
+			if got := codes(body.Findings); !reflect.DeepEqual(got, tt.findings) {
+				t.Errorf("findings = %v, want %v", got, tt.findings)
+			}
+			if got := codes(body.Blocking); !reflect.DeepEqual(got, tt.blocking) {
+				t.Errorf("blocking = %v, want %v", got, tt.blocking)
+			}
+
+			// Nothing is deployed
+			resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/definitions/"+tt.decisionIDs[0], nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != 404 {
+				t.Errorf("definition after validation: status = %d, want 404", resp.StatusCode)
+			}
+		})
+	}
+}
+
+// taggedModel is deployModel with a camunda:versionTag on its decision
+func taggedModel(tag, value string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" xmlns:camunda="http://camunda.org/schema/1.0/dmn" id="defs" name="Tagged" namespace="http://example.org/tagged">
+  <decision id="a" name="a" camunda:versionTag="` + tag + `">
+    <variable name="a"/>
+    <literalExpression><text>` + value + `</text></literalExpression>
+  </decision>
+</definitions>`
+}
+
+func TestVersionTag(t *testing.T) {
+	app := newTestApp(&deployEngine{})
+	get := func(path string, out interface{}) int {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == 200 {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	for i, tag := range []string{"2.0", "2.1", "2.0"} {
+		req := httptest.NewRequest("POST", "/api/v1/definitions/", strings.NewReader(taggedModel(tag, fmt.Sprint(i))))
+		req.Header.Set("Content-Type", "application/xml")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var def DefinitionResponse
+		json.NewDecoder(resp.Body).Decode(&def)
+		resp.Body.Close()
+		if resp.StatusCode != 201 || def.VersionTag != tag {
+			t.Fatalf("deploy %d: status = %d, versionTag = %q, want 201 and %q", i, resp.StatusCode, def.VersionTag, tag)
+		}
+	}
+
+	tests := []struct {
+		query   string
+		status  int
+		version int
+	}{
+		{"", 200, 3},
+		{"?versionTag=2.1", 200, 2},
+		{"?versionTag=2.0", 200, 3},
+		{"?versionTag=3.0", 404, 0},
+		{"?version=1&versionTag=2.1", 200, 1},
+	}
+	for _, tt := range tests {
+		var def DefinitionResponse
+		if status := get("/api/v1/definitions/a"+tt.query, &def); status != tt.status {
+			t.Errorf("GET a%s: status = %d, want %d", tt.query, status, tt.status)
+		} else if status == 200 && def.Version != tt.version {
+			t.Errorf("GET a%s: version = %d, want %d", tt.query, def.Version, tt.version)
+		}
+	}
+
+	// The list holds the latest version carrying the tag
+	lists := []struct {
+		tag      string
+		versions []int
+	}{
+		{"2.1", []int{2}},
+		{"2.0", []int{3}},
+		{"3.0", []int{}},
+	}
+	for _, tt := range lists {
+		var defs []DefinitionResponse
+		if status := get("/api/v1/definitions/?versionTag="+tt.tag, &defs); status != 200 {
+			t.Fatalf("list %s: status = %d", tt.tag, status)
+		}
+		versions := []int{}
+		for _, def := range defs {
+			if def.VersionTag != tt.tag {
+				t.Errorf("list %s: got versionTag %q", tt.tag, def.VersionTag)
+			}
+			versions = append(versions, def.Version)
+		}
+		if !reflect.DeepEqual(versions, tt.versions) {
+			t.Errorf("list %s: versions = %v, want %v", tt.tag, versions, tt.versions)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// maxHistoryLimit bounds the number of evaluations returned by one query
+const maxHistoryLimit = 1000
+
+// GetHistory handles GET /api/v1/history
+func (h *Handler) GetHistory(c *fiber.Ctx) error {
+	if h.history == nil {
+		return c.Status(503).JSON(ErrorResponse{Error: "evaluation history is disabled"})
+	}
+
+	filter := &storage.HistoryFilter{
+		DecisionKey: c.Query("decisionKey"),
+		Version:     c.QueryInt("version", 0),
+		TenantID:    c.Get("X-Tenant-ID"),
+		RuleID:      c.Query("ruleId"),
+		Limit:       c.QueryInt("limit", 100),
+		Offset:      c.QueryInt("offset", 0),
+	}
+	if filter.Limit <= 0 || filter.Limit > maxHistoryLimit {
+		filter.Limit = maxHistoryLimit
+	}
+
+	var err error
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		return c.Status(400).JSON(ErrorResponse{Error: err.Error()})
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		return c.Status(400).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	entries, err := h.history.Query(c.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to query history", "error", err)
+		return c.Status(500).JSON(ErrorResponse{Error: "failed to query history"})
+	}
+
+	return c.JSON(entries)
+}
+
+// queryTime parses an RFC 3339 query parameter; a missing one is the zero time
+func queryTime(c *fiber.Ctx, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time: %w", name, err)
+	}
+	return t, nil
+}
+
+// newHistoryEntry records a successful evaluation. Strings taken from the
+// request may point into buffers fasthttp reuses, so they are copied
+func newHistoryEntry(tenantID string, variables map[string]interface{}, result *EvaluateResult, requestID string) *storage.HistoryEntry {
+	rules := make([]storage.HistoryRule, len(result.MatchedRules))
+	for i, m := range result.MatchedRules {
+		rules[i] = storage.HistoryRule{RuleID: m.RuleID, RuleIndex: m.RuleIndex}
+	}
+	return &storage.HistoryEntry{
+		DefinitionID: result.DefinitionID,
+		DecisionKey:  strings.Clone(result.DecisionKey),
+		DecisionName: result.DecisionName,
+		Version:      result.Version,
+		TenantID:     strings.Clone(tenantID),
+		Inputs:       variables,
+		Outputs:      result.Outputs,
+		MatchedRules: rules,
+		DurationNs:   result.DurationNs,
+		RequestID:    strings.Clone(requestID),
+		EvaluatedAt:  result.EvaluatedAt,
+	}
+}
+
+// recordHistory saves evaluations to the history, if it is enabled. The
+// evaluations have succeeded already, so a failure is only logged
+func recordHistory(ctx context.Context, history storage.HistoryRepository, logger *slog.Logger, entries []*storage.HistoryEntry) {
+	if history == nil || len(entries) == 0 {
+		return
+	}
+	if err := history.Record(ctx, entries); err != nil {
+		logger.Warn("failed to record evaluation history",
+			"entries", len(entries),
+			"error", err,
+		)
+	}
+}
+
+// requestID returns the ID assigned by RequestIDMiddleware
+func requestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("requestId").(string)
+	return id
+}
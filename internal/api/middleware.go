@@ -1,6 +1,8 @@
 package api
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"time"
 
@@ -58,3 +60,32 @@ func TenantMiddleware() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// BodyLimitMiddleware rejects request bodies above limit bytes with 413.
+// The server streams request bodies so that NDJSON evaluation streams can
+// be of any size; every other body, including NDJSON sent to other routes,
+// is limited here
+func BodyLimitMiddleware(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if isEvaluationStream(c) {
+			return c.Next()
+		}
+
+		if c.Request().Header.ContentLength() > limit {
+			return c.Status(413).JSON(ErrorResponse{Error: fmt.Sprintf("request body exceeds %d bytes", limit)})
+		}
+
+		// Chunked bodies have no length up front
+		if stream := c.Context().RequestBodyStream(); stream != nil && c.Request().Header.ContentLength() < 0 {
+			body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+			if err != nil {
+				return c.Status(400).JSON(ErrorResponse{Error: "failed to read request body"})
+			}
+			if len(body) > limit {
+				return c.Status(413).JSON(ErrorResponse{Error: fmt.Sprintf("request body exceeds %d bytes", limit)})
+			}
+			c.Request().SetBody(body)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/konstantin/dmn-engine-go/internal/config"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// newStreamingApp serves the API like the server does: request bodies are
+// streamed and limited by BodyLimitMiddleware
+func newStreamingApp(bodyLimit int, validation config.ValidationConfig) *fiber.App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(storage.NewMemoryRepository(), nil, nil, validation, config.EngineConfig{}, logger)
+	app := fiber.New(fiber.Config{BodyLimit: bodyLimit, StreamRequestBody: true})
+	app.Use(BodyLimitMiddleware(bodyLimit))
+	SetupRoutes(app, h)
+	return app
+}
+
+// newChunkedRequest returns a request whose body is sent without a length
+func newChunkedRequest(path string, body io.Reader) *http.Request {
+	req := httptest.NewRequest("POST", path, io.MultiReader(body))
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	return req
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	ndjson := strings.Repeat(`{"a": 1}`+"\n", 50)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		chunked     bool
+		wantStatus  int
+	}{
+		{"stream", "/api/v1/evaluate/stream", "application/x-ndjson", false, 400},
+		{"stream with trailing slash", "/api/v1/evaluate/stream/", "application/x-ndjson; charset=utf-8", false, 400},
+		{"stream of JSON", "/api/v1/evaluate/stream", "application/json", false, 413},
+		{"NDJSON to batch", "/api/v1/evaluate/batch", "application/x-ndjson", false, 413},
+		{"NDJSON to deploy", "/api/v1/definitions/", "application/x-ndjson", false, 413},
+		{"chunked NDJSON to validate", "/api/v1/definitions/validate", "application/x-ndjson", true, 413},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(ndjson))
+			if tt.chunked {
+				req = newChunkedRequest(tt.path, strings.NewReader(ndjson))
+			}
+			req.Header.Set("Content-Type", tt.contentType)
+
+			resp, err := newStreamingApp(100, config.ValidationConfig{}).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			// The stream handler rejects the request for its missing
+			// decisionKey, after the middleware let it through
+			if resp.StatusCode != tt.wantStatus {
+				data, _ := io.ReadAll(resp.Body)
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, data)
+			}
+			if tt.wantStatus == 413 {
+				var errResp ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+					t.Fatal(err)
+				}
+				if errResp.Error != "request body exceeds 100 bytes" {
+					t.Errorf("error = %q", errResp.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestReadDMNContentStreamed(t *testing.T) {
+	// Larger than the part of a streamed body the server reads up front
+	doc := limitsModel + strings.Repeat(" ", 16*1024)
+	size := int64(len(doc))
+
+	tests := []struct {
+		name       string
+		maxBytes   int64
+		chunked    bool
+		multipart  bool
+		wantStatus int
+	}{
+		{"raw at limit", size, false, false, 200},
+		{"raw over limit", size - 1, false, false, 413},
+		{"chunked raw at limit", size, true, false, 200},
+		{"chunked raw over limit", size - 1, true, false, 413},
+		{"multipart at limit", size, false, true, 200},
+		{"multipart over limit", size - 1, false, true, 413},
+		{"chunked multipart over limit", size - 1, true, true, 413},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(doc)
+			contentType := "application/xml"
+			if tt.multipart {
+				body, contentType = multipartBody(t, doc)
+			}
+			req := httptest.NewRequest("POST", "/api/v1/definitions/validate", body)
+			if tt.chunked {
+				req = newChunkedRequest("/api/v1/definitions/validate", body)
+			}
+			req.Header.Set("Content-Type", contentType)
+
+			validation := config.ValidationConfig{Limits: config.ParseLimitsConfig{MaxBytes: tt.maxBytes}}
+			resp, err := newStreamingApp(1024*1024, validation).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			data, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, data)
+			}
+			if want := fmt.Sprintf("DMN document exceeds maxBytes of %d", tt.maxBytes); tt.wantStatus == 413 && !strings.Contains(string(data), want) {
+				t.Errorf("body = %s, want %q", data, want)
+			}
+		})
+	}
+}
@@ -19,13 +19,23 @@ func SetupRoutes(app *fiber.App, h *Handler) {
 	// Definitions (Decision Definition management)
 	definitions := v1.Group("/definitions")
 	definitions.Post("/", h.DeployDefinition)                  // Deploy new definition
+	definitions.Post("/validate", h.ValidateDefinition)        // Validate without deploying
 	definitions.Get("/", h.ListDefinitions)                    // List all definitions
 	definitions.Get("/:key", h.GetDefinition)                  // Get definition by key
 	definitions.Get("/:key/xml", h.GetDefinitionXML)           // Get original XML
 	definitions.Get("/:key/parsed", h.GetDefinitionParsed)     // Get parsed model
 	definitions.Get("/:key/versions", h.GetDefinitionVersions) // Get all versions
+	definitions.Get("/:key/analysis", h.GetDefinitionAnalysis) // Analyze decision tables
 	definitions.Delete("/:key", h.DeleteDefinition)            // Delete definition
 
 	// Evaluation
-	v1.Post("/evaluate", h.Evaluate) // Evaluate a decision
+	v1.Post("/evaluate", h.Evaluate)              // Evaluate a decision
+	v1.Post("/evaluate/batch", h.EvaluateBatch)   // Evaluate a decision for many variable sets
+	v1.Post("/evaluate/stream", h.EvaluateStream) // Evaluate NDJSON lines as a stream
+
+	// Evaluation history
+	v1.Get("/history", h.GetHistory) // Query recorded evaluations
+
+	// Compiled-definition cache
+	v1.Get("/cache/stats", h.CacheStats) // Cache hit/miss statistics
 }
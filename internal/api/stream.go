@@ -0,0 +1,311 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// maxStreamLineBytes bounds a single line of an NDJSON evaluation stream
+const maxStreamLineBytes = 1024 * 1024
+
+// Error policies of an evaluation stream
+const (
+	StreamOnErrorSkip  = "skip"  // report the failed line and continue
+	StreamOnErrorAbort = "abort" // report the failed line and end the stream
+)
+
+// StreamLine is a result line of an evaluation stream
+type StreamLine struct {
+	Line   int             `json:"line"` // line number in the request, starting at 1
+	Result *EvaluateResult `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// StreamSummary is the last line of an evaluation stream
+type StreamSummary struct {
+	Done      bool   `json:"done"`
+	Processed int    `json:"processed"`
+	Failed    int    `json:"failed"`
+	Aborted   bool   `json:"aborted,omitempty"`
+	Error     string `json:"error,omitempty"` // the request body could not be read to the end
+}
+
+// streamPath is the route of EvaluateStream
+const streamPath = "/api/v1/evaluate/stream"
+
+// isNDJSON reports whether the request body is newline-delimited JSON
+func isNDJSON(c *fiber.Ctx) bool {
+	return strings.HasPrefix(c.Get("Content-Type"), "application/x-ndjson")
+}
+
+// isEvaluationStream reports whether the request is an NDJSON evaluation
+// stream, whose body is read line by line instead of into memory
+func isEvaluationStream(c *fiber.Ctx) bool {
+	return c.Method() == fiber.MethodPost && strings.TrimSuffix(c.Path(), "/") == streamPath && isNDJSON(c)
+}
+
+// EvaluateStream handles POST /api/v1/evaluate/stream. The body holds one
+// JSON object of variables per line; a result line is written for each of
+// them in input order as soon as it is computed. At most BatchWorkers lines
+// are evaluated at a time and no more input is read while the client is
+// not reading results
+func (h *Handler) EvaluateStream(c *fiber.Ctx) error {
+	if !isNDJSON(c) {
+		return c.Status(415).JSON(ErrorResponse{Error: "content type must be application/x-ndjson"})
+	}
+
+	decisionKey := c.Query("decisionKey")
+	if decisionKey == "" {
+		return c.Status(400).JSON(ErrorResponse{Error: "decisionKey is required"})
+	}
+
+	var version *int
+	if v := c.QueryInt("version"); v > 0 {
+		version = &v
+	}
+
+	onError := c.Query("onError", StreamOnErrorSkip)
+	if onError != StreamOnErrorSkip && onError != StreamOnErrorAbort {
+		return c.Status(400).JSON(ErrorResponse{Error: "onError must be skip or abort"})
+	}
+
+	if h.engine == nil {
+		return c.Status(503).JSON(ErrorResponse{Error: "evaluation engine not available"})
+	}
+
+	// Resolve the decision once, so a wrong key fails the request instead
+	// of every line, and every line evaluates the same version even when
+	// another one is deployed during the stream
+	tenantID := c.Get("X-Tenant-ID")
+	var def *storage.Definition
+	var err error
+	if version != nil {
+		def, err = h.repo.GetByDecisionIDAndVersion(c.Context(), decisionKey, *version, tenantID)
+	} else {
+		def, err = h.repo.GetByDecisionID(c.Context(), decisionKey, tenantID)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(404).JSON(ErrorResponse{Error: "decision not found: " + decisionKey})
+	}
+	if err != nil {
+		return c.Status(500).JSON(ErrorResponse{Error: "failed to resolve decision: " + err.Error()})
+	}
+	version = &def.Version
+
+	body := c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(append([]byte(nil), c.Body()...))
+	}
+
+	s := &evaluationStream{
+		engine:      h.engine,
+		history:     h.history,
+		logger:      h.logger,
+		conn:        c.Context().Conn(),
+		idleTimeout: h.evaluation.StreamIdleTimeout,
+		workers:     h.evaluation.BatchWorkers,
+		abort:       onError == StreamOnErrorAbort,
+		requestID:   requestID(c),
+		request: EvaluateRequest{
+			DecisionKey: decisionKey,
+			Version:     version,
+			TenantID:    tenantID,
+		},
+	}
+
+	// Unread input may remain on an aborted stream
+	c.Context().Response.Header.SetConnectionClose()
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		s.run(body, w)
+	})
+	return nil
+}
+
+// evaluationStream evaluates the lines of an NDJSON request body. It runs
+// after the handler has returned, so it holds everything it needs from the
+// request
+type evaluationStream struct {
+	engine      EngineInterface
+	history     storage.HistoryRepository
+	logger      *slog.Logger
+	conn        net.Conn
+	idleTimeout time.Duration
+	workers     int
+	abort       bool
+	requestID   string
+	request     EvaluateRequest // decision reference shared by all lines
+
+	processed, failed int
+}
+
+// pendingLine is a line whose evaluation is in progress
+type pendingLine struct {
+	result chan StreamLine
+}
+
+func (s *evaluationStream) run(body io.Reader, w *bufio.Writer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	workers := s.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineBytes)
+	enc := json.NewEncoder(w)
+
+	var queue []*pendingLine
+	summary := StreamSummary{Done: true}
+	line := 0
+	eof := false
+
+	for !eof || len(queue) > 0 {
+		// Read ahead while the window has room
+		if !eof && len(queue) < workers {
+			// Write what is ready before blocking on the client
+			if !s.writeReady(enc, &queue, false, &summary) || !s.flush(w) {
+				break
+			}
+			if summary.Aborted {
+				break
+			}
+
+			s.setDeadline(false)
+			if !scanner.Scan() {
+				eof = true
+				if err := scanner.Err(); err != nil {
+					summary.Error = fmt.Sprintf("line %d: %v", line+1, err)
+				}
+				continue
+			}
+			line++
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			queue = append(queue, s.start(ctx, line, scanner.Bytes()))
+			continue
+		}
+
+		// The window is full or the input is exhausted: wait for the oldest line
+		if !s.writeReady(enc, &queue, true, &summary) {
+			break
+		}
+		if summary.Aborted {
+			break
+		}
+	}
+
+	summary.Processed = s.processed
+	summary.Failed = s.failed
+	if err := enc.Encode(summary); err == nil {
+		s.flush(w)
+	}
+
+	s.logger.Info("stream evaluated",
+		"decisionKey", s.request.DecisionKey,
+		"processed", s.processed,
+		"failed", s.failed,
+		"aborted", summary.Aborted,
+		"request_id", s.requestID,
+	)
+}
+
+// start parses a line and evaluates it in the background
+func (s *evaluationStream) start(ctx context.Context, line int, data []byte) *pendingLine {
+	p := &pendingLine{result: make(chan StreamLine, 1)}
+
+	var variables map[string]interface{}
+	if err := json.Unmarshal(data, &variables); err != nil {
+		p.result <- StreamLine{Line: line, Error: "invalid JSON: " + err.Error()}
+		return p
+	}
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+
+	req := s.request
+	req.Variables = variables
+	go func() {
+		result, err := s.engine.Evaluate(ctx, &req)
+		if err != nil {
+			p.result <- StreamLine{Line: line, Error: err.Error()}
+			return
+		}
+		recordHistory(ctx, s.history, s.logger, []*storage.HistoryEntry{
+			newHistoryEntry(req.TenantID, variables, result, s.requestID),
+		})
+		p.result <- StreamLine{Line: line, Result: result}
+	}()
+	return p
+}
+
+// writeReady writes the finished lines at the head of the queue. With block
+// set it waits for the oldest line. It returns false if the client is gone
+func (s *evaluationStream) writeReady(enc *json.Encoder, queue *[]*pendingLine, block bool, summary *StreamSummary) bool {
+	for len(*queue) > 0 {
+		var out StreamLine
+		if block {
+			out = <-(*queue)[0].result
+			block = false
+		} else {
+			select {
+			case out = <-(*queue)[0].result:
+			default:
+				return true
+			}
+		}
+		*queue = (*queue)[1:]
+
+		s.processed++
+		if out.Error != "" {
+			s.failed++
+		}
+		if err := enc.Encode(out); err != nil {
+			return false
+		}
+		if out.Error != "" && s.abort {
+			summary.Aborted = true
+			return true
+		}
+	}
+	return true
+}
+
+// flush sends buffered result lines to the client
+func (s *evaluationStream) flush(w *bufio.Writer) bool {
+	s.setDeadline(true)
+	return w.Flush() == nil
+}
+
+// setDeadline moves the read or write deadline of the connection
+// idleTimeout ahead; the server timeouts apply to the whole request and
+// would cut long streams short
+func (s *evaluationStream) setDeadline(write bool) {
+	if s.conn == nil {
+		return
+	}
+	var deadline time.Time
+	if s.idleTimeout > 0 {
+		deadline = time.Now().Add(s.idleTimeout)
+	}
+	if write {
+		s.conn.SetWriteDeadline(deadline)
+	} else {
+		s.conn.SetReadDeadline(deadline)
+	}
+}
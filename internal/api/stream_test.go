@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/konstantin/dmn-engine-go/internal/config"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// lineEngine evaluates stream lines from their variables: a line waits
+// "delay" milliseconds, fails when "fail" is set and otherwise returns "n".
+// It records the version every line asked for
+type lineEngine struct {
+	stubEngine
+	onEvaluate func() // called before each evaluation
+
+	mu       sync.Mutex
+	versions []int
+}
+
+func (e *lineEngine) Evaluate(ctx context.Context, req *EvaluateRequest) (*EvaluateResult, error) {
+	if e.onEvaluate != nil {
+		e.onEvaluate()
+	}
+	e.mu.Lock()
+	version := 0
+	if req.Version != nil {
+		version = *req.Version
+	}
+	e.versions = append(e.versions, version)
+	e.mu.Unlock()
+
+	if delay, ok := req.Variables["delay"].(float64); ok {
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+	}
+	if req.Variables["fail"] == true {
+		return nil, fmt.Errorf("evaluation failed")
+	}
+	return &EvaluateResult{DecisionKey: req.DecisionKey, Version: version, Outputs: []map[string]interface{}{{"n": req.Variables["n"]}}}, nil
+}
+
+// deployStreamModel deploys a new version of the definition holding the
+// decision "d"
+func deployStreamModel(t *testing.T, repo storage.DefinitionRepository) {
+	t.Helper()
+	if err := repo.Deploy(context.Background(), &storage.Definition{Key: "d", DecisionIDs: []string{"d"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// evaluateStream posts an NDJSON body to the stream endpoint and returns
+// the status and the lines of the response
+func evaluateStream(t *testing.T, repo storage.DefinitionRepository, engine EngineInterface, query, body string) (int, []string) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(repo, nil, engine, config.ValidationConfig{}, config.EngineConfig{BatchWorkers: 4}, logger)
+	app := fiber.New()
+	SetupRoutes(app, h)
+
+	req := httptest.NewRequest("POST", "/api/v1/evaluate/stream?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, lines
+}
+
+func TestEvaluateStream(t *testing.T) {
+	// Earlier lines take longer, so they finish after later ones
+	body := `{"n": 1, "delay": 30}
+{"n": 2, "delay": 20}
+
+{"n": 3, "fail": true}
+not json
+{"n": 5}
+`
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "skip",
+			query: "decisionKey=d",
+			want: []string{
+				`{"line":1,"n":1}`,
+				`{"line":2,"n":2}`,
+				`{"line":4,"error":"evaluation failed"}`,
+				`{"line":5,"error":"invalid JSON: invalid character 'o' in literal null (expecting 'u')"}`,
+				`{"line":6,"n":5}`,
+				`{"done":true,"processed":5,"failed":2}`,
+			},
+		},
+		{
+			name:  "abort",
+			query: "decisionKey=d&onError=abort",
+			want: []string{
+				`{"line":1,"n":1}`,
+				`{"line":2,"n":2}`,
+				`{"line":4,"error":"evaluation failed"}`,
+				`{"done":true,"processed":3,"failed":1,"aborted":true}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storage.NewMemoryRepository()
+			deployStreamModel(t, repo)
+			status, lines := evaluateStream(t, repo, &lineEngine{}, tt.query, body)
+			if status != 200 {
+				t.Fatalf("status = %d: %v", status, lines)
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(tt.want), strings.Join(lines, "\n"))
+			}
+			for i, line := range lines {
+				if got := compactLine(t, line); got != tt.want[i] {
+					t.Errorf("line %d = %s, want %s", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// compactLine reduces a result line to its line number and output "n" or
+// error; other lines are returned as they are
+func compactLine(t *testing.T, line string) string {
+	t.Helper()
+	var out StreamLine
+	if err := json.Unmarshal([]byte(line), &out); err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case out.Error != "":
+		return fmt.Sprintf(`{"line":%d,"error":%q}`, out.Line, out.Error)
+	case out.Result != nil:
+		return fmt.Sprintf(`{"line":%d,"n":%v}`, out.Line, out.Result.Outputs[0]["n"])
+	}
+	return line
+}
+
+func TestEvaluateStreamVersion(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	deployStreamModel(t, repo)
+	deployStreamModel(t, repo)
+
+	// A version deployed while the stream runs is not evaluated
+	var once sync.Once
+	engine := &lineEngine{onEvaluate: func() {
+		once.Do(func() { deployStreamModel(t, repo) })
+	}}
+	status, lines := evaluateStream(t, repo, engine, "decisionKey=d", strings.Repeat(`{"n": 1}`+"\n", 10))
+	if status != 200 || len(lines) != 11 {
+		t.Fatalf("status = %d, %d lines", status, len(lines))
+	}
+	for i, version := range engine.versions {
+		if version != 2 {
+			t.Errorf("evaluation %d asked for version %d, want 2", i, version)
+		}
+	}
+
+	engine = &lineEngine{}
+	if status, _ = evaluateStream(t, repo, engine, "decisionKey=d&version=1", `{"n": 1}`); status != 200 || engine.versions[0] != 1 {
+		t.Errorf("status = %d, versions %v, want version 1", status, engine.versions)
+	}
+	if status, _ = evaluateStream(t, repo, &lineEngine{}, "decisionKey=d&version=9", `{"n": 1}`); status != 404 {
+		t.Errorf("status of a missing version = %d, want 404", status)
+	}
+}
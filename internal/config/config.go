@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,6 +16,15 @@ type Config struct {
 	// Database
 	Database DatabaseConfig
 
+	// Validation
+	Validation ValidationConfig
+
+	// Engine
+	Engine EngineConfig
+
+	// Evaluation history
+	History HistoryConfig
+
 	// Logging
 	LogLevel string
 }
@@ -43,6 +54,86 @@ type DatabaseConfig struct {
 	HealthCheckPeriod time.Duration
 }
 
+// EngineConfig holds evaluation engine settings
+type EngineConfig struct {
+	// CacheSize is the number of compiled definitions kept in memory.
+	// Zero disables the cache
+	CacheSize int
+
+	// CacheLatestTTL bounds how long the resolution of a decision to its
+	// latest version is cached. Deploys and deletes through this instance
+	// invalidate it immediately; the TTL picks up changes made by other
+	// instances sharing the database. Zero means no expiry
+	CacheLatestTTL time.Duration
+
+	// EvaluationTimeout bounds a single evaluation, including each item of
+	// a batch and each line of a stream. Zero means no limit
+	EvaluationTimeout time.Duration
+
+	// RuleIndex looks up candidate rules of large decision tables in
+	// per-column indexes instead of testing every rule
+	RuleIndex bool
+
+	// BatchWorkers is the number of items of a batch or lines of a stream
+	// evaluated concurrently
+	BatchWorkers int
+
+	// BatchMaxItems is the largest number of items accepted in one batch
+	BatchMaxItems int
+
+	// StreamIdleTimeout is how long an evaluation stream waits for the
+	// client to send a line or to accept results. Zero means no limit
+	StreamIdleTimeout time.Duration
+}
+
+// HistoryConfig holds evaluation history settings
+type HistoryConfig struct {
+	// Enabled records every successful evaluation
+	Enabled bool
+
+	// Retention is how long evaluations are kept. Zero keeps them forever
+	Retention time.Duration
+
+	// CleanupInterval is how often evaluations older than Retention are
+	// removed
+	CleanupInterval time.Duration
+}
+
+// ValidationConfig holds deploy validation settings
+type ValidationConfig struct {
+	// StrictParse checks documents against the DMN schema by default. The
+	// "strict" query parameter overrides it per request
+	StrictParse bool
+
+	// BlockingCodes are the lint codes that block deployment in addition
+	// to errors
+	BlockingCodes []string
+
+	// TenantBlockingCodes overrides BlockingCodes for individual tenants
+	TenantBlockingCodes map[string][]string
+
+	// Limits bound uploaded documents
+	Limits ParseLimitsConfig
+}
+
+// ParseLimitsConfig holds size and complexity limits for uploaded DMN
+// documents. Zero disables a limit
+type ParseLimitsConfig struct {
+	MaxBytes         int64
+	MaxDecisions     int
+	MaxRulesPerTable int
+	MaxCellLength    int
+	MaxDepth         int
+}
+
+// BlockingCodesFor returns the lint codes that block deployment for a tenant
+func (c *ValidationConfig) BlockingCodesFor(tenantID string) []string {
+	if codes, ok := c.TenantBlockingCodes[tenantID]; ok {
+		return codes
+	}
+	return c.BlockingCodes
+}
+
 // ConnectionString returns PostgreSQL connection string
 func (c *DatabaseConfig) ConnectionString() string {
 	if c.URL != "" {
@@ -73,6 +164,32 @@ func Load() *Config {
 			MaxConnIdleTime:   getEnvDuration("DB_MAX_CONN_IDLE_TIME", 30*time.Minute),
 			HealthCheckPeriod: getEnvDuration("DB_HEALTH_CHECK_PERIOD", time.Minute),
 		},
+		Validation: ValidationConfig{
+			StrictParse:         getEnvBool("VALIDATION_STRICT_PARSE", false),
+			BlockingCodes:       getEnvList("VALIDATION_BLOCKING_CODES"),
+			TenantBlockingCodes: getEnvTenantLists("VALIDATION_TENANT_BLOCKING_CODES"),
+			Limits: ParseLimitsConfig{
+				MaxBytes:         int64(getEnvInt("DMN_MAX_BYTES", 4*1024*1024)),
+				MaxDecisions:     getEnvInt("DMN_MAX_DECISIONS", 1000),
+				MaxRulesPerTable: getEnvInt("DMN_MAX_RULES_PER_TABLE", 10000),
+				MaxCellLength:    getEnvInt("DMN_MAX_CELL_LENGTH", 16*1024),
+				MaxDepth:         getEnvInt("DMN_MAX_XML_DEPTH", 64),
+			},
+		},
+		Engine: EngineConfig{
+			CacheSize:         getEnvInt("ENGINE_CACHE_SIZE", 256),
+			CacheLatestTTL:    getEnvDuration("ENGINE_CACHE_LATEST_TTL", 0),
+			EvaluationTimeout: getEnvDuration("ENGINE_EVALUATION_TIMEOUT", 5*time.Second),
+			RuleIndex:         getEnvBool("ENGINE_RULE_INDEX", true),
+			BatchWorkers:      getEnvInt("ENGINE_BATCH_WORKERS", runtime.NumCPU()),
+			BatchMaxItems:     getEnvInt("ENGINE_BATCH_MAX_ITEMS", 50000),
+			StreamIdleTimeout: getEnvDuration("ENGINE_STREAM_IDLE_TIMEOUT", time.Minute),
+		},
+		History: HistoryConfig{
+			Enabled:         getEnvBool("HISTORY_ENABLED", false),
+			Retention:       getEnvDuration("HISTORY_RETENTION", 30*24*time.Hour),
+			CleanupInterval: getEnvDuration("HISTORY_CLEANUP_INTERVAL", time.Hour),
+		},
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 	}
 }
@@ -101,3 +218,52 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated list
+func getEnvList(key string) []string {
+	return splitList(os.Getenv(key), ",")
+}
+
+// getEnvTenantLists reads per-tenant lists in the form
+// "tenant-a=code1,code2;tenant-b=code3". An empty list is kept, so a tenant
+// can opt out of the global setting with "tenant-a="
+func getEnvTenantLists(key string) map[string][]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	result := make(map[string][]string)
+	for _, entry := range strings.Split(value, ";") {
+		tenant, list, ok := strings.Cut(entry, "=")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || tenant == "" {
+			continue
+		}
+		codes := splitList(list, ",")
+		if codes == nil {
+			codes = []string{}
+		}
+		result[tenant] = codes
+	}
+	return result
+}
+
+func splitList(value, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
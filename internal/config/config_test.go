@@ -0,0 +1,33 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlockingCodesFor(t *testing.T) {
+	t.Setenv("VALIDATION_BLOCKING_CODES", "rule-missing-id, duplicate-rule")
+	t.Setenv("VALIDATION_TENANT_BLOCKING_CODES", "acme=hit-policy-omitted,incomplete-table; lax= ;=ignored;broken")
+	cfg := Load().Validation
+
+	tests := []struct {
+		tenantID string
+		want     []string
+	}{
+		{"", []string{"rule-missing-id", "duplicate-rule"}},
+		{"other", []string{"rule-missing-id", "duplicate-rule"}},
+		{"acme", []string{"hit-policy-omitted", "incomplete-table"}},
+		{"lax", []string{}}, // overrides the defaults with no codes
+	}
+	for _, tt := range tests {
+		t.Run(tt.tenantID, func(t *testing.T) {
+			if got := cfg.BlockingCodesFor(tt.tenantID); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BlockingCodesFor(%q) = %q, want %q", tt.tenantID, got, tt.want)
+			}
+		})
+	}
+
+	if len(cfg.TenantBlockingCodes) != 2 {
+		t.Errorf("tenant codes = %v, want acme and lax only", cfg.TenantBlockingCodes)
+	}
+}
@@ -0,0 +1,675 @@
+package dmn
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+)
+
+// maxReportedGaps bounds the number of gaps reported for a single table
+const maxReportedGaps = 20
+
+// TableAnalysis is the result of the static analysis of a decision table
+type TableAnalysis struct {
+	Overlaps      []RuleOverlap  `json:"overlaps"`
+	Gaps          []Gap          `json:"gaps"`
+	Subsumed      []SubsumedRule `json:"subsumed"`
+	SkippedInputs []int          `json:"skippedInputs,omitempty"` // inputs with cells that cannot be analyzed
+	GapsTruncated bool           `json:"gapsTruncated,omitempty"` // more than maxReportedGaps gaps exist
+}
+
+// RuleRef identifies a rule by its position and ID
+type RuleRef struct {
+	Index int    `json:"index"` // position of the rule in the table, starting at 0
+	ID    string `json:"id,omitempty"`
+}
+
+// RuleOverlap is a pair of rules that match a common input
+type RuleOverlap struct {
+	Rules   [2]RuleRef    `json:"rules"`
+	Example []interface{} `json:"example"` // input values matched by both rules
+}
+
+// Gap is a combination of inputs that no rule matches
+type Gap struct {
+	Conditions []string      `json:"conditions"` // FEEL unary tests per input
+	Example    []interface{} `json:"example"`    // input values matched by no rule
+}
+
+// SubsumedRule is a rule that only matches inputs another rule matches too
+type SubsumedRule struct {
+	Rule RuleRef `json:"rule"`
+	By   RuleRef `json:"by"`
+}
+
+// DecisionTableAnalysis is the analysis of a table of a decision or BKM
+type DecisionTableAnalysis struct {
+	ElementID   string `json:"elementId"`
+	ElementName string `json:"elementName"`
+	HitPolicy   string `json:"hitPolicy"`
+	TableAnalysis
+}
+
+// AnalyzeDefinitions analyzes every decision table of a model
+func AnalyzeDefinitions(defs *Definitions) []DecisionTableAnalysis {
+	var result []DecisionTableAnalysis
+	add := func(id, name string, dt *DecisionTable) {
+		result = append(result, DecisionTableAnalysis{
+			ElementID:     id,
+			ElementName:   name,
+			HitPolicy:     dt.HitPolicy,
+			TableAnalysis: *AnalyzeDecisionTable(dt),
+		})
+	}
+
+	for i := range defs.Decisions {
+		if d := &defs.Decisions[i]; d.DecisionTable != nil {
+			add(d.ID, d.Name, d.DecisionTable)
+		}
+	}
+	for i := range defs.BusinessKnowledgeModels {
+		bkm := &defs.BusinessKnowledgeModels[i]
+		if bkm.EncapsulatedLogic != nil && bkm.EncapsulatedLogic.DecisionTable != nil {
+			add(bkm.ID, bkm.Name, bkm.EncapsulatedLogic.DecisionTable)
+		}
+	}
+	return result
+}
+
+// AnalyzeDecisionTable reports overlapping rules, input combinations no rule
+// covers and rules subsumed by other rules. Inputs compared against numbers,
+// strings or booleans are analyzed; other inputs are skipped, which never
+// produces false overlaps or gaps but may hide some
+func AnalyzeDecisionTable(dt *DecisionTable) *TableAnalysis {
+	a := &tableAnalyzer{table: dt}
+	for i := range dt.Inputs {
+		a.columns = append(a.columns, newColumn(dt, i))
+	}
+
+	result := &TableAnalysis{
+		Overlaps: []RuleOverlap{},
+		Gaps:     []Gap{},
+		Subsumed: []SubsumedRule{},
+	}
+	for i, col := range a.columns {
+		if col.opaque {
+			result.SkippedInputs = append(result.SkippedInputs, i)
+		}
+	}
+
+	a.findOverlaps(result)
+	a.findSubsumed(result)
+
+	candidates := make([]int, len(dt.Rules))
+	for i := range candidates {
+		candidates[i] = i
+	}
+	a.findGaps(result, 0, candidates, nil)
+
+	return result
+}
+
+// FormatExample renders example input values as "expr = value" pairs
+func FormatExample(dt *DecisionTable, example []interface{}) string {
+	parts := make([]string, 0, len(example))
+	for i, v := range example {
+		if i >= len(dt.Inputs) || v == nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s = %s", strings.TrimSpace(dt.Inputs[i].InputExpression.Text), formatValue(v)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// interval is a numeric interval; infinite ends ignore their bound
+type interval struct {
+	lo, hi         float64
+	loInf, hiInf   bool
+	loIncl, hiIncl bool
+}
+
+func (iv interval) contains(x float64) bool {
+	if !iv.loInf && (x < iv.lo || (x == iv.lo && !iv.loIncl)) {
+		return false
+	}
+	if !iv.hiInf && (x > iv.hi || (x == iv.hi && !iv.hiIncl)) {
+		return false
+	}
+	return true
+}
+
+// text renders the interval as a FEEL unary test
+func (iv interval) text() string {
+	switch {
+	case iv.loInf && iv.hiInf:
+		return "-"
+	case iv.loInf:
+		if iv.hiIncl {
+			return "<= " + formatNumber(iv.hi)
+		}
+		return "< " + formatNumber(iv.hi)
+	case iv.hiInf:
+		if iv.loIncl {
+			return ">= " + formatNumber(iv.lo)
+		}
+		return "> " + formatNumber(iv.lo)
+	case iv.lo == iv.hi:
+		return formatNumber(iv.lo)
+	}
+
+	open, closing := "]", "["
+	if iv.loIncl {
+		open = "["
+	}
+	if iv.hiIncl {
+		closing = "]"
+	}
+	return open + formatNumber(iv.lo) + ".." + formatNumber(iv.hi) + closing
+}
+
+// test is a single positive unary test reduced to a value set
+type test struct {
+	kind string // "number", "string" or "boolean"
+	iv   interval
+	str  string
+	b    bool
+}
+
+func (t test) matches(v interface{}) bool {
+	switch t.kind {
+	case "number":
+		x, ok := v.(float64)
+		return ok && t.iv.contains(x)
+	case "string":
+		s, ok := v.(string)
+		return ok && s == t.str
+	case "boolean":
+		b, ok := v.(bool)
+		return ok && b == t.b
+	}
+	return false
+}
+
+// cell is an input entry reduced to value sets
+type cell struct {
+	any     bool
+	negated bool
+	tests   []test
+}
+
+func (c *cell) matches(v interface{}) bool {
+	if c.any {
+		return true
+	}
+	for _, t := range c.tests {
+		if t.matches(v) {
+			return !c.negated
+		}
+	}
+	return c.negated
+}
+
+// parseCell reduces input entry text to value sets; ok is false for cells
+// that refer to variables, call functions or use unsupported tests
+func parseCell(text string) (*cell, bool) {
+	tests, err := feel.ParseUnaryTests(text)
+	if err != nil {
+		return nil, false
+	}
+	if tests.Any {
+		return &cell{any: true}, true
+	}
+
+	c := &cell{negated: tests.Negated}
+	for _, ut := range tests.Tests {
+		t, ok := parseTest(ut)
+		if !ok {
+			return nil, false
+		}
+		c.tests = append(c.tests, t)
+	}
+	return c, true
+}
+
+func parseTest(ut *feel.UnaryTest) (test, bool) {
+	switch ut.Kind {
+	case feel.TestRange:
+		lo, ok1 := literal(ut.Range.Start)
+		hi, ok2 := literal(ut.Range.End)
+		loNum, ok3 := lo.(float64)
+		hiNum, ok4 := hi.(float64)
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return test{}, false
+		}
+		return test{kind: "number", iv: interval{
+			lo: loNum, hi: hiNum, loIncl: ut.Range.StartIncluded, hiIncl: ut.Range.EndIncluded,
+		}}, true
+
+	case feel.TestComparison, feel.TestExpr:
+		v, ok := literal(ut.Expr)
+		if !ok {
+			return test{}, false
+		}
+		op := ut.Op
+		if ut.Kind == feel.TestExpr {
+			op = "="
+		}
+
+		switch v := v.(type) {
+		case float64:
+			switch op {
+			case "=":
+				return test{kind: "number", iv: interval{lo: v, hi: v, loIncl: true, hiIncl: true}}, true
+			case "<", "<=":
+				return test{kind: "number", iv: interval{loInf: true, hi: v, hiIncl: op == "<="}}, true
+			case ">", ">=":
+				return test{kind: "number", iv: interval{lo: v, hiInf: true, loIncl: op == ">="}}, true
+			}
+		case string:
+			if op == "=" {
+				return test{kind: "string", str: v}, true
+			}
+		case bool:
+			if op == "=" {
+				return test{kind: "boolean", b: v}, true
+			}
+		}
+	}
+	return test{}, false
+}
+
+// literal returns the value of a number, string or boolean literal
+func literal(n feel.Node) (interface{}, bool) {
+	switch n := n.(type) {
+	case *feel.NumberLit:
+		return n.Value, true
+	case *feel.StringLit:
+		return n.Value, true
+	case *feel.BoolLit:
+		return n.Value, true
+	case *feel.Negation:
+		if num, ok := n.Operand.(*feel.NumberLit); ok {
+			return -num.Value, true
+		}
+	}
+	return nil, false
+}
+
+// piece is an elementary part of an input domain: every cell of the column
+// matches either all of its values or none
+type piece struct {
+	value interface{} // representative value, used in examples
+	text  string      // FEEL unary test describing the piece
+	iv    *interval   // set for numeric pieces
+}
+
+// column holds the domain partition of an input and the pieces each rule matches
+type column struct {
+	opaque  bool
+	pieces  []piece
+	covered [][]bool // covered[rule][piece]
+	texts   []string // original cell text per rule, compared for opaque columns
+}
+
+// newColumn partitions the domain of input i by the values its cells use
+func newColumn(dt *DecisionTable, i int) *column {
+	col := &column{}
+	cells := make([]*cell, len(dt.Rules))
+	kind := ""
+	for _, rule := range dt.Rules {
+		text := ""
+		if i < len(rule.InputEntries) {
+			text = rule.InputEntries[i].Text
+		}
+		col.texts = append(col.texts, strings.TrimSpace(text))
+	}
+
+	for r, text := range col.texts {
+		c, ok := parseCell(text)
+		if !ok {
+			col.opaque = true
+			return col
+		}
+		for _, t := range c.tests {
+			if kind != "" && kind != t.kind {
+				col.opaque = true
+				return col
+			}
+			kind = t.kind
+		}
+		cells[r] = c
+	}
+
+	// Allowed input values restrict the domain
+	var domain *cell
+	if values := dt.Inputs[i].InputValues; values != nil && strings.TrimSpace(values.Text) != "" {
+		if c, ok := parseCell(values.Text); ok && !c.any {
+			domain = c
+			for _, t := range c.tests {
+				if kind == "" {
+					kind = t.kind
+				}
+				if t.kind != kind {
+					domain = nil
+					break
+				}
+			}
+		}
+	}
+
+	switch kind {
+	case "number":
+		col.pieces = numberPieces(cells, domain)
+	case "string":
+		col.pieces = stringPieces(cells, domain)
+	case "boolean":
+		col.pieces = []piece{{value: true, text: "true"}, {value: false, text: "false"}}
+	default:
+		col.pieces = []piece{{text: "-"}}
+	}
+
+	if domain != nil && kind != "" {
+		var allowed []piece
+		for _, p := range col.pieces {
+			if domain.matches(p.value) {
+				allowed = append(allowed, p)
+			}
+		}
+		col.pieces = allowed
+	}
+
+	col.covered = make([][]bool, len(cells))
+	for r, c := range cells {
+		col.covered[r] = make([]bool, len(col.pieces))
+		for p, pc := range col.pieces {
+			col.covered[r][p] = c.any || kind == "" || c.matches(pc.value)
+		}
+	}
+	return col
+}
+
+// numberPieces splits the number line at every bound used by the cells
+func numberPieces(cells []*cell, domain *cell) []piece {
+	seen := make(map[float64]bool)
+	var points []float64
+	addBounds := func(c *cell) {
+		for _, t := range c.tests {
+			for _, b := range []struct {
+				v   float64
+				inf bool
+			}{{t.iv.lo, t.iv.loInf}, {t.iv.hi, t.iv.hiInf}} {
+				if !b.inf && !seen[b.v] {
+					seen[b.v] = true
+					points = append(points, b.v)
+				}
+			}
+		}
+	}
+	for _, c := range cells {
+		addBounds(c)
+	}
+	if domain != nil {
+		addBounds(domain)
+	}
+	sort.Float64s(points)
+
+	if len(points) == 0 {
+		iv := interval{loInf: true, hiInf: true}
+		return []piece{{value: 0.0, text: iv.text(), iv: &iv}}
+	}
+
+	var pieces []piece
+	add := func(iv interval, value float64) {
+		pieces = append(pieces, piece{value: value, text: iv.text(), iv: &iv})
+	}
+	add(interval{loInf: true, hi: points[0]}, points[0]-1)
+	for i, p := range points {
+		add(interval{lo: p, hi: p, loIncl: true, hiIncl: true}, p)
+		if i+1 < len(points) {
+			add(interval{lo: p, hi: points[i+1]}, (p+points[i+1])/2)
+		}
+	}
+	last := points[len(points)-1]
+	add(interval{lo: last, hiInf: true}, last+1)
+	return pieces
+}
+
+// stringPieces has a piece per string used by the cells and, unless the
+// input values enumerate the domain, one for all other strings
+func stringPieces(cells []*cell, domain *cell) []piece {
+	seen := make(map[string]bool)
+	var values []string
+	addValues := func(c *cell) {
+		for _, t := range c.tests {
+			if !seen[t.str] {
+				seen[t.str] = true
+				values = append(values, t.str)
+			}
+		}
+	}
+	for _, c := range cells {
+		addValues(c)
+	}
+	if domain != nil {
+		addValues(domain)
+	}
+	sort.Strings(values)
+
+	pieces := make([]piece, 0, len(values)+1)
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+		pieces = append(pieces, piece{value: v, text: quoted[i]})
+	}
+
+	if domain == nil || domain.negated {
+		other := "other"
+		for seen[other] {
+			other += "_"
+		}
+		text := "-"
+		if len(values) > 0 {
+			text = "not(" + strings.Join(quoted, ", ") + ")"
+		}
+		pieces = append(pieces, piece{value: other, text: text})
+	}
+	return pieces
+}
+
+type tableAnalyzer struct {
+	table   *DecisionTable
+	columns []*column
+}
+
+func (a *tableAnalyzer) ruleRef(i int) RuleRef {
+	return RuleRef{Index: i, ID: a.table.Rules[i].ID}
+}
+
+// intersect returns an input matched by both rules, or false when the
+// rules are disjoint or the overlap cannot be decided
+func (a *tableAnalyzer) intersect(r1, r2 int) ([]interface{}, bool) {
+	example := make([]interface{}, len(a.columns))
+	for i, col := range a.columns {
+		if col.opaque {
+			if col.texts[r1] != col.texts[r2] && col.texts[r1] != "-" && col.texts[r2] != "-" {
+				return nil, false
+			}
+			continue
+		}
+
+		found := false
+		for p := range col.pieces {
+			if col.covered[r1][p] && col.covered[r2][p] {
+				example[i] = col.pieces[p].value
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return example, true
+}
+
+// subsumes reports whether every input matched by rule r is matched by rule by
+func (a *tableAnalyzer) subsumes(by, r int) bool {
+	for _, col := range a.columns {
+		if col.opaque {
+			if col.texts[by] != "-" && col.texts[by] != col.texts[r] {
+				return false
+			}
+			continue
+		}
+		for p := range col.pieces {
+			if col.covered[r][p] && !col.covered[by][p] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (a *tableAnalyzer) findOverlaps(result *TableAnalysis) {
+	for i := range a.table.Rules {
+		for j := i + 1; j < len(a.table.Rules); j++ {
+			if example, ok := a.intersect(i, j); ok {
+				result.Overlaps = append(result.Overlaps, RuleOverlap{
+					Rules:   [2]RuleRef{a.ruleRef(i), a.ruleRef(j)},
+					Example: example,
+				})
+			}
+		}
+	}
+}
+
+// findSubsumed reports each rule covered by another rule; of two identical
+// rules the later one is reported
+func (a *tableAnalyzer) findSubsumed(result *TableAnalysis) {
+	for r := range a.table.Rules {
+		for by := range a.table.Rules {
+			if by == r || !a.subsumes(by, r) {
+				continue
+			}
+			if by > r && a.subsumes(r, by) {
+				continue
+			}
+			result.Subsumed = append(result.Subsumed, SubsumedRule{Rule: a.ruleRef(r), By: a.ruleRef(by)})
+			break
+		}
+	}
+}
+
+// findGaps walks the inputs in order, splitting the candidate rules by the
+// pieces of each input; pieces no candidate matches form a gap
+func (a *tableAnalyzer) findGaps(result *TableAnalysis, col int, candidates []int, chosen [][]int) {
+	if col == len(a.columns) || result.GapsTruncated {
+		return
+	}
+
+	c := a.columns[col]
+	if c.opaque {
+		a.findGaps(result, col+1, candidates, append(chosen, nil))
+		return
+	}
+
+	// Group pieces matched by the same candidate rules
+	var groups [][]int
+	var groupRules [][]int
+	index := make(map[string]int)
+	for p := range c.pieces {
+		var rules []int
+		for _, r := range candidates {
+			if c.covered[r][p] {
+				rules = append(rules, r)
+			}
+		}
+		key := fmt.Sprint(rules)
+		if g, ok := index[key]; ok {
+			groups[g] = append(groups[g], p)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []int{p})
+		groupRules = append(groupRules, rules)
+	}
+
+	for g, pieces := range groups {
+		next := append(append([][]int{}, chosen...), pieces)
+		if len(groupRules[g]) > 0 {
+			a.findGaps(result, col+1, groupRules[g], next)
+			continue
+		}
+
+		if len(result.Gaps) >= maxReportedGaps {
+			result.GapsTruncated = true
+			return
+		}
+		result.Gaps = append(result.Gaps, a.gap(next))
+	}
+}
+
+// gap describes the chosen pieces of the leading inputs; the remaining
+// inputs may take any value
+func (a *tableAnalyzer) gap(chosen [][]int) Gap {
+	gap := Gap{
+		Conditions: make([]string, len(a.columns)),
+		Example:    make([]interface{}, len(a.columns)),
+	}
+	for i, col := range a.columns {
+		if i >= len(chosen) || chosen[i] == nil {
+			gap.Conditions[i] = "-"
+			if !col.opaque && len(col.pieces) > 0 {
+				gap.Example[i] = col.pieces[0].value
+			}
+			continue
+		}
+		gap.Conditions[i] = piecesText(col.pieces, chosen[i])
+		gap.Example[i] = col.pieces[chosen[i][0]].value
+	}
+	return gap
+}
+
+// piecesText renders a set of pieces as unary tests, joining adjacent
+// numeric pieces into a single interval
+func piecesText(pieces []piece, indexes []int) string {
+	var parts []string
+	for i := 0; i < len(indexes); i++ {
+		p := pieces[indexes[i]]
+		if p.iv == nil {
+			parts = append(parts, p.text)
+			continue
+		}
+
+		j := i
+		for j+1 < len(indexes) && indexes[j+1] == indexes[j]+1 {
+			j++
+		}
+		last := pieces[indexes[j]].iv
+		merged := interval{
+			lo: p.iv.lo, loInf: p.iv.loInf, loIncl: p.iv.loIncl,
+			hi: last.hi, hiInf: last.hiInf, hiIncl: last.hiIncl,
+		}
+		parts = append(parts, merged.text())
+		i = j
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return formatNumber(v)
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,257 @@
+package dmn
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// table builds a decision table with one input per expression and one
+// rule per row of input entries; rules are named r1, r2, ...
+func table(exprs []string, rows ...[]string) *DecisionTable {
+	dt := &DecisionTable{HitPolicy: HitPolicyUnique}
+	for _, expr := range exprs {
+		dt.Inputs = append(dt.Inputs, Input{InputExpression: InputExpression{Text: expr}})
+	}
+	dt.Outputs = []Output{{Name: "out"}}
+	for i, row := range rows {
+		rule := Rule{ID: fmt.Sprintf("r%d", i+1), OutputEntries: []OutputEntry{{Text: fmt.Sprint(i + 1)}}}
+		for _, text := range row {
+			rule.InputEntries = append(rule.InputEntries, InputEntry{Text: text})
+		}
+		dt.Rules = append(dt.Rules, rule)
+	}
+	return dt
+}
+
+// overlapPairs renders overlaps as "r1/r2" pairs
+func overlapPairs(overlaps []RuleOverlap) []string {
+	var result []string
+	for _, o := range overlaps {
+		result = append(result, o.Rules[0].ID+"/"+o.Rules[1].ID)
+	}
+	return result
+}
+
+// gapConditions renders gaps as their conditions joined by " | "
+func gapConditions(gaps []Gap) []string {
+	var result []string
+	for _, g := range gaps {
+		result = append(result, strings.Join(g.Conditions, " | "))
+	}
+	return result
+}
+
+func TestAnalyzeDecisionTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    *DecisionTable
+		overlaps []string
+		gaps     []string
+		subsumed []string // "rule<by"
+		skipped  []int
+	}{
+		{
+			name:  "disjoint and complete",
+			table: table([]string{"age"}, []string{"< 18"}, []string{">= 18"}),
+		},
+		{
+			name:     "shared bound",
+			table:    table([]string{"age"}, []string{"<= 18"}, []string{">= 18"}),
+			overlaps: []string{"r1/r2"},
+		},
+		{
+			name:  "open bound gap",
+			table: table([]string{"age"}, []string{"< 18"}, []string{"> 18"}),
+			gaps:  []string{"18"},
+		},
+		{
+			name:  "ranges",
+			table: table([]string{"score"}, []string{"[0..50["}, []string{"[50..100]"}),
+			gaps:  []string{"< 0, > 100"},
+		},
+		{
+			name:     "subsumed range",
+			table:    table([]string{"score"}, []string{"-"}, []string{"[10..20]"}),
+			overlaps: []string{"r1/r2"},
+			subsumed: []string{"r2<r1"},
+		},
+		{
+			name:     "identical rules",
+			table:    table([]string{"x"}, []string{"1"}, []string{"1"}, []string{"not(1)"}),
+			overlaps: []string{"r1/r2"},
+			subsumed: []string{"r2<r1"},
+		},
+		{
+			name:  "strings",
+			table: table([]string{"category"}, []string{`"A"`}, []string{`"B"`}),
+			gaps:  []string{`not("A", "B")`},
+		},
+		{
+			name: "strings with input values",
+			table: func() *DecisionTable {
+				dt := table([]string{"category"}, []string{`"A"`}, []string{`"B"`})
+				dt.Inputs[0].InputValues = &InputValues{Text: `"A","B"`}
+				return dt
+			}(),
+		},
+		{
+			name:  "booleans",
+			table: table([]string{"member"}, []string{"true"}),
+			gaps:  []string{"false"},
+		},
+		{
+			name: "two inputs",
+			table: table([]string{"age", "member"},
+				[]string{"< 18", "-"},
+				[]string{">= 18", "true"},
+			),
+			gaps: []string{">= 18 | false"},
+		},
+		{
+			name: "disjoint on the second input",
+			table: table([]string{"age", "member"},
+				[]string{"-", "true"},
+				[]string{"> 10", "false"},
+				[]string{"<= 10", "false"},
+			),
+		},
+		{
+			name: "variable cells are skipped",
+			table: table([]string{"age", "member"},
+				[]string{"< limit", "true"},
+				[]string{">= limit", "true"},
+				[]string{"-", "false"},
+			),
+			skipped: []int{0},
+		},
+		{
+			name: "same opaque text overlaps",
+			table: table([]string{"age"},
+				[]string{"< limit"},
+				[]string{"< limit"},
+			),
+			overlaps: []string{"r1/r2"},
+			subsumed: []string{"r2<r1"},
+			skipped:  []int{0},
+		},
+		{
+			name:    "mixed kinds are skipped",
+			table:   table([]string{"x"}, []string{"1"}, []string{`"a"`}),
+			skipped: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AnalyzeDecisionTable(tt.table)
+			if pairs := overlapPairs(got.Overlaps); !reflect.DeepEqual(pairs, tt.overlaps) {
+				t.Errorf("overlaps = %v, want %v", pairs, tt.overlaps)
+			}
+			if gaps := gapConditions(got.Gaps); !reflect.DeepEqual(gaps, tt.gaps) {
+				t.Errorf("gaps = %q, want %q", gaps, tt.gaps)
+			}
+			var subsumed []string
+			for _, s := range got.Subsumed {
+				subsumed = append(subsumed, s.Rule.ID+"<"+s.By.ID)
+			}
+			if !reflect.DeepEqual(subsumed, tt.subsumed) {
+				t.Errorf("subsumed = %v, want %v", subsumed, tt.subsumed)
+			}
+			if !reflect.DeepEqual(got.SkippedInputs, tt.skipped) {
+				t.Errorf("skipped inputs = %v, want %v", got.SkippedInputs, tt.skipped)
+			}
+
+			// Examples are real witnesses
+			for _, o := range got.Overlaps {
+				for _, r := range o.Rules {
+					if !matchesExample(t, tt.table, r.Index, o.Example) {
+						t.Errorf("overlap example %v is not matched by %s", o.Example, r.ID)
+					}
+				}
+			}
+			for _, g := range got.Gaps {
+				for r := range tt.table.Rules {
+					if matchesExample(t, tt.table, r, g.Example) {
+						t.Errorf("gap example %v is matched by %s", g.Example, tt.table.Rules[r].ID)
+					}
+				}
+			}
+		})
+	}
+}
+
+// matchesExample reports whether a rule matches example input values;
+// inputs without a value are treated as matched
+func matchesExample(t *testing.T, dt *DecisionTable, rule int, example []interface{}) bool {
+	t.Helper()
+	for i, v := range example {
+		if v == nil {
+			continue
+		}
+		c, ok := parseCell(dt.Rules[rule].InputEntries[i].Text)
+		if !ok {
+			continue
+		}
+		if !c.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAnalyzeGapsTruncated(t *testing.T) {
+	// Each rule covers one x only for its own y, leaving a gap per x
+	var rows [][]string
+	for i := 0; i < 2*maxReportedGaps; i++ {
+		rows = append(rows, []string{fmt.Sprint(i), fmt.Sprint(i)})
+	}
+	got := AnalyzeDecisionTable(table([]string{"x", "y"}, rows...))
+	if len(got.Gaps) != maxReportedGaps || !got.GapsTruncated {
+		t.Errorf("%d gaps, truncated %v; want %d, truncated", len(got.Gaps), got.GapsTruncated, maxReportedGaps)
+	}
+}
+
+func TestFormatExample(t *testing.T) {
+	dt := table([]string{" age ", "category", "member"})
+	got := FormatExample(dt, []interface{}{18.5, "A", nil})
+	if want := `age = 18.5, category = "A"`; got != want {
+		t.Errorf("FormatExample = %q, want %q", got, want)
+	}
+}
+
+func TestValidateRuleOverlap(t *testing.T) {
+	tests := []struct {
+		hitPolicy string
+		outputs   [2]string
+		want      string
+	}{
+		{HitPolicyUnique, [2]string{"1", "2"}, `rule r2 overlaps rule r1 under UNIQUE hit policy, e.g. for age = 18`},
+		{HitPolicyAny, [2]string{"1", "2"}, `rule r2 overlaps rule r1 under ANY hit policy, e.g. for age = 18`},
+		{HitPolicyAny, [2]string{"1", " 1"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hitPolicy+" "+tt.outputs[1], func(t *testing.T) {
+			dt := table([]string{"age"}, []string{"<= 18"}, []string{">= 18"})
+			dt.HitPolicy = tt.hitPolicy
+			dt.Rules[0].OutputEntries[0].Text = tt.outputs[0]
+			dt.Rules[1].OutputEntries[0].Text = tt.outputs[1]
+
+			errs := overlapErrors(dt, "decision[d].decisionTable")
+			if tt.want == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %+v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 {
+				t.Fatalf("errors = %+v, want one", errs)
+			}
+			if errs[0].Message != tt.want || errs[0].RuleID != "r2" || errs[0].Field != "decision[d].decisionTable.rules[1]" {
+				t.Errorf("error = %+v", errs[0])
+			}
+		})
+	}
+}
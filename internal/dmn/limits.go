@@ -0,0 +1,151 @@
+package dmn
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Limits bound the size and complexity of documents a Parser accepts. They
+// are checked while the document is read, before it is decoded into the
+// model, so an oversized upload cannot exhaust memory. Zero means no limit
+type Limits struct {
+	MaxBytes         int64 // size of the document
+	MaxDecisions     int   // decisions in the model
+	MaxRulesPerTable int   // rules in a single decision table
+	MaxCellLength    int   // characters in a single text element
+	MaxDepth         int   // nesting depth of XML elements
+}
+
+// IsZero reports whether no limit is set
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Names of the limits reported in LimitError
+const (
+	LimitBytes         = "maxBytes"
+	LimitDecisions     = "maxDecisions"
+	LimitRulesPerTable = "maxRulesPerTable"
+	LimitCellLength    = "maxCellLength"
+	LimitDepth         = "maxDepth"
+)
+
+// LimitError is returned when a document exceeds one of the parser limits
+type LimitError struct {
+	Limit    string
+	Max      int64
+	Position Position
+}
+
+func (e *LimitError) Error() string {
+	msg := fmt.Sprintf("DMN document exceeds %s of %d", e.Limit, e.Max)
+	if e.Position.Line > 0 {
+		msg += fmt.Sprintf(" (line %d, column %d)", e.Position.Line, e.Position.Column)
+	}
+	return msg
+}
+
+// errTooLarge is returned by limitedReader past the size limit
+var errTooLarge = errors.New("document too large")
+
+// limitedReader fails instead of silently truncating like io.LimitReader
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Only fail if there is actually more data
+		var one [1]byte
+		n, err := l.r.Read(one[:])
+		if n > 0 {
+			return 0, errTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// readLimited reads a document while checking the limits. When buf is not
+// nil the document is copied into it
+func readLimited(r io.Reader, limits Limits, buf *bytes.Buffer) error {
+	if limits.MaxBytes > 0 {
+		r = &limitedReader{r: r, remaining: limits.MaxBytes}
+	}
+	if buf != nil {
+		r = io.TeeReader(r, buf)
+	}
+
+	decoder := xml.NewDecoder(r)
+	exceeded := func(limit string, max int) error {
+		line, column := decoder.InputPos()
+		return &LimitError{Limit: limit, Max: int64(max), Position: Position{Line: line, Column: column}}
+	}
+
+	var stack []string
+	decisions, rules, textLength := 0, 0, 0
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, errTooLarge) {
+			return &LimitError{Limit: LimitBytes, Max: limits.MaxBytes}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse DMN XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			stack = append(stack, t.Name.Local)
+			if limits.MaxDepth > 0 && len(stack) > limits.MaxDepth {
+				return exceeded(LimitDepth, limits.MaxDepth)
+			}
+
+			switch {
+			case t.Name.Local == "decision" && parent == "definitions":
+				decisions++
+				if limits.MaxDecisions > 0 && decisions > limits.MaxDecisions {
+					return exceeded(LimitDecisions, limits.MaxDecisions)
+				}
+			case t.Name.Local == "decisionTable":
+				rules = 0
+			case t.Name.Local == "rule" && parent == "decisionTable":
+				rules++
+				if limits.MaxRulesPerTable > 0 && rules > limits.MaxRulesPerTable {
+					return exceeded(LimitRulesPerTable, limits.MaxRulesPerTable)
+				}
+			case t.Name.Local == "text":
+				textLength = 0
+			}
+
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+
+		case xml.CharData:
+			// Text may arrive in several chunks, e.g. around entities
+			if limits.MaxCellLength > 0 && len(stack) > 0 && stack[len(stack)-1] == "text" {
+				textLength += len([]rune(string(t)))
+				if textLength > limits.MaxCellLength {
+					return exceeded(LimitCellLength, limits.MaxCellLength)
+				}
+			}
+		}
+	}
+}
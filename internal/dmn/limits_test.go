@@ -0,0 +1,126 @@
+package dmn
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// limitsModel has the given number of decisions, each with a table of the
+// given number of rules whose input entries hold cell. Its elements nest
+// six levels deep: definitions/decision/decisionTable/rule/inputEntry/text
+func limitsModel(decisions, rules int, cell string) string {
+	var b strings.Builder
+	b.WriteString(`<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Limits" namespace="http://example.org/limits">`)
+	for d := 0; d < decisions; d++ {
+		fmt.Fprintf(&b, `<decision id="d%d" name="d%d"><decisionTable hitPolicy="FIRST">`, d, d)
+		b.WriteString(`<input><inputExpression><text>x</text></inputExpression></input><output name="r"/>`)
+		for r := 0; r < rules; r++ {
+			fmt.Fprintf(&b, `<rule><inputEntry><text>%s</text></inputEntry><outputEntry><text>1</text></outputEntry></rule>`, cell)
+		}
+		b.WriteString(`</decisionTable></decision>`)
+	}
+	b.WriteString(`</definitions>`)
+	return b.String()
+}
+
+func TestLimits(t *testing.T) {
+	model := limitsModel(2, 3, "&lt;= 10")
+	size := int64(len(model))
+
+	tests := []struct {
+		name   string
+		limits Limits
+		src    string
+		want   string // exceeded limit, "" when the document is accepted
+	}{
+		{"no limits", Limits{}, model, ""},
+		{"bytes at limit", Limits{MaxBytes: size}, model, ""},
+		{"bytes over limit", Limits{MaxBytes: size - 1}, model, LimitBytes},
+		{"decisions at limit", Limits{MaxDecisions: 2}, model, ""},
+		{"decisions over limit", Limits{MaxDecisions: 1}, model, LimitDecisions},
+		{"rules at limit", Limits{MaxRulesPerTable: 3}, model, ""},
+		{"rules over limit", Limits{MaxRulesPerTable: 2}, model, LimitRulesPerTable},
+		{"rules counted per table", Limits{MaxRulesPerTable: 3}, limitsModel(5, 3, "1"), ""},
+		// "&lt;= 10" is five characters after unescaping
+		{"cell at limit", Limits{MaxCellLength: 5}, model, ""},
+		{"cell over limit", Limits{MaxCellLength: 4}, model, LimitCellLength},
+		{"cell counts characters", Limits{MaxCellLength: 3}, limitsModel(1, 1, `"äöü"`), LimitCellLength},
+		{"cell of characters at limit", Limits{MaxCellLength: 5}, limitsModel(1, 1, `"äöü"`), ""},
+		{"depth at limit", Limits{MaxDepth: 6}, model, ""},
+		{"depth over limit", Limits{MaxDepth: 5}, model, LimitDepth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParserWithOptions(ParseOptions{Limits: tt.limits})
+			readers := map[string]func() (*Definitions, error){
+				"Parse":      func() (*Definitions, error) { return parser.Parse(strings.NewReader(tt.src)) },
+				"ParseBytes": func() (*Definitions, error) { return parser.ParseBytes([]byte(tt.src)) },
+			}
+			for name, parse := range readers {
+				defs, err := parse()
+				if tt.want == "" {
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					if len(defs.Decisions) == 0 {
+						t.Errorf("%s: no decisions parsed", name)
+					}
+					continue
+				}
+
+				var limitErr *LimitError
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("%s: error = %v, want a limit error", name, err)
+				}
+				if limitErr.Limit != tt.want {
+					t.Errorf("%s: exceeded %s, want %s", name, limitErr.Limit, tt.want)
+				}
+				if tt.want != LimitBytes && limitErr.Position.Line == 0 {
+					t.Errorf("%s: %s has no position", name, limitErr.Limit)
+				}
+			}
+		})
+	}
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	r *strings.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestLimitsStopReading(t *testing.T) {
+	// A huge document is rejected after reading little more than the limit
+	src := limitsModel(1, 100000, "1")
+	r := &countingReader{r: strings.NewReader(src)}
+	_, err := NewParserWithOptions(ParseOptions{Limits: Limits{MaxBytes: 4096}}).Parse(r)
+
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitBytes {
+		t.Fatalf("error = %v, want %s", err, LimitBytes)
+	}
+	if r.n > 2*4096 {
+		t.Errorf("read %d bytes of %d", r.n, len(src))
+	}
+	if want := "DMN document exceeds maxBytes of 4096"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestLimitsMalformed(t *testing.T) {
+	_, err := NewParserWithOptions(ParseOptions{Limits: Limits{MaxDepth: 10}}).Parse(bytes.NewReader([]byte("<definitions><decision>")))
+	var limitErr *LimitError
+	if err == nil || errors.As(err, &limitErr) {
+		t.Errorf("error = %v, want a syntax error", err)
+	}
+}
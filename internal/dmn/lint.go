@@ -0,0 +1,259 @@
+package dmn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is the level of a validation finding
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // the model cannot be evaluated correctly
+	SeverityWarning Severity = "warning" // likely a modeling mistake
+	SeverityInfo    Severity = "info"    // worth knowing, often intended
+)
+
+// Codes of the structural checks performed by Validate and by a strict
+// Parser. Findings of these checks are always errors
+const (
+	CodeMissingID          = "missing-id"
+	CodeDuplicateID        = "duplicate-id"
+	CodeNoDecisions        = "no-decisions"
+	CodeMissingLogic       = "missing-logic"
+	CodeMissingVariable    = "missing-variable"
+	CodeInvalidHitPolicy   = "invalid-hit-policy"
+	CodeInvalidAggregation = "invalid-aggregation"
+	CodeEntryCount         = "entry-count"
+	CodeFEELSyntax         = "feel-syntax"
+	CodeUnknownName        = "unknown-name"
+	CodeFunctionSignature  = "function-signature"
+	CodeUnknownReference   = "unknown-reference"
+	CodeUnresolvedImport   = "unresolved-import"
+	CodeCyclicDependency   = "cyclic-dependency"
+	CodeRuleOverlap        = "rule-overlap"
+	CodeUnknownNamespace   = "unknown-namespace"
+	CodeUnknownElement     = "unknown-element"
+	CodeUnknownAttribute   = "unknown-attribute"
+)
+
+// Codes of the lint rules
+const (
+	CodeRuleMissingID    = "rule-missing-id"
+	CodeEmptyOutputEntry = "empty-output-entry"
+	CodeUnreachableRule  = "unreachable-rule"
+	CodeHitPolicyOmitted = "hit-policy-omitted"
+	CodeIncompleteTable  = "incomplete-table"
+	CodeDuplicateRule    = "duplicate-rule"
+)
+
+// LintRule is a check of modeling practice reported under a stable code.
+// Lint findings do not block deployment unless their code is configured
+// as blocking
+type LintRule struct {
+	Code        string   `json:"code"`
+	Severity    Severity `json:"severity"`
+	Description string   `json:"description"`
+	check       func(prefix string, dt *DecisionTable) []ValidationError
+}
+
+var lintRules = []LintRule{
+	{
+		Code:        CodeRuleMissingID,
+		Severity:    SeverityWarning,
+		Description: "rule has no id, so it cannot be identified in results and findings",
+		check:       lintRuleMissingID,
+	},
+	{
+		Code:        CodeEmptyOutputEntry,
+		Severity:    SeverityWarning,
+		Description: "output entry is empty and evaluates to null",
+		check:       lintEmptyOutputEntry,
+	},
+	{
+		Code:        CodeUnreachableRule,
+		Severity:    SeverityWarning,
+		Description: "rule of a FIRST table never matches because an earlier rule covers all of its inputs",
+		check:       lintUnreachableRule,
+	},
+	{
+		Code:        CodeDuplicateRule,
+		Severity:    SeverityWarning,
+		Description: "rule has the same input entries as an earlier rule",
+		check:       lintDuplicateRule,
+	},
+	{
+		Code:        CodeHitPolicyOmitted,
+		Severity:    SeverityInfo,
+		Description: "decision table has no hitPolicy attribute and defaults to UNIQUE",
+		check:       lintHitPolicyOmitted,
+	},
+	{
+		Code:        CodeIncompleteTable,
+		Severity:    SeverityInfo,
+		Description: "single-hit table without default outputs has input combinations no rule matches",
+		check:       lintIncompleteTable,
+	},
+}
+
+// LintRules returns the lint rules applied by Validate
+func LintRules() []LintRule {
+	return append([]LintRule(nil), lintRules...)
+}
+
+// Lint applies the lint rules to every decision table of the model
+func (v *Validator) Lint(defs *Definitions) []ValidationError {
+	var findings []ValidationError
+	forEachTable(defs, func(prefix string, dt *DecisionTable) {
+		for _, rule := range lintRules {
+			for _, f := range rule.check(prefix+".decisionTable", dt) {
+				f.Code = rule.Code
+				f.Severity = rule.Severity
+				findings = append(findings, f)
+			}
+		}
+	})
+	return findings
+}
+
+// Blocking returns the findings that prevent deployment: all errors, and
+// warnings or infos whose code is listed in blockingCodes
+func Blocking(findings []ValidationError, blockingCodes []string) []ValidationError {
+	var blocking []ValidationError
+	for _, f := range findings {
+		if f.Severity == SeverityError || containsCode(blockingCodes, f.Code) {
+			blocking = append(blocking, f)
+		}
+	}
+	return blocking
+}
+
+// forEachTable calls fn for the decision table of every decision and BKM
+func forEachTable(defs *Definitions, fn func(prefix string, dt *DecisionTable)) {
+	for i := range defs.Decisions {
+		if d := &defs.Decisions[i]; d.DecisionTable != nil {
+			fn(fmt.Sprintf("decision[%s]", d.ID), d.DecisionTable)
+		}
+	}
+	for i := range defs.BusinessKnowledgeModels {
+		bkm := &defs.BusinessKnowledgeModels[i]
+		if bkm.EncapsulatedLogic != nil && bkm.EncapsulatedLogic.DecisionTable != nil {
+			fn(fmt.Sprintf("businessKnowledgeModel[%s].encapsulatedLogic", bkm.ID), bkm.EncapsulatedLogic.DecisionTable)
+		}
+	}
+}
+
+func lintRuleMissingID(prefix string, dt *DecisionTable) []ValidationError {
+	var findings []ValidationError
+	for i, rule := range dt.Rules {
+		if rule.ID == "" {
+			findings = append(findings, ValidationError{
+				Field:    fmt.Sprintf("%s.rules[%d].id", prefix, i),
+				Message:  "rule has no id",
+				Position: rule.Pos,
+			})
+		}
+	}
+	return findings
+}
+
+func lintEmptyOutputEntry(prefix string, dt *DecisionTable) []ValidationError {
+	var findings []ValidationError
+	for i, rule := range dt.Rules {
+		for j, entry := range rule.OutputEntries {
+			if strings.TrimSpace(entry.Text) == "" {
+				f := cellError("", fmt.Sprintf("%s.rules[%d].outputEntries[%d]", prefix, i, j),
+					"output entry is empty", rule.ID, j, entry.Pos)
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+func lintUnreachableRule(prefix string, dt *DecisionTable) []ValidationError {
+	if dt.HitPolicy != HitPolicyFirst {
+		return nil
+	}
+
+	var findings []ValidationError
+	for _, s := range AnalyzeDecisionTable(dt).Subsumed {
+		if s.By.Index > s.Rule.Index {
+			continue
+		}
+		rule := &dt.Rules[s.Rule.Index]
+		findings = append(findings, ValidationError{
+			Field:    fmt.Sprintf("%s.rules[%d]", prefix, s.Rule.Index),
+			Message:  fmt.Sprintf("rule %s is unreachable: rule %s matches all of its inputs first", ruleLabel(s.Rule), ruleLabel(s.By)),
+			RuleID:   rule.ID,
+			Position: rule.Pos,
+		})
+	}
+	return findings
+}
+
+func lintDuplicateRule(prefix string, dt *DecisionTable) []ValidationError {
+	var findings []ValidationError
+	seen := make(map[string]int)
+	for i, rule := range dt.Rules {
+		texts := make([]string, len(rule.InputEntries))
+		for j, entry := range rule.InputEntries {
+			texts[j] = strings.Join(strings.Fields(entry.Text), " ")
+		}
+		key := strings.Join(texts, "\x00")
+
+		if first, ok := seen[key]; ok {
+			findings = append(findings, ValidationError{
+				Field:    fmt.Sprintf("%s.rules[%d]", prefix, i),
+				Message:  fmt.Sprintf("rule %s has the same input entries as rule %s", ruleLabel(RuleRef{i, rule.ID}), ruleLabel(RuleRef{first, dt.Rules[first].ID})),
+				RuleID:   rule.ID,
+				Position: rule.Pos,
+			})
+			continue
+		}
+		seen[key] = i
+	}
+	return findings
+}
+
+func lintHitPolicyOmitted(prefix string, dt *DecisionTable) []ValidationError {
+	if !dt.hitPolicyOmitted {
+		return nil
+	}
+	return []ValidationError{{
+		Field:    prefix + ".hitPolicy",
+		Message:  "hitPolicy is not set, UNIQUE is assumed",
+		Position: dt.Pos,
+	}}
+}
+
+func lintIncompleteTable(prefix string, dt *DecisionTable) []ValidationError {
+	if !IsSingleHit(dt.HitPolicy) || dt.HasDefaultOutputs() {
+		return nil
+	}
+
+	analysis := AnalyzeDecisionTable(dt)
+	if len(analysis.Gaps) == 0 {
+		return nil
+	}
+
+	count := fmt.Sprint(len(analysis.Gaps))
+	if analysis.GapsTruncated {
+		count = "more than " + count
+	}
+	return []ValidationError{{
+		Field: prefix,
+		Message: fmt.Sprintf("%s input combinations match no rule, e.g. %s",
+			count, FormatExample(dt, analysis.Gaps[0].Example)),
+		Position: dt.Pos,
+	}}
+}
+
+func containsCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package dmn
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lintModel is a decision whose table has the given attributes and rules.
+// The input age ranges over numbers, category over strings
+func lintModel(tableAttrs, output, rules string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Lint" namespace="http://example.org/lint">
+  <inputData id="in_age" name="age"><variable name="age"/></inputData>
+  <decision id="d" name="D">
+    <variable name="D"/>
+    <informationRequirement><requiredInput href="#in_age"/></informationRequirement>
+    <decisionTable` + tableAttrs + `>
+      <input id="i1"><inputExpression><text>age</text></inputExpression></input>
+      <output id="o1" name="r">` + output + `</output>
+      ` + rules + `
+    </decisionTable>
+  </decision>
+</definitions>`
+}
+
+// rule is a rule element with one input and one output entry
+func rule(id, input, output string) string {
+	attr := ""
+	if id != "" {
+		attr = ` id="` + id + `"`
+	}
+	return `<rule` + attr + `><inputEntry><text>` + input + `</text></inputEntry><outputEntry><text>` + output + `</text></outputEntry></rule>`
+}
+
+func TestLint(t *testing.T) {
+	complete := rule("r1", "&lt; 18", "1") + rule("r2", "&gt;= 18", "2")
+
+	tests := []struct {
+		name     string
+		attrs    string
+		output   string
+		rules    string
+		want     []string
+		severity Severity
+		message  string
+		ruleID   string
+	}{
+		{name: "clean", attrs: ` hitPolicy="UNIQUE"`, rules: complete},
+		{
+			name: "rule missing id", attrs: ` hitPolicy="UNIQUE"`,
+			rules: rule("r1", "&lt; 18", "1") + rule("", "&gt;= 18", "2"),
+			want:  []string{CodeRuleMissingID}, severity: SeverityWarning, message: "rule has no id",
+		},
+		{
+			name: "empty output entry", attrs: ` hitPolicy="UNIQUE"`,
+			rules: rule("r1", "&lt; 18", "1") + rule("r2", "&gt;= 18", " "),
+			want:  []string{CodeEmptyOutputEntry}, severity: SeverityWarning, message: "output entry is empty", ruleID: "r2",
+		},
+		{
+			name: "unreachable rule", attrs: ` hitPolicy="FIRST"`,
+			rules: rule("r1", "-", "1") + rule("r2", "&gt; 18", "2"),
+			want:  []string{CodeUnreachableRule}, severity: SeverityWarning, ruleID: "r2",
+			message: "rule r2 is unreachable: rule r1 matches all of its inputs first",
+		},
+		{
+			name: "later catch-all is reachable", attrs: ` hitPolicy="FIRST"`,
+			rules: rule("r1", "&gt; 18", "1") + rule("r2", "-", "2"),
+		},
+		{
+			name: "duplicate rule", attrs: ` hitPolicy="COLLECT"`,
+			rules: rule("r1", "&gt;  18", "1") + rule("r2", "&gt; 18", "2"),
+			want:  []string{CodeDuplicateRule}, severity: SeverityWarning, ruleID: "r2",
+			message: "rule r2 has the same input entries as rule r1",
+		},
+		{
+			name: "hit policy omitted", rules: complete,
+			want: []string{CodeHitPolicyOmitted}, severity: SeverityInfo,
+			message: "hitPolicy is not set, UNIQUE is assumed",
+		},
+		{
+			name: "incomplete table", attrs: ` hitPolicy="UNIQUE"`,
+			rules: rule("r1", "&lt; 18", "1") + rule("r2", "&gt; 18", "2"),
+			want:  []string{CodeIncompleteTable}, severity: SeverityInfo,
+			message: "1 input combinations match no rule, e.g. age = 18",
+		},
+		{
+			name: "incomplete table with default", attrs: ` hitPolicy="UNIQUE"`,
+			output: `<defaultOutputEntry><text>0</text></defaultOutputEntry>`,
+			rules:  rule("r1", "&lt; 18", "1") + rule("r2", "&gt; 18", "2"),
+		},
+		{
+			name: "incomplete multi-hit table", attrs: ` hitPolicy="COLLECT"`,
+			rules: rule("r1", "&lt; 18", "1") + rule("r2", "&gt; 18", "2"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs := parse(t, lintModel(tt.attrs, tt.output, tt.rules))
+			findings := NewValidator().Lint(defs)
+			if got := codes(findings); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("findings = %+v, want codes %v", findings, tt.want)
+			}
+			if len(findings) == 0 {
+				return
+			}
+			f := findings[0]
+			if f.Severity != tt.severity || f.Message != tt.message || f.RuleID != tt.ruleID {
+				t.Errorf("finding = %+v, want %s %q for rule %q", f, tt.severity, tt.message, tt.ruleID)
+			}
+			if f.Line == 0 {
+				t.Errorf("finding has no position: %+v", f)
+			}
+
+			// Validate reports lint findings along with the checks
+			if !strings.Contains(strings.Join(codes(NewValidator().Validate(defs)), ","), tt.want[0]) {
+				t.Errorf("Validate does not report %s", tt.want[0])
+			}
+		})
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	rules := LintRules()
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if seen[r.Code] {
+			t.Errorf("duplicate lint rule %s", r.Code)
+		}
+		seen[r.Code] = true
+		if r.Severity == SeverityError || r.Description == "" {
+			t.Errorf("lint rule %s has severity %q and description %q", r.Code, r.Severity, r.Description)
+		}
+	}
+
+	// The result is a copy
+	rules[0].Severity = SeverityError
+	if LintRules()[0].Severity == SeverityError {
+		t.Error("LintRules exposes the registry")
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	findings := []ValidationError{
+		{Code: CodeFEELSyntax, Severity: SeverityError},
+		{Code: CodeRuleMissingID, Severity: SeverityWarning},
+		{Code: CodeHitPolicyOmitted, Severity: SeverityInfo},
+	}
+
+	tests := []struct {
+		name  string
+		codes []string
+		want  []string
+	}{
+		{"errors only", nil, []string{CodeFEELSyntax}},
+		{"blocking warning", []string{CodeRuleMissingID}, []string{CodeFEELSyntax, CodeRuleMissingID}},
+		{"blocking info", []string{CodeHitPolicyOmitted, "unknown"}, []string{CodeFEELSyntax, CodeHitPolicyOmitted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codes(Blocking(findings, tt.codes)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blocking = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
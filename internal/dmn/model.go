@@ -1,6 +1,9 @@
 package dmn
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"strings"
+)
 
 // Definitions is the root element of a DMN model
 type Definitions struct {
@@ -9,9 +12,29 @@ type Definitions struct {
 	Name                    string                   `xml:"name,attr"`
 	Namespace               string                   `xml:"namespace,attr"`
 	ExpressionLanguage      string                   `xml:"expressionLanguage,attr,omitempty"`
+	Imports                 []Import                 `xml:"import"`
+	ItemDefinitions         []ItemDefinition         `xml:"itemDefinition"`
 	Decisions               []Decision               `xml:"decision"`
 	InputData               []InputData              `xml:"inputData"`
 	BusinessKnowledgeModels []BusinessKnowledgeModel `xml:"businessKnowledgeModel"`
+	DecisionServices        []DecisionService        `xml:"decisionService"`
+
+	DMNDI *DMNDI `xml:"DMNDI"`
+
+	// DMNVersion is the DMN version detected from the model namespace
+	DMNVersion string `xml:"-"`
+
+	// NamespacePrefixes are the prefixes declared on the root element. The
+	// writer declares them again so DMNDI content keeps its meaning
+	NamespacePrefixes map[string]string `xml:"-"`
+}
+
+// DMNDI is the diagram interchange section of a model. The engine does not
+// use it, so it is kept verbatim
+type DMNDI struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
 }
 
 // Decision represents a DMN decision element
@@ -20,8 +43,37 @@ type Decision struct {
 	Name                    string                   `xml:"name,attr"`
 	Variable                *Variable                `xml:"variable"`
 	InformationRequirements []InformationRequirement `xml:"informationRequirement"`
+	KnowledgeRequirements   []KnowledgeRequirement   `xml:"knowledgeRequirement"`
 	DecisionTable           *DecisionTable           `xml:"decisionTable"`
 	LiteralExpression       *LiteralExpression       `xml:"literalExpression"`
+	Pos                     Position                 `xml:"-" json:"-"`
+
+	// Camunda extensions
+	VersionTag        string `xml:"http://camunda.org/schema/1.0/dmn versionTag,attr,omitempty"`
+	HistoryTimeToLive string `xml:"http://camunda.org/schema/1.0/dmn historyTimeToLive,attr,omitempty"`
+}
+
+// Import references another model by namespace. Elements of the imported
+// model are accessed through the import name, e.g. "lib.myBkm(x)"
+type Import struct {
+	Namespace   string `xml:"namespace,attr"`
+	Name        string `xml:"name,attr"`
+	ImportType  string `xml:"importType,attr"`
+	LocationURI string `xml:"locationURI,attr,omitempty"`
+}
+
+// IsDMN reports whether the import refers to another DMN model
+func (i Import) IsDMN() bool {
+	return strings.Contains(i.ImportType, "omg.org/spec/DMN/")
+}
+
+// ItemDefinition represents a named data type
+type ItemDefinition struct {
+	ID             string           `xml:"id,attr,omitempty"`
+	Name           string           `xml:"name,attr"`
+	TypeRef        string           `xml:"typeRef,omitempty"`
+	IsCollection   bool             `xml:"isCollection,attr,omitempty"`
+	ItemComponents []ItemDefinition `xml:"itemComponent"`
 }
 
 // Variable represents the output variable of a decision
@@ -55,21 +107,77 @@ type InputData struct {
 	Variable *Variable `xml:"variable"`
 }
 
-// BusinessKnowledgeModel represents a BKM element
+// DecisionService represents a DMN decision service: a reusable boundary
+// around a sub-graph of decisions that is invoked as a single unit
+type DecisionService struct {
+	ID                    string             `xml:"id,attr"`
+	Name                  string             `xml:"name,attr"`
+	Variable              *Variable          `xml:"variable"`
+	OutputDecisions       []ElementReference `xml:"outputDecision"`       // results returned to the caller
+	EncapsulatedDecisions []ElementReference `xml:"encapsulatedDecision"` // evaluated inside the service, not returned
+	InputDecisions        []ElementReference `xml:"inputDecision"`        // supplied by the caller
+	InputData             []ElementReference `xml:"inputData"`            // supplied by the caller
+}
+
+// ElementReference is an href reference to another DRG element
+type ElementReference struct {
+	Href string `xml:"href,attr"` // e.g., "#decision1"
+}
+
+// ElementID returns the referenced element ID without the "#" prefix
+func (r ElementReference) ElementID() string {
+	_, id := SplitHref(r.Href)
+	return id
+}
+
+// SplitHref splits an href such as "#id" or "http://ns#id" into the
+// namespace of the referenced model (empty for local references) and the ID
+func SplitHref(href string) (namespace, id string) {
+	idx := strings.LastIndex(href, "#")
+	if idx < 0 {
+		return "", href
+	}
+	return href[:idx], href[idx+1:]
+}
+
+// KnowledgeRequirement represents a dependency on a business knowledge model
+type KnowledgeRequirement struct {
+	ID                string            `xml:"id,attr,omitempty"`
+	RequiredKnowledge *ElementReference `xml:"requiredKnowledge"`
+}
+
+// BusinessKnowledgeModel represents a BKM element: a reusable function
+// invoked from FEEL by its name
 type BusinessKnowledgeModel struct {
-	ID   string `xml:"id,attr"`
-	Name string `xml:"name,attr"`
+	ID                    string                 `xml:"id,attr"`
+	Name                  string                 `xml:"name,attr"`
+	Variable              *Variable              `xml:"variable"`
+	EncapsulatedLogic     *FunctionDefinition    `xml:"encapsulatedLogic"`
+	KnowledgeRequirements []KnowledgeRequirement `xml:"knowledgeRequirement"`
+	Pos                   Position               `xml:"-" json:"-"`
+}
+
+// FunctionDefinition is the body of a BKM with its formal parameters
+type FunctionDefinition struct {
+	Kind              string             `xml:"kind,attr,omitempty"` // FEEL (default)
+	FormalParameters  []Variable         `xml:"formalParameter"`
+	LiteralExpression *LiteralExpression `xml:"literalExpression"`
+	DecisionTable     *DecisionTable     `xml:"decisionTable"`
 }
 
 // DecisionTable represents a DMN decision table
 type DecisionTable struct {
-	ID              string   `xml:"id,attr,omitempty"`
-	HitPolicy       string   `xml:"hitPolicy,attr,omitempty"` // UNIQUE, FIRST, PRIORITY, ANY, COLLECT, RULE ORDER, OUTPUT ORDER
-	Aggregation     string   `xml:"aggregation,attr,omitempty"` // SUM, COUNT, MIN, MAX (for COLLECT)
-	PreferredOrientation string `xml:"preferredOrientation,attr,omitempty"` // Rule-as-Row, Rule-as-Column
-	Inputs          []Input  `xml:"input"`
-	Outputs         []Output `xml:"output"`
-	Rules           []Rule   `xml:"rule"`
+	ID                   string                 `xml:"id,attr,omitempty"`
+	HitPolicy            string                 `xml:"hitPolicy,attr,omitempty"`            // UNIQUE, FIRST, PRIORITY, ANY, COLLECT, RULE ORDER, OUTPUT ORDER
+	Aggregation          string                 `xml:"aggregation,attr,omitempty"`          // SUM, COUNT, MIN, MAX (for COLLECT)
+	PreferredOrientation string                 `xml:"preferredOrientation,attr,omitempty"` // Rule-as-Row, Rule-as-Column
+	Inputs               []Input                `xml:"input"`
+	Outputs              []Output               `xml:"output"`
+	Annotations          []RuleAnnotationClause `xml:"annotation"` // DMN 1.3 annotation columns
+	Rules                []Rule                 `xml:"rule"`
+	Pos                  Position               `xml:"-" json:"-"`
+
+	hitPolicyOmitted bool // set by the parser when UNIQUE was assumed
 }
 
 // Input represents an input column in a decision table
@@ -78,13 +186,32 @@ type Input struct {
 	Label           string          `xml:"label,attr,omitempty"`
 	InputExpression InputExpression `xml:"inputExpression"`
 	InputValues     *InputValues    `xml:"inputValues,omitempty"`
+	// InputVariable is camunda:inputVariable, the name under which input
+	// entries can refer to the value under test besides "?"
+	InputVariable string `xml:"http://camunda.org/schema/1.0/dmn inputVariable,attr,omitempty"`
+}
+
+// CamundaNamespace is the namespace of the Camunda DMN extension attributes
+const CamundaNamespace = "http://camunda.org/schema/1.0/dmn"
+
+// DefaultInputVariable is the Camunda default name of the value under test
+const DefaultInputVariable = "cellInput"
+
+// InputVariableName returns the name input entries use for the value under
+// test of this column
+func (in *Input) InputVariableName() string {
+	if in.InputVariable != "" {
+		return in.InputVariable
+	}
+	return DefaultInputVariable
 }
 
 // InputExpression defines the expression for an input
 type InputExpression struct {
-	ID      string `xml:"id,attr,omitempty"`
-	TypeRef string `xml:"typeRef,attr,omitempty"`
-	Text    string `xml:"text"` // FEEL expression
+	ID      string   `xml:"id,attr,omitempty"`
+	TypeRef string   `xml:"typeRef,attr,omitempty"`
+	Text    string   `xml:"text"` // FEEL expression
+	Pos     Position `xml:"-" json:"-"`
 }
 
 // InputValues defines allowed values for an input
@@ -99,6 +226,8 @@ type Output struct {
 	Name         string        `xml:"name,attr,omitempty"`
 	TypeRef      string        `xml:"typeRef,attr,omitempty"`
 	OutputValues *OutputValues `xml:"outputValues,omitempty"`
+	// DefaultOutputEntry is returned by single-hit tables when no rule matches
+	DefaultOutputEntry *LiteralExpression `xml:"defaultOutputEntry,omitempty"`
 }
 
 // OutputValues defines allowed values for an output
@@ -108,29 +237,70 @@ type OutputValues struct {
 
 // Rule represents a rule in a decision table
 type Rule struct {
-	ID            string        `xml:"id,attr,omitempty"`
-	Description   string        `xml:"description,omitempty"`
-	InputEntries  []InputEntry  `xml:"inputEntry"`
-	OutputEntries []OutputEntry `xml:"outputEntry"`
+	ID                string            `xml:"id,attr,omitempty"`
+	Description       string            `xml:"description,omitempty"`
+	InputEntries      []InputEntry      `xml:"inputEntry"`
+	OutputEntries     []OutputEntry     `xml:"outputEntry"`
+	AnnotationEntries []AnnotationEntry `xml:"annotationEntry"`
+	Pos               Position          `xml:"-" json:"-"`
 }
 
 // InputEntry represents a condition cell in a rule
 type InputEntry struct {
-	ID   string `xml:"id,attr,omitempty"`
-	Text string `xml:"text"` // FEEL unary test: ">= 18", "[1..100]", "-" (any)
+	ID   string   `xml:"id,attr,omitempty"`
+	Text string   `xml:"text"` // FEEL unary test: ">= 18", "[1..100]", "-" (any)
+	Pos  Position `xml:"-" json:"-"`
 }
 
 // OutputEntry represents an output cell in a rule
 type OutputEntry struct {
-	ID   string `xml:"id,attr,omitempty"`
-	Text string `xml:"text"` // FEEL expression or literal
+	ID   string   `xml:"id,attr,omitempty"`
+	Text string   `xml:"text"` // FEEL expression or literal
+	Pos  Position `xml:"-" json:"-"`
+}
+
+// RuleAnnotationClause represents an annotation column in a decision table
+type RuleAnnotationClause struct {
+	Name string `xml:"name,attr"`
+}
+
+// AnnotationEntry represents an annotation cell in a rule
+type AnnotationEntry struct {
+	Text string `xml:"text"` // free text, not evaluated
 }
 
 // LiteralExpression represents a decision defined by a FEEL expression
 type LiteralExpression struct {
-	ID      string `xml:"id,attr,omitempty"`
-	TypeRef string `xml:"typeRef,attr,omitempty"`
-	Text    string `xml:"text"` // FEEL expression
+	ID      string   `xml:"id,attr,omitempty"`
+	TypeRef string   `xml:"typeRef,attr,omitempty"`
+	Text    string   `xml:"text"` // FEEL expression
+	Pos     Position `xml:"-" json:"-"`
+}
+
+// Position is a line and column in the DMN XML source, both starting at 1
+type Position struct {
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+// IsSingleHit reports whether the hit policy returns at most one rule's outputs
+func IsSingleHit(hitPolicy string) bool {
+	switch hitPolicy {
+	case "", HitPolicyUnique, HitPolicyFirst, HitPolicyPriority, HitPolicyAny:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasDefaultOutputs reports whether any output of the table declares a default
+func (dt *DecisionTable) HasDefaultOutputs() bool {
+	for _, out := range dt.Outputs {
+		if out.DefaultOutputEntry != nil {
+			return true
+		}
+	}
+	return false
 }
 
 // HitPolicy constants
@@ -151,4 +321,3 @@ const (
 	AggregationMin   = "MIN"
 	AggregationMax   = "MAX"
 )
-
@@ -0,0 +1,145 @@
+package dmn
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// ModelVersion returns the DMN version of the model. Models parsed before
+// the version was recorded fall back to the namespace of the root element
+func (d *Definitions) ModelVersion() string {
+	if d.DMNVersion != "" {
+		return d.DMNVersion
+	}
+	return namespaceVersion(d.XMLName.Space)
+}
+
+// normalize records the DMN version of the document and rewrites constructs
+// of older versions into their current form, so the validator and the
+// engine only deal with one model
+func normalize(data []byte, defs *Definitions) {
+	defs.DMNVersion = namespaceVersion(defs.XMLName.Space)
+	defs.NamespacePrefixes = rootPrefixes(data)
+	if defs.DMNVersion != "1.1" {
+		return
+	}
+
+	// DMN 1.1 references types by QName, e.g. "feel:string" or
+	// "lib:tCustomer", and imports have no name: the prefix bound to the
+	// imported namespace plays that role
+	prefixes := defs.NamespacePrefixes
+	for i := range defs.Imports {
+		imp := &defs.Imports[i]
+		if imp.Name != "" {
+			continue
+		}
+		for prefix, ns := range prefixes {
+			if ns == imp.Namespace {
+				imp.Name = prefix
+				break
+			}
+		}
+	}
+
+	typeRef := func(ref *string) {
+		*ref = normalizeTypeRef(*ref, defs, prefixes)
+	}
+	variable := func(v *Variable) {
+		if v != nil {
+			typeRef(&v.TypeRef)
+		}
+	}
+	literal := func(expr *LiteralExpression) {
+		if expr != nil {
+			typeRef(&expr.TypeRef)
+		}
+	}
+
+	var itemDefinition func(item *ItemDefinition)
+	itemDefinition = func(item *ItemDefinition) {
+		typeRef(&item.TypeRef)
+		for i := range item.ItemComponents {
+			itemDefinition(&item.ItemComponents[i])
+		}
+	}
+	for i := range defs.ItemDefinitions {
+		itemDefinition(&defs.ItemDefinitions[i])
+	}
+
+	for i := range defs.Decisions {
+		d := &defs.Decisions[i]
+		variable(d.Variable)
+		literal(d.LiteralExpression)
+	}
+	for i := range defs.InputData {
+		variable(defs.InputData[i].Variable)
+	}
+	for i := range defs.DecisionServices {
+		variable(defs.DecisionServices[i].Variable)
+	}
+	for i := range defs.BusinessKnowledgeModels {
+		bkm := &defs.BusinessKnowledgeModels[i]
+		variable(bkm.Variable)
+		if bkm.EncapsulatedLogic != nil {
+			for j := range bkm.EncapsulatedLogic.FormalParameters {
+				variable(&bkm.EncapsulatedLogic.FormalParameters[j])
+			}
+			literal(bkm.EncapsulatedLogic.LiteralExpression)
+		}
+	}
+	forEachTable(defs, func(_ string, dt *DecisionTable) {
+		for i := range dt.Inputs {
+			typeRef(&dt.Inputs[i].InputExpression.TypeRef)
+		}
+		for i := range dt.Outputs {
+			typeRef(&dt.Outputs[i].TypeRef)
+			literal(dt.Outputs[i].DefaultOutputEntry)
+		}
+	})
+}
+
+// normalizeTypeRef converts a DMN 1.1 QName type reference into the DMN 1.2+
+// form: FEEL and local types lose their prefix, imported types are qualified
+// with the import name
+func normalizeTypeRef(ref string, defs *Definitions, prefixes map[string]string) string {
+	prefix, local, ok := strings.Cut(ref, ":")
+	if !ok {
+		return ref
+	}
+
+	ns, bound := prefixes[prefix]
+	switch {
+	case !bound:
+		if prefix == "feel" {
+			return local
+		}
+		return ref
+	case strings.Contains(ns, "/FEEL/") || ns == defs.Namespace:
+		return local
+	}
+	if imp := defs.GetImport(ns); imp != nil && imp.Name != "" {
+		return imp.Name + "." + local
+	}
+	return ref
+}
+
+// rootPrefixes returns the namespace prefixes declared on the root element
+func rootPrefixes(data []byte) map[string]string {
+	prefixes := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return prefixes
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			for _, a := range start.Attr {
+				if a.Name.Space == "xmlns" {
+					prefixes[a.Name.Local] = a.Value
+				}
+			}
+			return prefixes
+		}
+	}
+}
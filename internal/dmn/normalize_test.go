@@ -0,0 +1,69 @@
+package dmn
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// parseFile parses a model of testdata/dmn
+func parseFile(t *testing.T, name string) *Definitions {
+	t.Helper()
+	data, err := os.ReadFile("../../testdata/dmn/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := NewParser().ParseBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return defs
+}
+
+// modelJSON renders the parts of a model the validator and the engine see,
+// leaving out how the document declared its version
+func modelJSON(t *testing.T, defs *Definitions) string {
+	t.Helper()
+	m := *defs
+	m.XMLName.Space, m.DMNVersion, m.NamespacePrefixes = "", "", nil
+	m.Imports = append([]Import(nil), defs.Imports...)
+	for i := range m.Imports {
+		m.Imports[i].ImportType = ""
+	}
+	data, err := json.MarshalIndent(&m, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestNormalizeVersions(t *testing.T) {
+	want := parseFile(t, "versions/risk_dmn13.dmn")
+
+	for _, tt := range []struct {
+		file    string
+		version string
+	}{
+		{"versions/risk_dmn11.dmn", "1.1"},
+		{"versions/risk_dmn12.dmn", "1.2"},
+		{"versions/risk_dmn13.dmn", "1.3"},
+	} {
+		t.Run(tt.version, func(t *testing.T) {
+			defs := parseFile(t, tt.file)
+			if defs.DMNVersion != tt.version || defs.ModelVersion() != tt.version {
+				t.Errorf("version = %q, want %q", defs.DMNVersion, tt.version)
+			}
+
+			// Type references and import names read as in DMN 1.3
+			if got := defs.Imports[0].Name; got != "lib" {
+				t.Errorf("import name = %q, want lib", got)
+			}
+			if got := defs.InputData[1].Variable.TypeRef; got != "lib.tScore" {
+				t.Errorf("imported typeRef = %q, want lib.tScore", got)
+			}
+			if got, want := modelJSON(t, defs), modelJSON(t, want); got != want {
+				t.Errorf("normalized model differs from DMN 1.3:\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
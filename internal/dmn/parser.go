@@ -1,6 +1,7 @@
 package dmn
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -8,30 +9,49 @@ import (
 )
 
 // Parser parses DMN XML files into Go structures
-type Parser struct{}
+type Parser struct {
+	opts ParseOptions
+}
+
+// ParseOptions configures a Parser
+type ParseOptions struct {
+	// Strict checks the document against the schema of its DMN version and
+	// fails with a *SchemaError on unknown elements and attributes, which
+	// are otherwise ignored
+	Strict bool
+
+	// Limits bound the document size and complexity; violations fail with
+	// a *LimitError
+	Limits Limits
+}
 
 // NewParser creates a new DMN parser
 func NewParser() *Parser {
 	return &Parser{}
 }
 
+// NewParserWithOptions creates a DMN parser with the given options
+func NewParserWithOptions(opts ParseOptions) *Parser {
+	return &Parser{opts: opts}
+}
+
 // Parse parses DMN XML from a reader
 func (p *Parser) Parse(r io.Reader) (*Definitions, error) {
-	var defs Definitions
-	decoder := xml.NewDecoder(r)
-	
-	if err := decoder.Decode(&defs); err != nil {
-		return nil, fmt.Errorf("failed to parse DMN XML: %w", err)
-	}
-	
-	// Set default hit policy if not specified
-	for i := range defs.Decisions {
-		if defs.Decisions[i].DecisionTable != nil && defs.Decisions[i].DecisionTable.HitPolicy == "" {
-			defs.Decisions[i].DecisionTable.HitPolicy = HitPolicyUnique
+	if p.opts.Limits.IsZero() {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DMN XML: %w", err)
 		}
+		return p.parse(data)
 	}
-	
-	return &defs, nil
+
+	// Check the limits while reading, so an oversized document is
+	// rejected before it is decoded
+	var buf bytes.Buffer
+	if err := readLimited(r, p.opts.Limits, &buf); err != nil {
+		return nil, err
+	}
+	return p.parse(buf.Bytes())
 }
 
 // ParseFile parses a DMN XML file
@@ -41,24 +61,47 @@ func (p *Parser) ParseFile(path string) (*Definitions, error) {
 		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
 	}
 	defer f.Close()
-	
+
 	return p.Parse(f)
 }
 
 // ParseBytes parses DMN XML from bytes
 func (p *Parser) ParseBytes(data []byte) (*Definitions, error) {
+	if !p.opts.Limits.IsZero() {
+		if err := readLimited(bytes.NewReader(data), p.opts.Limits, nil); err != nil {
+			return nil, err
+		}
+	}
+	return p.parse(data)
+}
+
+// parse decodes a document that is within the limits
+func (p *Parser) parse(data []byte) (*Definitions, error) {
 	var defs Definitions
 	if err := xml.Unmarshal(data, &defs); err != nil {
 		return nil, fmt.Errorf("failed to parse DMN XML: %w", err)
 	}
-	
-	// Set default hit policy if not specified
-	for i := range defs.Decisions {
-		if defs.Decisions[i].DecisionTable != nil && defs.Decisions[i].DecisionTable.HitPolicy == "" {
-			defs.Decisions[i].DecisionTable.HitPolicy = HitPolicyUnique
+
+	if p.opts.Strict {
+		if schemaErr := checkSchema(data); schemaErr != nil {
+			return nil, schemaErr
 		}
 	}
-	
+
+	// Detect the DMN version and bring older constructs to the current model
+	normalize(data, &defs)
+
+	// Remember where elements are in the source for validation messages
+	recordPositions(data, &defs)
+
+	// Set default hit policy if not specified
+	forEachTable(&defs, func(_ string, dt *DecisionTable) {
+		if dt.HitPolicy == "" {
+			dt.HitPolicy = HitPolicyUnique
+			dt.hitPolicyOmitted = true
+		}
+	})
+
 	return &defs, nil
 }
 
@@ -72,6 +115,46 @@ func (d *Definitions) GetDecision(id string) *Decision {
 	return nil
 }
 
+// GetDecisionService returns a decision service by ID
+func (d *Definitions) GetDecisionService(id string) *DecisionService {
+	for i := range d.DecisionServices {
+		if d.DecisionServices[i].ID == id {
+			return &d.DecisionServices[i]
+		}
+	}
+	return nil
+}
+
+// GetBusinessKnowledgeModel returns a BKM by ID
+func (d *Definitions) GetBusinessKnowledgeModel(id string) *BusinessKnowledgeModel {
+	for i := range d.BusinessKnowledgeModels {
+		if d.BusinessKnowledgeModels[i].ID == id {
+			return &d.BusinessKnowledgeModels[i]
+		}
+	}
+	return nil
+}
+
+// GetImport returns the import of the given namespace
+func (d *Definitions) GetImport(namespace string) *Import {
+	for i := range d.Imports {
+		if d.Imports[i].Namespace == namespace {
+			return &d.Imports[i]
+		}
+	}
+	return nil
+}
+
+// GetItemDefinition returns an item definition by name
+func (d *Definitions) GetItemDefinition(name string) *ItemDefinition {
+	for i := range d.ItemDefinitions {
+		if d.ItemDefinitions[i].Name == name {
+			return &d.ItemDefinitions[i]
+		}
+	}
+	return nil
+}
+
 // GetInputData returns input data by ID
 func (d *Definitions) GetInputData(id string) *InputData {
 	for i := range d.InputData {
@@ -81,4 +164,3 @@ func (d *Definitions) GetInputData(id string) *InputData {
 	}
 	return nil
 }
-
@@ -0,0 +1,153 @@
+package dmn
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sort"
+)
+
+// positionIndex converts byte offsets of a document into line and column
+type positionIndex struct {
+	lineStarts []int
+}
+
+func newPositionIndex(data []byte) *positionIndex {
+	starts := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &positionIndex{lineStarts: starts}
+}
+
+// position returns the line and column of a byte offset
+func (idx *positionIndex) position(offset int) Position {
+	line := sort.Search(len(idx.lineStarts), func(i int) bool {
+		return idx.lineStarts[i] > offset
+	})
+	return Position{Line: line, Column: offset - idx.lineStarts[line-1] + 1}
+}
+
+// recordPositions walks the XML tokens a second time and stores the source
+// position of decisions, tables, rules and cells on the decoded model.
+// Elements are matched by their order, the same way encoding/xml fills slices
+func recordPositions(data []byte, defs *Definitions) {
+	idx := newPositionIndex(data)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var stack []string
+	var decision *Decision
+	var bkm *BusinessKnowledgeModel
+	var table *DecisionTable
+	var rule *Rule
+	var nDecision, nBKM, nInput, nOutput, nRule, nInputEntry, nOutputEntry int
+
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err != nil {
+			return
+		}
+
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+
+		case xml.StartElement:
+			name := t.Name.Local
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			stack = append(stack, name)
+			pos := idx.position(offset)
+
+			switch {
+			case parent == "definitions" && name == "decision":
+				decision, bkm, table = nil, nil, nil
+				if nDecision < len(defs.Decisions) {
+					decision = &defs.Decisions[nDecision]
+					decision.Pos = pos
+				}
+				nDecision++
+
+			case parent == "definitions" && name == "businessKnowledgeModel":
+				decision, bkm, table = nil, nil, nil
+				if nBKM < len(defs.BusinessKnowledgeModels) {
+					bkm = &defs.BusinessKnowledgeModels[nBKM]
+					bkm.Pos = pos
+				}
+				nBKM++
+
+			case name == "decisionTable" || name == "literalExpression":
+				var logicTable *DecisionTable
+				var literal *LiteralExpression
+				if parent == "decision" && decision != nil {
+					logicTable, literal = decision.DecisionTable, decision.LiteralExpression
+				} else if parent == "encapsulatedLogic" && bkm != nil && bkm.EncapsulatedLogic != nil {
+					logicTable, literal = bkm.EncapsulatedLogic.DecisionTable, bkm.EncapsulatedLogic.LiteralExpression
+				} else {
+					continue
+				}
+
+				if name == "literalExpression" {
+					if literal != nil {
+						literal.Pos = pos
+					}
+					continue
+				}
+				table, rule = logicTable, nil
+				nInput, nOutput, nRule = 0, 0, 0
+				if table != nil {
+					table.Pos = pos
+				}
+
+			case table == nil:
+				continue
+
+			case parent == "input" && name == "inputExpression":
+				if nInput > 0 && nInput <= len(table.Inputs) {
+					table.Inputs[nInput-1].InputExpression.Pos = pos
+				}
+
+			case parent == "output" && name == "defaultOutputEntry":
+				if nOutput > 0 && nOutput <= len(table.Outputs) && table.Outputs[nOutput-1].DefaultOutputEntry != nil {
+					table.Outputs[nOutput-1].DefaultOutputEntry.Pos = pos
+				}
+
+			case parent == "decisionTable":
+				switch name {
+				case "input":
+					nInput++
+				case "output":
+					nOutput++
+				case "rule":
+					rule = nil
+					if nRule < len(table.Rules) {
+						rule = &table.Rules[nRule]
+						rule.Pos = pos
+					}
+					nRule++
+					nInputEntry, nOutputEntry = 0, 0
+				}
+
+			case parent == "rule" && rule != nil:
+				switch name {
+				case "inputEntry":
+					if nInputEntry < len(rule.InputEntries) {
+						rule.InputEntries[nInputEntry].Pos = pos
+					}
+					nInputEntry++
+				case "outputEntry":
+					if nOutputEntry < len(rule.OutputEntries) {
+						rule.OutputEntries[nOutputEntry].Pos = pos
+					}
+					nOutputEntry++
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package dmn
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//go:embed schema/dmn.schema
+var schemaSource []byte
+
+// modelNamespaces maps the model namespaces of the DMN specifications to
+// their version
+var modelNamespaces = map[string]string{
+	"www.omg.org/spec/DMN/20151101/dmn.xsd": "1.1",
+	"www.omg.org/spec/DMN/20180521/MODEL/":  "1.2",
+	"www.omg.org/spec/DMN/20191111/MODEL/":  "1.3",
+	"www.omg.org/spec/DMN/20211108/MODEL/":  "1.4",
+	"www.omg.org/spec/DMN/20230324/MODEL/":  "1.5",
+}
+
+// namespaceVersion returns the DMN version of a model namespace, or "" if
+// the namespace is not a DMN model namespace. Both http and https forms are
+// accepted since tools use them interchangeably
+func namespaceVersion(namespace string) string {
+	ns := strings.TrimPrefix(strings.TrimPrefix(namespace, "https://"), "http://")
+	return modelNamespaces[ns]
+}
+
+// schemaType is a complex type of the DMN schema
+type schemaType struct {
+	base   string
+	attrs  map[string]string      // attribute name -> version it exists since
+	elems  map[string]schemaChild // child element name -> type
+	groups []string               // substitution groups allowed as children
+}
+
+// schemaChild is a child element declaration
+type schemaChild struct {
+	typ   string
+	since string
+}
+
+// schema is the parsed form of schema/dmn.schema
+type schema struct {
+	types  map[string]*schemaType
+	groups map[string]map[string]schemaChild
+}
+
+var (
+	dmnSchema     *schema
+	dmnSchemaOnce sync.Once
+)
+
+// loadSchema parses the embedded schema description once
+func loadSchema() *schema {
+	dmnSchemaOnce.Do(func() {
+		s := &schema{
+			types:  make(map[string]*schemaType),
+			groups: make(map[string]map[string]schemaChild),
+		}
+
+		var current *schemaType
+		var group map[string]schemaChild
+		scanner := bufio.NewScanner(bytes.NewReader(schemaSource))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+
+			switch fields[0] {
+			case "type":
+				current, group = &schemaType{
+					attrs: make(map[string]string),
+					elems: make(map[string]schemaChild),
+				}, nil
+				if len(fields) == 4 && fields[2] == ":" {
+					current.base = fields[3]
+				}
+				s.types[fields[1]] = current
+			case "group":
+				current, group = nil, make(map[string]schemaChild)
+				s.groups[fields[1]] = group
+			case "attr":
+				for _, f := range fields[1:] {
+					name, since := splitSince(f)
+					current.attrs[name] = since
+				}
+			case "elem":
+				for _, f := range fields[1:] {
+					if strings.HasPrefix(f, "$") {
+						current.groups = append(current.groups, f[1:])
+						continue
+					}
+					decl, since := splitSince(f)
+					name, typ, _ := strings.Cut(decl, ":")
+					if group != nil {
+						group[name] = schemaChild{typ: typ, since: since}
+					} else {
+						current.elems[name] = schemaChild{typ: typ, since: since}
+					}
+				}
+			}
+		}
+		dmnSchema = s
+	})
+	return dmnSchema
+}
+
+// splitSince splits "name@1.3" into the name and the version
+func splitSince(s string) (string, string) {
+	name, since, _ := strings.Cut(s, "@")
+	return name, since
+}
+
+// attr reports whether a type allows an unqualified attribute in a version
+func (s *schema) attr(typ, name, version string) bool {
+	for t := s.types[typ]; t != nil; t = s.types[t.base] {
+		if since, ok := t.attrs[name]; ok {
+			return since <= version
+		}
+	}
+	return false
+}
+
+// child returns the type of a child element in a version
+func (s *schema) child(typ, name, version string) (string, bool) {
+	for t := s.types[typ]; t != nil; t = s.types[t.base] {
+		if c, ok := t.elems[name]; ok {
+			return c.typ, c.since <= version
+		}
+		for _, g := range t.groups {
+			if c, ok := s.groups[g][name]; ok {
+				return c.typ, c.since <= version
+			}
+		}
+	}
+	return "", false
+}
+
+// SchemaError is returned by a strict parser when the document does not
+// follow the structure of the DMN schema
+type SchemaError struct {
+	Version  string
+	Findings []ValidationError
+}
+
+func (e *SchemaError) Error() string {
+	msg := "document does not match the DMN schema"
+	if e.Version != "" {
+		msg = fmt.Sprintf("document does not match the DMN %s schema", e.Version)
+	}
+	if len(e.Findings) == 0 {
+		return msg
+	}
+	msg += ": " + e.Findings[0].Error()
+	if len(e.Findings) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Findings)-1)
+	}
+	return msg
+}
+
+// checkSchema checks elements and attributes of a document against the
+// schema of the DMN version declared by its namespace. Attributes in other
+// namespaces are allowed everywhere, foreign elements only inside
+// extensionElements. DMNDI content is not checked.
+//
+// The schema description is not the official XSD: it knows which children
+// and attributes each element may have, but not their order, how often
+// they occur, whether they are required or the types of attribute values
+func checkSchema(data []byte) *SchemaError {
+	s := loadSchema()
+	idx := newPositionIndex(data)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	type frame struct {
+		typ  string
+		path string
+	}
+	var stack []frame
+	var version, namespace string
+	var findings []ValidationError
+
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+
+		case xml.CharData:
+			if len(stack) == 0 || len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			if top := stack[len(stack)-1]; top.typ != "text" && top.typ != "any" && top.typ != "unknown" {
+				findings = append(findings, ValidationError{
+					Field:    top.path,
+					Message:  "character data is not allowed here",
+					Code:     CodeUnknownElement,
+					Position: idx.position(offset),
+				})
+			}
+
+		case xml.StartElement:
+			pos := idx.position(offset)
+			name := t.Name.Local
+
+			if len(stack) == 0 {
+				version, namespace = namespaceVersion(t.Name.Space), t.Name.Space
+				if name != "definitions" || version == "" {
+					return &SchemaError{Findings: []ValidationError{{
+						Field:    name,
+						Message:  fmt.Sprintf("root element {%s}%s is not a DMN 1.1-1.5 definitions element", t.Name.Space, name),
+						Code:     CodeUnknownNamespace,
+						Position: pos,
+					}}}
+				}
+				stack = append(stack, frame{typ: "tDefinitions", path: name})
+				findings = append(findings, checkAttrs(s, "tDefinitions", name, version, t.Attr, pos)...)
+				continue
+			}
+
+			parent := stack[len(stack)-1]
+			path := parent.path + "/" + name
+			typ := ""
+			switch {
+			case parent.typ == "any" || parent.typ == "unknown":
+				typ = parent.typ
+			case parent.typ == "tDefinitions" && name == "DMNDI" && strings.Contains(t.Name.Space, "/DMNDI/") && version >= "1.2":
+				typ = "any"
+			case t.Name.Space != namespace:
+				findings = append(findings, ValidationError{
+					Field:    path,
+					Message:  fmt.Sprintf("element {%s}%s is not allowed outside extensionElements", t.Name.Space, name),
+					Code:     CodeUnknownElement,
+					Position: pos,
+				})
+				typ = "unknown"
+			default:
+				childType, ok := s.child(parent.typ, name, version)
+				if !ok {
+					msg := fmt.Sprintf("unknown element %s in %s", name, parent.path[strings.LastIndex(parent.path, "/")+1:])
+					if childType != "" {
+						msg = fmt.Sprintf("element %s is not defined in DMN %s", name, version)
+					}
+					findings = append(findings, ValidationError{
+						Field:    path,
+						Message:  msg,
+						Code:     CodeUnknownElement,
+						Position: pos,
+					})
+					typ = "unknown"
+				} else {
+					typ = childType
+					findings = append(findings, checkAttrs(s, typ, path, version, t.Attr, pos)...)
+				}
+			}
+			stack = append(stack, frame{typ: typ, path: path})
+		}
+	}
+
+	if len(findings) > 0 {
+		setDefaultSeverity(findings)
+		return &SchemaError{Version: version, Findings: findings}
+	}
+	return nil
+}
+
+// checkAttrs reports unqualified attributes the type does not declare
+func checkAttrs(s *schema, typ, path, version string, attrs []xml.Attr, pos Position) []ValidationError {
+	if typ == "text" || typ == "any" {
+		return nil
+	}
+
+	var findings []ValidationError
+	for _, a := range attrs {
+		if a.Name.Space != "" || a.Name.Local == "xmlns" {
+			continue
+		}
+		if !s.attr(typ, a.Name.Local, version) {
+			findings = append(findings, ValidationError{
+				Field:    path + "/@" + a.Name.Local,
+				Message:  fmt.Sprintf("unknown attribute %s on %s", a.Name.Local, path[strings.LastIndex(path, "/")+1:]),
+				Code:     CodeUnknownAttribute,
+				Position: pos,
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,139 @@
+package dmn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// versionNamespaces are the model namespaces of DMN 1.1-1.5
+var versionNamespaces = map[string]string{
+	"1.1": "http://www.omg.org/spec/DMN/20151101/dmn.xsd",
+	"1.2": "http://www.omg.org/spec/DMN/20180521/MODEL/",
+	"1.3": "https://www.omg.org/spec/DMN/20191111/MODEL/",
+	"1.4": "https://www.omg.org/spec/DMN/20211108/MODEL/",
+	"1.5": "https://www.omg.org/spec/DMN/20230324/MODEL/",
+}
+
+// schemaModel is a model in a DMN version with extra content inside the
+// definitions and the decision table. The extra content starts on line 3
+// and line 8
+func schemaModel(version, definitions, table string) string {
+	return `<definitions xmlns="` + versionNamespaces[version] + `" xmlns:camunda="http://camunda.org/schema/1.0/dmn" id="defs" name="Schema" namespace="http://example.org/schema">
+  <itemDefinition name="tAge"><typeRef>number</typeRef></itemDefinition>
+  ` + definitions + `
+  <decision id="d" name="D" camunda:historyTimeToLive="30">
+    <extensionElements><camunda:note>anything</camunda:note></extensionElements>
+    <decisionTable id="dt" hitPolicy="FIRST">
+      <input id="i1"><inputExpression typeRef="number"><text>age</text></inputExpression></input>
+      ` + table + `
+      <output id="o1" name="r"/>
+      <rule id="r1"><inputEntry><text>-</text></inputEntry><outputEntry><text>1</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+}
+
+func TestStrictParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		definitions string
+		table       string
+		code        string
+		message     string
+		line        int
+	}{
+		{name: "valid 1.1", version: "1.1"},
+		{name: "valid 1.2", version: "1.2"},
+		{name: "valid 1.3", version: "1.3"},
+		{name: "valid 1.4", version: "1.4"},
+		{name: "valid 1.5", version: "1.5"},
+		{
+			name: "annotation column in 1.1", version: "1.1",
+			table: `<annotation name="note"/>`,
+			code:  CodeUnknownElement, message: "element annotation is not defined in DMN 1.1", line: 8,
+		},
+		{
+			name: "group in 1.2", version: "1.2",
+			definitions: `<group id="g" name="G"/>`,
+			code:        CodeUnknownElement, message: "element group is not defined in DMN 1.2", line: 3,
+		},
+		{
+			name: "conditional in 1.3", version: "1.3",
+			definitions: `<decision id="c" name="C"><conditional/></decision>`,
+			code:        CodeUnknownElement, message: "element conditional is not defined in DMN 1.3", line: 3,
+		},
+		{
+			name: "type constraint in 1.4", version: "1.4",
+			definitions: `<itemDefinition name="tScore"><typeConstraint><text>&gt; 0</text></typeConstraint></itemDefinition>`,
+			code:        CodeUnknownElement, message: "element typeConstraint is not defined in DMN 1.4", line: 3,
+		},
+		{
+			name: "misspelled element in 1.5", version: "1.5",
+			table: `<inputEntri/>`,
+			code:  CodeUnknownElement, message: "unknown element inputEntri in decisionTable", line: 8,
+		},
+		{
+			name: "unknown attribute in 1.5", version: "1.5",
+			definitions: `<inputData id="age" name="age" typeRef="number"/>`,
+			code:        CodeUnknownAttribute, message: "unknown attribute typeRef on inputData", line: 3,
+		},
+		{
+			name: "foreign element in 1.5", version: "1.5",
+			definitions: `<camunda:note>outside extensions</camunda:note>`,
+			code:        CodeUnknownElement, message: "element {http://camunda.org/schema/1.0/dmn}note is not allowed outside extensionElements", line: 3,
+		},
+		{
+			name: "character data in 1.5", version: "1.5",
+			table: `stray`,
+			code:  CodeUnknownElement, message: "character data is not allowed here", line: 7,
+		},
+	}
+
+	parser := NewParserWithOptions(ParseOptions{Strict: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := schemaModel(tt.version, tt.definitions, tt.table)
+			_, err := parser.ParseBytes([]byte(src))
+			if tt.code == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("error = %v, want a schema error", err)
+			}
+			if schemaErr.Version != tt.version || len(schemaErr.Findings) != 1 {
+				t.Fatalf("schema error = %+v, want one finding for DMN %s", schemaErr, tt.version)
+			}
+			f := schemaErr.Findings[0]
+			if f.Code != tt.code || f.Message != tt.message || f.Severity != SeverityError {
+				t.Errorf("finding = %+v, want %s %q", f, tt.code, tt.message)
+			}
+			if f.Line != tt.line {
+				t.Errorf("line = %d, want %d", f.Line, tt.line)
+			}
+
+			// Without strict mode the document is accepted
+			if _, err := NewParser().ParseBytes([]byte(src)); err != nil {
+				t.Errorf("lenient parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestStrictParseRoot(t *testing.T) {
+	src := `<definitions xmlns="http://example.org/not-dmn" id="defs"/>`
+	_, err := NewParserWithOptions(ParseOptions{Strict: true}).ParseBytes([]byte(src))
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || len(schemaErr.Findings) != 1 || schemaErr.Findings[0].Code != CodeUnknownNamespace {
+		t.Fatalf("error = %v, want %s", err, CodeUnknownNamespace)
+	}
+	if !strings.HasPrefix(err.Error(), "document does not match the DMN schema: ") {
+		t.Errorf("error = %q", err)
+	}
+}
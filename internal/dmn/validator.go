@@ -3,26 +3,75 @@ package dmn
 import (
 	"fmt"
 	"strings"
+
+	"github.com/konstantin/dmn-engine-go/internal/feel"
 )
 
-// ValidationError represents a validation error
+// ValidationError represents a validation finding
 type ValidationError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
+	Field       string   `json:"field"`
+	Message     string   `json:"message"`
+	Code        string   `json:"code"`                  // stable identifier of the check
+	Severity    Severity `json:"severity"`              // error unless the check is a lint rule
+	RuleID      string   `json:"ruleId,omitempty"`      // rule of a decision table cell
+	ColumnIndex *int     `json:"columnIndex,omitempty"` // input or output column of a cell, starting at 0
+	Position             // location in the XML source, if known
 }
 
 func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s: %s (line %d, column %d)", e.Field, e.Message, e.Line, e.Column)
+	}
 	return fmt.Sprintf("%s: %s", e.Field, e.Message)
 }
 
+// cellError builds an error for a decision table cell or column
+func cellError(code, field, message, ruleID string, column int, pos Position) ValidationError {
+	return ValidationError{
+		Field:       field,
+		Message:     message,
+		Code:        code,
+		RuleID:      ruleID,
+		ColumnIndex: &column,
+		Position:    pos,
+	}
+}
+
+// ImportResolver returns the deployed model for an imported namespace
+type ImportResolver func(namespace string) (*Definitions, error)
+
 // Validator validates DMN models
-type Validator struct{}
+type Validator struct {
+	functions   map[string]*feel.Function // functions implemented in Go, by name
+	hitPolicies map[string]bool           // custom hit policies
+}
+
+// ValidatorOptions describes what an engine supports beyond the DMN
+// specification
+type ValidatorOptions struct {
+	// Functions may be called besides the built-ins. Invocations of typed
+	// functions are checked against their parameters
+	Functions map[string]*feel.Function
+
+	// HitPolicies are accepted besides the hit policies of the specification
+	HitPolicies []string
+}
 
 // NewValidator creates a new validator
 func NewValidator() *Validator {
 	return &Validator{}
 }
 
+// NewValidatorWithOptions creates a validator for models that use the
+// given extensions
+func NewValidatorWithOptions(opts ValidatorOptions) *Validator {
+	v := &Validator{functions: opts.Functions, hitPolicies: make(map[string]bool, len(opts.HitPolicies))}
+	for _, name := range opts.HitPolicies {
+		v.hitPolicies[name] = true
+	}
+	return v
+}
+
 // Validate validates a DMN definitions model and returns any errors found
 func (v *Validator) Validate(defs *Definitions) []ValidationError {
 	var errors []ValidationError
@@ -31,6 +80,7 @@ func (v *Validator) Validate(defs *Definitions) []ValidationError {
 	if defs.ID == "" {
 		errors = append(errors, ValidationError{
 			Field:   "definitions.id",
+			Code:    CodeMissingID,
 			Message: "definitions must have an id",
 		})
 	}
@@ -39,85 +89,357 @@ func (v *Validator) Validate(defs *Definitions) []ValidationError {
 	if len(defs.Decisions) == 0 {
 		errors = append(errors, ValidationError{
 			Field:   "definitions.decisions",
+			Code:    CodeNoDecisions,
 			Message: "definitions must have at least one decision",
 		})
 	}
 
+	// Names that FEEL expressions of the model may refer to
+	names := modelNames(defs)
+
 	// Check unique IDs
 	seenIDs := make(map[string]bool)
-	
+
 	for _, d := range defs.Decisions {
 		if d.ID == "" {
 			errors = append(errors, ValidationError{
-				Field:   "decision.id",
-				Message: "decision must have an id",
+				Field:    "decision.id",
+				Code:     CodeMissingID,
+				Message:  "decision must have an id",
+				Position: d.Pos,
 			})
 			continue
 		}
-		
+
 		if seenIDs[d.ID] {
 			errors = append(errors, ValidationError{
-				Field:   fmt.Sprintf("decision[%s].id", d.ID),
-				Message: "duplicate decision id",
+				Field:    fmt.Sprintf("decision[%s].id", d.ID),
+				Code:     CodeDuplicateID,
+				Message:  "duplicate decision id",
+				Position: d.Pos,
 			})
 		}
 		seenIDs[d.ID] = true
 
 		// Validate decision
-		errors = append(errors, v.validateDecision(&d)...)
+		errors = append(errors, v.validateDecision(&d, names)...)
 	}
 
 	for _, input := range defs.InputData {
 		if input.ID == "" {
 			errors = append(errors, ValidationError{
 				Field:   "inputData.id",
+				Code:    CodeMissingID,
 				Message: "inputData must have an id",
 			})
 			continue
 		}
-		
+
 		if seenIDs[input.ID] {
 			errors = append(errors, ValidationError{
 				Field:   fmt.Sprintf("inputData[%s].id", input.ID),
+				Code:    CodeDuplicateID,
 				Message: "duplicate id",
 			})
 		}
 		seenIDs[input.ID] = true
 	}
 
+	for _, bkm := range defs.BusinessKnowledgeModels {
+		if bkm.ID == "" {
+			errors = append(errors, ValidationError{
+				Field:   "businessKnowledgeModel.id",
+				Code:    CodeMissingID,
+				Message: "businessKnowledgeModel must have an id",
+			})
+			continue
+		}
+
+		if seenIDs[bkm.ID] {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("businessKnowledgeModel[%s].id", bkm.ID),
+				Code:    CodeDuplicateID,
+				Message: "duplicate id",
+			})
+		}
+		seenIDs[bkm.ID] = true
+
+		errors = append(errors, v.validateBusinessKnowledgeModel(&bkm, names)...)
+	}
+
+	for _, svc := range defs.DecisionServices {
+		if svc.ID == "" {
+			errors = append(errors, ValidationError{
+				Field:   "decisionService.id",
+				Code:    CodeMissingID,
+				Message: "decisionService must have an id",
+			})
+			continue
+		}
+
+		if seenIDs[svc.ID] {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("decisionService[%s].id", svc.ID),
+				Code:    CodeDuplicateID,
+				Message: "duplicate id",
+			})
+		}
+		seenIDs[svc.ID] = true
+
+		errors = append(errors, v.validateDecisionService(defs, &svc)...)
+	}
+
+	// Check that requirements point at existing elements
+	errors = append(errors, v.checkReferences(defs)...)
+
 	// Check for cyclic dependencies
 	if cycleErr := v.checkCyclicDependencies(defs); cycleErr != nil {
 		errors = append(errors, *cycleErr)
 	}
 
+	// Structural findings are errors; lint rules carry their own severity
+	setDefaultSeverity(errors)
+	errors = append(errors, v.Lint(defs)...)
+
 	return errors
 }
 
+// setDefaultSeverity marks findings without a severity as errors
+func setDefaultSeverity(findings []ValidationError) {
+	for i := range findings {
+		if findings[i].Severity == "" {
+			findings[i].Severity = SeverityError
+		}
+	}
+}
+
 // validateDecision validates a single decision
-func (v *Validator) validateDecision(d *Decision) []ValidationError {
+func (v *Validator) validateDecision(d *Decision, names map[string]bool) []ValidationError {
 	var errors []ValidationError
 	prefix := fmt.Sprintf("decision[%s]", d.ID)
 
 	// Decision must have either a decision table or literal expression
 	if d.DecisionTable == nil && d.LiteralExpression == nil {
 		errors = append(errors, ValidationError{
-			Field:   prefix,
-			Message: "decision must have either a decisionTable or literalExpression",
+			Field:    prefix,
+			Code:     CodeMissingLogic,
+			Message:  "decision must have either a decisionTable or literalExpression",
+			Position: d.Pos,
 		})
 		return errors
 	}
 
+	// The variable holds the decision result for dependent decisions
+	if d.Variable == nil {
+		errors = append(errors, ValidationError{
+			Field:    prefix + ".variable",
+			Code:     CodeMissingVariable,
+			Message:  "decision must have a variable",
+			Position: d.Pos,
+		})
+	}
+
 	// Validate decision table if present
 	if d.DecisionTable != nil {
-		errors = append(errors, v.validateDecisionTable(d.DecisionTable, prefix)...)
+		errors = append(errors, v.validateDecisionTable(d.DecisionTable, prefix, names)...)
 	}
 
 	// Validate literal expression if present
 	if d.LiteralExpression != nil {
-		if d.LiteralExpression.Text == "" {
+		errors = append(errors, v.validateLiteralExpression(d.LiteralExpression, prefix+".literalExpression", names)...)
+	}
+
+	return errors
+}
+
+// validateBusinessKnowledgeModel validates the body of a BKM
+func (v *Validator) validateBusinessKnowledgeModel(bkm *BusinessKnowledgeModel, names map[string]bool) []ValidationError {
+	var errors []ValidationError
+	prefix := fmt.Sprintf("businessKnowledgeModel[%s]", bkm.ID)
+
+	logic := bkm.EncapsulatedLogic
+	if logic == nil || (logic.LiteralExpression == nil && logic.DecisionTable == nil) {
+		errors = append(errors, ValidationError{
+			Field:    prefix + ".encapsulatedLogic",
+			Code:     CodeMissingLogic,
+			Message:  "business knowledge model must have a literalExpression or decisionTable",
+			Position: bkm.Pos,
+		})
+		return errors
+	}
+
+	// Formal parameters are visible in the body of the BKM
+	params := copyNames(names)
+	for _, param := range logic.FormalParameters {
+		params[param.Name] = true
+	}
+
+	if logic.DecisionTable != nil {
+		errors = append(errors, v.validateDecisionTable(logic.DecisionTable, prefix+".encapsulatedLogic", params)...)
+	}
+	if logic.LiteralExpression != nil {
+		errors = append(errors, v.validateLiteralExpression(logic.LiteralExpression, prefix+".encapsulatedLogic.literalExpression", params)...)
+	}
+
+	return errors
+}
+
+// ValidateImports resolves the DMN imports of a model and checks that every
+// reference into an imported namespace points at an existing element
+func (v *Validator) ValidateImports(defs *Definitions, resolve ImportResolver) []ValidationError {
+	var errors []ValidationError
+	imported := make(map[string]*Definitions)
+
+	for _, imp := range defs.Imports {
+		field := fmt.Sprintf("import[%s]", imp.Namespace)
+
+		if !imp.IsDMN() {
+			errors = append(errors, ValidationError{
+				Field:   field + ".importType",
+				Code:    CodeUnresolvedImport,
+				Message: fmt.Sprintf("unsupported import type: %s", imp.ImportType),
+			})
+			continue
+		}
+		if imp.Name == "" {
+			errors = append(errors, ValidationError{
+				Field:   field + ".name",
+				Code:    CodeUnresolvedImport,
+				Message: "import must have a name",
+			})
+		}
+
+		target, err := resolve(imp.Namespace)
+		if err != nil || target == nil {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Code:    CodeUnresolvedImport,
+				Message: fmt.Sprintf("cannot resolve import %q: no deployed definitions with namespace %s", imp.Name, imp.Namespace),
+			})
+			continue
+		}
+		imported[imp.Namespace] = target
+	}
+
+	// checkRef verifies an href into an imported namespace
+	checkRef := func(field, href string, exists func(*Definitions, string) bool) {
+		ns, id := SplitHref(href)
+		if ns == "" || ns == defs.Namespace {
+			return
+		}
+		if defs.GetImport(ns) == nil {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Code:    CodeUnresolvedImport,
+				Message: fmt.Sprintf("namespace %s is not imported", ns),
+			})
+			return
+		}
+		if target, ok := imported[ns]; ok && !exists(target, id) {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Code:    CodeUnknownReference,
+				Message: fmt.Sprintf("element %s does not exist in imported namespace %s", id, ns),
+			})
+		}
+	}
+	decisionExists := func(d *Definitions, id string) bool { return d.GetDecision(id) != nil }
+	bkmExists := func(d *Definitions, id string) bool { return d.GetBusinessKnowledgeModel(id) != nil }
+
+	for _, d := range defs.Decisions {
+		for _, req := range d.InformationRequirements {
+			if req.RequiredDecision != nil {
+				checkRef(fmt.Sprintf("decision[%s].requiredDecision", d.ID), req.RequiredDecision.Href, decisionExists)
+			}
+		}
+		for _, req := range d.KnowledgeRequirements {
+			if req.RequiredKnowledge != nil {
+				checkRef(fmt.Sprintf("decision[%s].requiredKnowledge", d.ID), req.RequiredKnowledge.Href, bkmExists)
+			}
+		}
+		if d.Variable != nil {
+			errors = append(errors, checkTypeRef(defs, imported, fmt.Sprintf("decision[%s].variable.typeRef", d.ID), d.Variable.TypeRef)...)
+		}
+	}
+	for _, bkm := range defs.BusinessKnowledgeModels {
+		for _, req := range bkm.KnowledgeRequirements {
+			if req.RequiredKnowledge != nil {
+				checkRef(fmt.Sprintf("businessKnowledgeModel[%s].requiredKnowledge", bkm.ID), req.RequiredKnowledge.Href, bkmExists)
+			}
+		}
+	}
+	for _, input := range defs.InputData {
+		if input.Variable != nil {
+			errors = append(errors, checkTypeRef(defs, imported, fmt.Sprintf("inputData[%s].variable.typeRef", input.ID), input.Variable.TypeRef)...)
+		}
+	}
+
+	setDefaultSeverity(errors)
+	return errors
+}
+
+// checkTypeRef verifies that a type qualified with an import name, such as
+// "lib.tCustomer", is defined by the imported model
+func checkTypeRef(defs *Definitions, imported map[string]*Definitions, field, typeRef string) []ValidationError {
+	idx := strings.Index(typeRef, ".")
+	if idx < 0 {
+		return nil
+	}
+	prefix, name := typeRef[:idx], typeRef[idx+1:]
+
+	for _, imp := range defs.Imports {
+		if imp.Name != prefix {
+			continue
+		}
+		target, ok := imported[imp.Namespace]
+		if ok && target.GetItemDefinition(name) == nil {
+			return []ValidationError{{
+				Field:   field,
+				Code:    CodeUnknownReference,
+				Message: fmt.Sprintf("type %s is not defined in imported namespace %s", name, imp.Namespace),
+			}}
+		}
+		return nil
+	}
+	return nil
+}
+
+// validateDecisionService validates the references of a decision service
+func (v *Validator) validateDecisionService(defs *Definitions, svc *DecisionService) []ValidationError {
+	var errors []ValidationError
+	prefix := fmt.Sprintf("decisionService[%s]", svc.ID)
+
+	if len(svc.OutputDecisions) == 0 {
+		errors = append(errors, ValidationError{
+			Field:   prefix + ".outputDecisions",
+			Code:    CodeMissingLogic,
+			Message: "decision service must have at least one output decision",
+		})
+	}
+
+	decisionRefs := map[string][]ElementReference{
+		"outputDecision":       svc.OutputDecisions,
+		"encapsulatedDecision": svc.EncapsulatedDecisions,
+		"inputDecision":        svc.InputDecisions,
+	}
+	for _, kind := range []string{"outputDecision", "encapsulatedDecision", "inputDecision"} {
+		for _, ref := range decisionRefs[kind] {
+			if defs.GetDecision(ref.ElementID()) == nil {
+				errors = append(errors, ValidationError{
+					Field:   fmt.Sprintf("%s.%s[%s]", prefix, kind, ref.Href),
+					Code:    CodeUnknownReference,
+					Message: "referenced decision does not exist",
+				})
+			}
+		}
+	}
+
+	for _, ref := range svc.InputData {
+		if defs.GetInputData(ref.ElementID()) == nil {
 			errors = append(errors, ValidationError{
-				Field:   prefix + ".literalExpression.text",
-				Message: "literal expression must have text",
+				Field:   fmt.Sprintf("%s.inputData[%s]", prefix, ref.Href),
+				Code:    CodeUnknownReference,
+				Message: "referenced inputData does not exist",
 			})
 		}
 	}
@@ -125,46 +447,123 @@ func (v *Validator) validateDecision(d *Decision) []ValidationError {
 	return errors
 }
 
-// validateDecisionTable validates a decision table
-func (v *Validator) validateDecisionTable(dt *DecisionTable, prefix string) []ValidationError {
+// validateDecisionTable validates a decision table and parses every cell
+func (v *Validator) validateDecisionTable(dt *DecisionTable, prefix string, names map[string]bool) []ValidationError {
 	var errors []ValidationError
 	prefix = prefix + ".decisionTable"
 
 	// Validate hit policy
-	if !isValidHitPolicy(dt.HitPolicy) {
+	if !isValidHitPolicy(dt.HitPolicy) && !v.hitPolicies[dt.HitPolicy] {
 		errors = append(errors, ValidationError{
-			Field:   prefix + ".hitPolicy",
-			Message: fmt.Sprintf("invalid hit policy: %s", dt.HitPolicy),
+			Field:    prefix + ".hitPolicy",
+			Code:     CodeInvalidHitPolicy,
+			Message:  fmt.Sprintf("invalid hit policy: %s", dt.HitPolicy),
+			Position: dt.Pos,
 		})
 	}
 
 	// Must have at least one output
 	if len(dt.Outputs) == 0 {
 		errors = append(errors, ValidationError{
-			Field:   prefix + ".outputs",
-			Message: "decision table must have at least one output",
+			Field:    prefix + ".outputs",
+			Code:     CodeMissingLogic,
+			Message:  "decision table must have at least one output",
+			Position: dt.Pos,
 		})
 	}
 
+	// Input expressions declare the variables the table reads, so cells may
+	// refer to them in addition to the names of the model
+	tableNames := copyNames(names)
+	for i, input := range dt.Inputs {
+		field := fmt.Sprintf("%s.inputs[%d].inputExpression", prefix, i)
+		if strings.TrimSpace(input.InputExpression.Text) == "" {
+			errors = append(errors, cellError(CodeMissingLogic, field, "input expression must have text", "", i, input.InputExpression.Pos))
+			continue
+		}
+
+		expr, err := feel.ParseExpression(input.InputExpression.Text)
+		if err != nil {
+			errors = append(errors, cellError(CodeFEELSyntax, field, "invalid FEEL expression: "+err.Error(), "", i, input.InputExpression.Pos))
+			continue
+		}
+		for _, name := range feel.FreeNames(expr) {
+			if !v.isKnown(name.Name, names) {
+				errors = append(errors, cellError(CodeUnknownName, field, fmt.Sprintf("unknown variable %q: no input data, decision or BKM declares it", name.Name), "", i, input.InputExpression.Pos))
+			}
+			tableNames[name.Name] = true
+		}
+	}
+
+	for i, output := range dt.Outputs {
+		if output.DefaultOutputEntry == nil {
+			continue
+		}
+		field := fmt.Sprintf("%s.outputs[%d].defaultOutputEntry", prefix, i)
+		if code, msg := v.checkExpression(output.DefaultOutputEntry.Text, tableNames); code != "" {
+			errors = append(errors, cellError(code, field, msg, "", i, output.DefaultOutputEntry.Pos))
+		}
+	}
+
+	// Input entries also see the value under test by its input variable
+	// name, "cellInput" unless the column sets camunda:inputVariable
+	columnNames := make([]map[string]bool, len(dt.Inputs))
+	for i := range dt.Inputs {
+		columnNames[i] = copyNames(tableNames)
+		columnNames[i][dt.Inputs[i].InputVariableName()] = true
+	}
+	entryNames := func(column int) map[string]bool {
+		if column < len(columnNames) {
+			return columnNames[column]
+		}
+		return tableNames
+	}
+
 	// Validate rules
 	for i, rule := range dt.Rules {
 		rulePrefix := fmt.Sprintf("%s.rules[%d]", prefix, i)
-		
+
 		// Rule must have same number of input entries as inputs
 		if len(rule.InputEntries) != len(dt.Inputs) {
 			errors = append(errors, ValidationError{
-				Field:   rulePrefix + ".inputEntries",
-				Message: fmt.Sprintf("expected %d input entries, got %d", len(dt.Inputs), len(rule.InputEntries)),
+				Field:    rulePrefix + ".inputEntries",
+				Code:     CodeEntryCount,
+				Message:  fmt.Sprintf("expected %d input entries, got %d", len(dt.Inputs), len(rule.InputEntries)),
+				RuleID:   rule.ID,
+				Position: rule.Pos,
 			})
 		}
-		
+
 		// Rule must have same number of output entries as outputs
 		if len(rule.OutputEntries) != len(dt.Outputs) {
 			errors = append(errors, ValidationError{
-				Field:   rulePrefix + ".outputEntries",
-				Message: fmt.Sprintf("expected %d output entries, got %d", len(dt.Outputs), len(rule.OutputEntries)),
+				Field:    rulePrefix + ".outputEntries",
+				Code:     CodeEntryCount,
+				Message:  fmt.Sprintf("expected %d output entries, got %d", len(dt.Outputs), len(rule.OutputEntries)),
+				RuleID:   rule.ID,
+				Position: rule.Pos,
 			})
 		}
+
+		for j, entry := range rule.InputEntries {
+			field := fmt.Sprintf("%s.inputEntries[%d]", rulePrefix, j)
+			if code, msg := v.checkUnaryTests(entry.Text, entryNames(j)); code != "" {
+				errors = append(errors, cellError(code, field, msg, rule.ID, j, entry.Pos))
+			}
+		}
+
+		for j, entry := range rule.OutputEntries {
+			field := fmt.Sprintf("%s.outputEntries[%d]", rulePrefix, j)
+			if code, msg := v.checkExpression(entry.Text, tableNames); code != "" {
+				errors = append(errors, cellError(code, field, msg, rule.ID, j, entry.Pos))
+			}
+		}
+	}
+
+	// Overlapping rules fail UNIQUE tables at runtime, and ANY tables when
+	// their outputs differ. Skip the analysis while cells are invalid
+	if len(errors) == 0 && (dt.HitPolicy == HitPolicyUnique || dt.HitPolicy == HitPolicyAny) {
+		errors = append(errors, overlapErrors(dt, prefix)...)
 	}
 
 	// Validate aggregation for COLLECT policy
@@ -172,6 +571,7 @@ func (v *Validator) validateDecisionTable(dt *DecisionTable, prefix string) []Va
 		if !isValidAggregation(dt.Aggregation) {
 			errors = append(errors, ValidationError{
 				Field:   prefix + ".aggregation",
+				Code:    CodeInvalidAggregation,
 				Message: fmt.Sprintf("invalid aggregation: %s", dt.Aggregation),
 			})
 		}
@@ -180,49 +580,283 @@ func (v *Validator) validateDecisionTable(dt *DecisionTable, prefix string) []Va
 	return errors
 }
 
-// checkCyclicDependencies checks for cyclic dependencies in the DRG
+// overlapErrors reports rule pairs that violate the hit policy of the table
+func overlapErrors(dt *DecisionTable, prefix string) []ValidationError {
+	var errors []ValidationError
+	for _, overlap := range AnalyzeDecisionTable(dt).Overlaps {
+		first, second := overlap.Rules[0], overlap.Rules[1]
+		if dt.HitPolicy == HitPolicyAny && sameOutputs(&dt.Rules[first.Index], &dt.Rules[second.Index]) {
+			continue
+		}
+
+		rule := &dt.Rules[second.Index]
+		errors = append(errors, ValidationError{
+			Field: fmt.Sprintf("%s.rules[%d]", prefix, second.Index),
+			Code:  CodeRuleOverlap,
+			Message: fmt.Sprintf("rule %s overlaps rule %s under %s hit policy, e.g. for %s",
+				ruleLabel(second), ruleLabel(first), dt.HitPolicy, FormatExample(dt, overlap.Example)),
+			RuleID:   rule.ID,
+			Position: rule.Pos,
+		})
+	}
+	return errors
+}
+
+// sameOutputs reports whether two rules have identical output entries
+func sameOutputs(a, b *Rule) bool {
+	if len(a.OutputEntries) != len(b.OutputEntries) {
+		return false
+	}
+	for i := range a.OutputEntries {
+		if strings.TrimSpace(a.OutputEntries[i].Text) != strings.TrimSpace(b.OutputEntries[i].Text) {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleLabel names a rule by ID, or by position when it has none
+func ruleLabel(r RuleRef) string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return fmt.Sprintf("#%d", r.Index)
+}
+
+// validateLiteralExpression checks that a literal expression is valid FEEL
+// and refers only to known names
+func (v *Validator) validateLiteralExpression(expr *LiteralExpression, field string, names map[string]bool) []ValidationError {
+	if strings.TrimSpace(expr.Text) == "" {
+		return []ValidationError{{
+			Field:    field + ".text",
+			Code:     CodeMissingLogic,
+			Message:  "literal expression must have text",
+			Position: expr.Pos,
+		}}
+	}
+
+	if code, msg := v.checkExpression(expr.Text, names); code != "" {
+		return []ValidationError{{
+			Field:    field + ".text",
+			Code:     code,
+			Message:  msg,
+			Position: expr.Pos,
+		}}
+	}
+	return nil
+}
+
+// checkUnaryTests parses an input entry and checks the names it refers to;
+// it returns the code and message of the first problem, or empty strings
+func (v *Validator) checkUnaryTests(text string, names map[string]bool) (string, string) {
+	tests, err := feel.ParseUnaryTests(text)
+	if err != nil {
+		return CodeFEELSyntax, "invalid FEEL unary tests: " + err.Error()
+	}
+	return v.checkNames(tests, names)
+}
+
+// checkExpression parses an output entry and checks the names it refers to;
+// empty entries are valid and evaluate to null
+func (v *Validator) checkExpression(text string, names map[string]bool) (string, string) {
+	if strings.TrimSpace(text) == "" {
+		return "", ""
+	}
+
+	expr, err := feel.ParseExpression(text)
+	if err != nil {
+		return CodeFEELSyntax, "invalid FEEL expression: " + err.Error()
+	}
+	return v.checkNames(expr, names)
+}
+
+// checkNames reports the first name of an expression that is neither known
+// to the model nor a function, or else the first invocation of a typed
+// function whose arguments do not fit its parameters
+func (v *Validator) checkNames(expr feel.Node, names map[string]bool) (string, string) {
+	for _, name := range feel.FreeNames(expr) {
+		if !v.isKnown(name.Name, names) {
+			return CodeUnknownName, fmt.Sprintf("unknown name %q at position %d", name.Name, name.Pos())
+		}
+	}
+
+	var code, msg string
+	feel.Walk(expr, func(n feel.Node) bool {
+		if code != "" {
+			return false
+		}
+		call, ok := n.(*feel.Call)
+		if !ok {
+			return true
+		}
+		callee, ok := call.Callee.(*feel.Name)
+		if !ok || names[callee.Name] || v.functions[callee.Name] == nil {
+			return true
+		}
+		if problem := feel.CheckArguments(v.functions[callee.Name], len(call.Args), call.ArgNames); problem != "" {
+			code, msg = CodeFunctionSignature, fmt.Sprintf("%s at position %d", problem, call.Pos())
+		}
+		return true
+	})
+	return code, msg
+}
+
+// isKnown reports whether a free name of an expression resolves to a name
+// of the model, a built-in or a registered function
+func (v *Validator) isKnown(name string, names map[string]bool) bool {
+	return names[name] || feel.IsBuiltin(name) || v.functions[name] != nil
+}
+
+// modelNames returns the names visible to FEEL expressions of a model:
+// input data, decisions, BKMs and imports
+func modelNames(defs *Definitions) map[string]bool {
+	names := make(map[string]bool)
+	for _, input := range defs.InputData {
+		names[variableName(input.Variable, input.Name)] = true
+	}
+	for _, d := range defs.Decisions {
+		names[variableName(d.Variable, d.Name)] = true
+	}
+	for _, bkm := range defs.BusinessKnowledgeModels {
+		names[variableName(bkm.Variable, bkm.Name)] = true
+	}
+	for _, imp := range defs.Imports {
+		names[imp.Name] = true
+	}
+	return names
+}
+
+// variableName returns the variable name of an element, falling back to
+// the element name
+func variableName(v *Variable, name string) string {
+	if v != nil && v.Name != "" {
+		return v.Name
+	}
+	return name
+}
+
+func copyNames(names map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(names))
+	for name := range names {
+		result[name] = true
+	}
+	return result
+}
+
+// checkReferences reports requirements that point at elements missing from
+// the model. References into imported namespaces are checked by ValidateImports
+func (v *Validator) checkReferences(defs *Definitions) []ValidationError {
+	var errors []ValidationError
+	local := func(href string) (string, bool) {
+		ns, id := SplitHref(href)
+		return id, ns == "" || ns == defs.Namespace
+	}
+	knowledge := func(prefix string, reqs []KnowledgeRequirement, pos Position) {
+		for i, req := range reqs {
+			if req.RequiredKnowledge == nil {
+				continue
+			}
+			if id, ok := local(req.RequiredKnowledge.Href); ok && defs.GetBusinessKnowledgeModel(id) == nil {
+				errors = append(errors, ValidationError{
+					Field:    fmt.Sprintf("%s.knowledgeRequirement[%d].requiredKnowledge", prefix, i),
+					Code:     CodeUnknownReference,
+					Message:  fmt.Sprintf("references unknown business knowledge model %s", id),
+					Position: pos,
+				})
+			}
+		}
+	}
+
+	for _, d := range defs.Decisions {
+		prefix := fmt.Sprintf("decision[%s]", d.ID)
+		for i, req := range d.InformationRequirements {
+			field := fmt.Sprintf("%s.informationRequirement[%d]", prefix, i)
+			if req.RequiredDecision != nil {
+				if id, ok := local(req.RequiredDecision.Href); ok && defs.GetDecision(id) == nil {
+					errors = append(errors, ValidationError{
+						Field:    field + ".requiredDecision",
+						Code:     CodeUnknownReference,
+						Message:  fmt.Sprintf("references unknown decision %s", id),
+						Position: d.Pos,
+					})
+				}
+			}
+			if req.RequiredInput != nil {
+				if id, ok := local(req.RequiredInput.Href); ok && defs.GetInputData(id) == nil {
+					errors = append(errors, ValidationError{
+						Field:    field + ".requiredInput",
+						Code:     CodeUnknownReference,
+						Message:  fmt.Sprintf("references unknown input data %s", id),
+						Position: d.Pos,
+					})
+				}
+			}
+		}
+		knowledge(prefix, d.KnowledgeRequirements, d.Pos)
+	}
+
+	for _, bkm := range defs.BusinessKnowledgeModels {
+		knowledge(fmt.Sprintf("businessKnowledgeModel[%s]", bkm.ID), bkm.KnowledgeRequirements, bkm.Pos)
+	}
+
+	return errors
+}
+
+// checkCyclicDependencies checks for cyclic dependencies in the DRG and
+// reports the full path of the first cycle found
 func (v *Validator) checkCyclicDependencies(defs *Definitions) *ValidationError {
-	// Build dependency graph
+	// Build dependency graph of local decisions
 	graph := make(map[string][]string)
 	for _, d := range defs.Decisions {
 		deps := make([]string, 0)
 		for _, req := range d.InformationRequirements {
 			if req.RequiredDecision != nil {
-				// Remove the # prefix from href
-				depID := strings.TrimPrefix(req.RequiredDecision.Href, "#")
-				deps = append(deps, depID)
+				ns, depID := SplitHref(req.RequiredDecision.Href)
+				if ns == "" || ns == defs.Namespace {
+					deps = append(deps, depID)
+				}
 			}
 		}
 		graph[d.ID] = deps
 	}
 
-	// Check for cycles using DFS
+	// Check for cycles using DFS, keeping the current path
 	visited := make(map[string]int) // 0: unvisited, 1: visiting, 2: visited
-	
-	var hasCycle func(node string) bool
-	hasCycle = func(node string) bool {
+	var path []string
+
+	var findCycle func(node string) []string
+	findCycle = func(node string) []string {
 		if visited[node] == 1 {
-			return true // cycle detected
+			// The cycle starts where node entered the path
+			for i, n := range path {
+				if n == node {
+					return append(append([]string{}, path[i:]...), node)
+				}
+			}
 		}
 		if visited[node] == 2 {
-			return false // already processed
+			return nil // already processed
 		}
-		
+
 		visited[node] = 1
+		path = append(path, node)
 		for _, dep := range graph[node] {
-			if hasCycle(dep) {
-				return true
+			if cycle := findCycle(dep); cycle != nil {
+				return cycle
 			}
 		}
+		path = path[:len(path)-1]
 		visited[node] = 2
-		return false
+		return nil
 	}
 
-	for id := range graph {
-		if hasCycle(id) {
+	// Walk decisions in document order for a stable report
+	for _, d := range defs.Decisions {
+		if cycle := findCycle(d.ID); cycle != nil {
 			return &ValidationError{
 				Field:   "decisions",
-				Message: fmt.Sprintf("cyclic dependency detected involving decision: %s", id),
+				Code:    CodeCyclicDependency,
+				Message: fmt.Sprintf("cyclic dependency detected: %s", strings.Join(cycle, " -> ")),
 			}
 		}
 	}
@@ -232,7 +866,7 @@ func (v *Validator) checkCyclicDependencies(defs *Definitions) *ValidationError
 
 func isValidHitPolicy(hp string) bool {
 	switch hp {
-	case "", HitPolicyUnique, HitPolicyFirst, HitPolicyPriority, 
+	case "", HitPolicyUnique, HitPolicyFirst, HitPolicyPriority,
 		HitPolicyAny, HitPolicyCollect, HitPolicyRuleOrder, HitPolicyOutputOrder:
 		return true
 	default:
@@ -248,4 +882,3 @@ func isValidAggregation(agg string) bool {
 		return false
 	}
 }
-
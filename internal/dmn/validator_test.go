@@ -0,0 +1,391 @@
+package dmn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+)
+
+// parse parses a model of a test
+func parse(t *testing.T, xml string) *Definitions {
+	t.Helper()
+	defs, err := NewParser().ParseBytes([]byte(xml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return defs
+}
+
+// codes returns the codes of findings, in order
+func codes(findings []ValidationError) []string {
+	var result []string
+	for _, f := range findings {
+		result = append(result, f.Code)
+	}
+	return result
+}
+
+const importedLib = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="lib" name="Lib" namespace="http://example.org/lib">
+  <itemDefinition name="tScore"><typeRef>number</typeRef></itemDefinition>
+  <businessKnowledgeModel id="double" name="double">
+    <variable name="double"/>
+    <encapsulatedLogic><formalParameter name="x"/><literalExpression><text>x * 2</text></literalExpression></encapsulatedLogic>
+  </businessKnowledgeModel>
+  <decision id="riskScore" name="Risk Score">
+    <variable name="Risk Score"/>
+    <literalExpression><text>1</text></literalExpression>
+  </decision>
+</definitions>`
+
+func importingModel(body string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="main" name="Main" namespace="http://example.org/main">
+  <import namespace="http://example.org/lib" name="lib" importType="https://www.omg.org/spec/DMN/20191111/MODEL/"/>
+  ` + body + `
+</definitions>`
+}
+
+func TestValidateImports(t *testing.T) {
+	lib := parse(t, importedLib)
+	resolve := func(namespace string) (*Definitions, error) {
+		if namespace == lib.Namespace {
+			return lib, nil
+		}
+		return nil, errors.New("not found")
+	}
+	missing := func(string) (*Definitions, error) { return nil, errors.New("not found") }
+
+	tests := []struct {
+		name    string
+		body    string
+		resolve ImportResolver
+		want    []string
+		message string
+	}{
+		{
+			name: "resolved",
+			body: `<decision id="result" name="Result">
+    <variable name="Result" typeRef="lib.tScore"/>
+    <informationRequirement><requiredDecision href="http://example.org/lib#riskScore"/></informationRequirement>
+    <knowledgeRequirement><requiredKnowledge href="http://example.org/lib#double"/></knowledgeRequirement>
+    <literalExpression><text>lib.double(lib.Risk Score)</text></literalExpression>
+  </decision>`,
+			resolve: resolve,
+		},
+		{
+			name: "missing import",
+			body: `<decision id="result" name="Result">
+    <variable name="Result"/>
+    <informationRequirement><requiredDecision href="http://example.org/lib#riskScore"/></informationRequirement>
+    <literalExpression><text>lib.Risk Score</text></literalExpression>
+  </decision>`,
+			resolve: missing,
+			want:    []string{CodeUnresolvedImport},
+			message: `cannot resolve import "lib": no deployed definitions with namespace http://example.org/lib`,
+		},
+		{
+			name: "unknown element",
+			body: `<decision id="result" name="Result">
+    <variable name="Result"/>
+    <informationRequirement><requiredDecision href="http://example.org/lib#nope"/></informationRequirement>
+    <literalExpression><text>1</text></literalExpression>
+  </decision>`,
+			resolve: resolve,
+			want:    []string{CodeUnknownReference},
+			message: "element nope does not exist in imported namespace http://example.org/lib",
+		},
+		{
+			name: "namespace not imported",
+			body: `<decision id="result" name="Result">
+    <variable name="Result"/>
+    <knowledgeRequirement><requiredKnowledge href="http://example.org/other#double"/></knowledgeRequirement>
+    <literalExpression><text>1</text></literalExpression>
+  </decision>`,
+			resolve: resolve,
+			want:    []string{CodeUnresolvedImport},
+			message: "namespace http://example.org/other is not imported",
+		},
+		{
+			name: "unknown imported type",
+			body: `<decision id="result" name="Result">
+    <variable name="Result" typeRef="lib.tMissing"/>
+    <literalExpression><text>1</text></literalExpression>
+  </decision>`,
+			resolve: resolve,
+			want:    []string{CodeUnknownReference},
+			message: "type tMissing is not defined in imported namespace http://example.org/lib",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := NewValidator().ValidateImports(parse(t, importingModel(tt.body)), tt.resolve)
+			if strings.Join(codes(findings), ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("findings = %+v, want codes %v", findings, tt.want)
+			}
+			if tt.message != "" && findings[0].Message != tt.message {
+				t.Errorf("message = %q, want %q", findings[0].Message, tt.message)
+			}
+			for _, f := range findings {
+				if f.Severity != SeverityError {
+					t.Errorf("%s has severity %s, want error", f.Code, f.Severity)
+				}
+			}
+		})
+	}
+}
+
+// cellModel is a one-rule table whose cells are set by a test, escaped as
+// XML text. The input expression is on line 7, the default output entry on
+// line 8 and the rule on line 9
+func cellModel(inputExpr, inputEntry, outputEntry, defaultEntry string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Cells" namespace="http://example.org/cells">
+  <inputData id="in_age" name="age"><variable name="age"/></inputData>
+  <decision id="d" name="D">
+    <variable name="D"/>
+    <informationRequirement><requiredInput href="#in_age"/></informationRequirement>
+    <decisionTable hitPolicy="FIRST">
+      <input id="i1"><inputExpression><text>` + inputExpr + `</text></inputExpression></input>
+      <output id="o1" name="r"><defaultOutputEntry><text>` + defaultEntry + `</text></defaultOutputEntry></output>
+      <rule id="r1"><inputEntry><text>` + inputEntry + `</text></inputEntry><outputEntry><text>` + outputEntry + `</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+}
+
+// blocking returns the findings with error severity
+func blocking(findings []ValidationError) []ValidationError {
+	var result []ValidationError
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func TestValidateCells(t *testing.T) {
+	type want struct {
+		code   string
+		field  string
+		ruleID string
+		line   int
+		msg    string
+	}
+	tests := []struct {
+		name                                         string
+		inputExpr, inputEntry, outputEntry, defaults string
+		want                                         *want
+	}{
+		{"valid", "age", "&lt; 18", `"minor"`, `"adult"`, nil},
+		{"input variable in output", "age", "&lt; 18", "age * 2", "null", nil},
+		{"cellInput in input entry", "age", "cellInput > 1", "1", "2", nil},
+		{"input test with ?", "age", "? > 1", "1", "2", nil},
+		{"builtin in input entry", "age", "&lt; abs(-18)", "1", "2", nil},
+		{"empty cells", "age", "", "", "", nil},
+		{
+			name: "input entry syntax", inputExpr: "age", inputEntry: "&lt; 18 )", outputEntry: "1", defaults: "2",
+			want: &want{CodeFEELSyntax, "decision[d].decisionTable.rules[0].inputEntries[0]", "r1", 9,
+				`invalid FEEL unary tests: syntax error at position 5: unexpected ")"`},
+		},
+		{
+			name: "unknown name in input entry", inputExpr: "age", inputEntry: "> limit", outputEntry: "1", defaults: "2",
+			want: &want{CodeUnknownName, "decision[d].decisionTable.rules[0].inputEntries[0]", "r1", 9,
+				`unknown name "limit" at position 2`},
+		},
+		{
+			name: "unknown name in output entry", inputExpr: "age", inputEntry: "-", outputEntry: "bonus + 1", defaults: "2",
+			want: &want{CodeUnknownName, "decision[d].decisionTable.rules[0].outputEntries[0]", "r1", 9,
+				`unknown name "bonus" at position 0`},
+		},
+		{
+			name: "default output syntax", inputExpr: "age", inputEntry: "-", outputEntry: "1", defaults: `"adult`,
+			want: &want{CodeFEELSyntax, "decision[d].decisionTable.outputs[0].defaultOutputEntry", "", 8,
+				"invalid FEEL expression: syntax error at position 0: unterminated string literal"},
+		},
+		{
+			name: "unknown input expression", inputExpr: "income", inputEntry: "-", outputEntry: "1", defaults: "2",
+			want: &want{CodeUnknownName, "decision[d].decisionTable.inputs[0].inputExpression", "", 7,
+				`unknown variable "income": no input data, decision or BKM declares it`},
+		},
+		{
+			name: "input expression syntax", inputExpr: "age +", inputEntry: "-", outputEntry: "1", defaults: "2",
+			want: &want{CodeFEELSyntax, "decision[d].decisionTable.inputs[0].inputExpression", "", 7,
+				"invalid FEEL expression: syntax error at position 5: unexpected end of expression"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs := parse(t, cellModel(tt.inputExpr, tt.inputEntry, tt.outputEntry, tt.defaults))
+			findings := blocking(NewValidator().Validate(defs))
+			if tt.want == nil {
+				if len(findings) > 0 {
+					t.Fatalf("unexpected findings: %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("findings = %+v, want one %s", findings, tt.want.code)
+			}
+			f := findings[0]
+			if f.Code != tt.want.code || f.Field != tt.want.field || f.RuleID != tt.want.ruleID {
+				t.Errorf("finding %s at %s (rule %q), want %s at %s (rule %q)", f.Code, f.Field, f.RuleID, tt.want.code, tt.want.field, tt.want.ruleID)
+			}
+			if f.Message != tt.want.msg {
+				t.Errorf("message = %q, want %q", f.Message, tt.want.msg)
+			}
+			if f.ColumnIndex == nil || *f.ColumnIndex != 0 {
+				t.Errorf("column index = %v, want 0", f.ColumnIndex)
+			}
+			if f.Line != tt.want.line || f.Column == 0 {
+				t.Errorf("position = %d:%d, want line %d", f.Line, f.Column, tt.want.line)
+			}
+		})
+	}
+}
+
+func TestValidateEntryCount(t *testing.T) {
+	xml := strings.Replace(cellModel("age", "&lt; 18", "1", "2"),
+		`<inputEntry><text>&lt; 18</text></inputEntry>`, `<inputEntry><text>&lt; 18</text></inputEntry><inputEntry><text>-</text></inputEntry>`, 1)
+	findings := blocking(NewValidator().Validate(parse(t, xml)))
+	if len(findings) != 1 || findings[0].Code != CodeEntryCount {
+		t.Fatalf("findings = %+v, want %s", findings, CodeEntryCount)
+	}
+	if findings[0].Message != "expected 1 input entries, got 2" || findings[0].RuleID != "r1" || findings[0].Line != 9 {
+		t.Errorf("finding = %+v", findings[0])
+	}
+}
+
+func TestValidateFunctions(t *testing.T) {
+	discount, err := feel.NewFunction("discount", []feel.Param{
+		{Name: "amount", Type: "number"},
+		{Name: "code", Type: "string", Optional: true},
+	}, func(args []interface{}) (interface{}, error) { return args[0], nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions := map[string]*feel.Function{"discount": discount}
+
+	tests := []struct {
+		name      string
+		output    string
+		functions map[string]*feel.Function
+		want      string // code of the finding, "" for none
+	}{
+		{"registered", "discount(age)", functions, ""},
+		{"optional argument", `discount(age, "X")`, functions, ""},
+		{"named arguments", "discount(amount: age)", functions, ""},
+		{"not registered", "discount(age)", nil, CodeUnknownName},
+		{"too many arguments", `discount(age, "X", 1)`, functions, CodeFunctionSignature},
+		{"missing argument", "discount()", functions, CodeFunctionSignature},
+		{"unknown parameter", "discount(amount: age, rate: 1)", functions, CodeFunctionSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs := parse(t, cellModel("age", "-", tt.output, ""))
+			findings := blocking(NewValidatorWithOptions(ValidatorOptions{Functions: tt.functions}).Validate(defs))
+			if got := strings.Join(codes(findings), ","); got != tt.want {
+				t.Errorf("findings = %+v, want %q", findings, tt.want)
+			}
+		})
+	}
+}
+
+// drgModel is a model of the given decisions and BKMs besides the input
+// data "in_x" and the decision "base"
+func drgModel(body string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="DRG" namespace="http://example.org/drg">
+  <inputData id="in_x" name="x"><variable name="x"/></inputData>
+  <decision id="base" name="base"><variable name="base"/><literalExpression><text>1</text></literalExpression></decision>
+  ` + body + `
+</definitions>`
+}
+
+// drgDecision is a decision d with the given requirements
+func drgDecision(requirements string) string {
+	return `<decision id="d" name="d"><variable name="d"/>` + requirements + `<literalExpression><text>1</text></literalExpression></decision>`
+}
+
+func TestValidateRequirements(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		code  string // "" for a valid model
+		field string
+		msg   string
+	}{
+		{
+			name: "valid",
+			body: drgDecision(`<informationRequirement><requiredDecision href="#base"/></informationRequirement>` +
+				`<informationRequirement><requiredInput href="#in_x"/></informationRequirement>`),
+		},
+		{
+			name:  "dangling requiredDecision",
+			body:  drgDecision(`<informationRequirement><requiredDecision href="#missing"/></informationRequirement>`),
+			code:  CodeUnknownReference,
+			field: "decision[d].informationRequirement[0].requiredDecision",
+			msg:   "references unknown decision missing",
+		},
+		{
+			name: "dangling requiredInput",
+			body: drgDecision(`<informationRequirement><requiredDecision href="#base"/></informationRequirement>` +
+				`<informationRequirement><requiredInput href="#in_y"/></informationRequirement>`),
+			code:  CodeUnknownReference,
+			field: "decision[d].informationRequirement[1].requiredInput",
+			msg:   "references unknown input data in_y",
+		},
+		{
+			name:  "dangling knowledgeRequirement of a decision",
+			body:  drgDecision(`<knowledgeRequirement><requiredKnowledge href="#rate"/></knowledgeRequirement>`),
+			code:  CodeUnknownReference,
+			field: "decision[d].knowledgeRequirement[0].requiredKnowledge",
+			msg:   "references unknown business knowledge model rate",
+		},
+		{
+			name: "dangling knowledgeRequirement of a BKM",
+			body: `<businessKnowledgeModel id="fee" name="fee"><variable name="fee"/>` +
+				`<knowledgeRequirement><requiredKnowledge href="#rate"/></knowledgeRequirement>` +
+				`<encapsulatedLogic><literalExpression><text>1</text></literalExpression></encapsulatedLogic></businessKnowledgeModel>`,
+			code:  CodeUnknownReference,
+			field: "businessKnowledgeModel[fee].knowledgeRequirement[0].requiredKnowledge",
+			msg:   "references unknown business knowledge model rate",
+		},
+		{
+			name:  "missing variable",
+			body:  `<decision id="d" name="d"><literalExpression><text>1</text></literalExpression></decision>`,
+			code:  CodeMissingVariable,
+			field: "decision[d].variable",
+			msg:   "decision must have a variable",
+		},
+		{
+			name: "cycle",
+			body: `<decision id="a" name="a"><variable name="a"/><informationRequirement><requiredDecision href="#b"/></informationRequirement><literalExpression><text>1</text></literalExpression></decision>
+  <decision id="b" name="b"><variable name="b"/><informationRequirement><requiredDecision href="#c"/></informationRequirement><literalExpression><text>1</text></literalExpression></decision>
+  <decision id="c" name="c"><variable name="c"/><informationRequirement><requiredDecision href="#base"/></informationRequirement><informationRequirement><requiredDecision href="#a"/></informationRequirement><literalExpression><text>1</text></literalExpression></decision>`,
+			code:  CodeCyclicDependency,
+			field: "decisions",
+			msg:   "cyclic dependency detected: a -> b -> c -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := blocking(NewValidator().Validate(parse(t, drgModel(tt.body))))
+			if tt.code == "" {
+				if len(findings) > 0 {
+					t.Fatalf("unexpected findings: %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("findings = %+v, want one %s", findings, tt.code)
+			}
+			if f := findings[0]; f.Code != tt.code || f.Field != tt.field || f.Message != tt.msg {
+				t.Errorf("finding %s at %s: %q, want %s at %s: %q", f.Code, f.Field, f.Message, tt.code, tt.field, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package dmn
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// defaultModelNamespace is used for models that were not parsed from XML
+// and for normalized DMN 1.1 models
+const defaultModelNamespace = "https://www.omg.org/spec/DMN/20191111/MODEL/"
+
+// Writer serializes Definitions to DMN XML. Parsing the output yields the
+// same model, including the DMNDI section when the source had one
+type Writer struct {
+	Indent string
+}
+
+// NewWriter creates a new DMN writer
+func NewWriter() *Writer {
+	return &Writer{Indent: "    "}
+}
+
+// Write writes the model as DMN XML
+func (w *Writer) Write(out io.Writer, defs *Definitions) error {
+	data, err := w.WriteBytes(defs)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// WriteBytes returns the model as DMN XML
+func (w *Writer) WriteBytes(defs *Definitions) ([]byte, error) {
+	if defs == nil {
+		return nil, fmt.Errorf("no definitions to write")
+	}
+
+	x := &xmlWriter{indent: w.Indent}
+	x.buf.WriteString(xml.Header)
+
+	// The parser brings DMN 1.1 constructs to the current model, so such
+	// models are written in the current namespace
+	namespace := defs.XMLName.Space
+	if namespace == "" || namespaceVersion(namespace) == "1.1" {
+		namespace = defaultModelNamespace
+	}
+
+	attrs := []string{"xmlns", namespace}
+	prefixes := make([]string, 0, len(defs.NamespacePrefixes))
+	for prefix := range defs.NamespacePrefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		attrs = append(attrs, "xmlns:"+prefix, defs.NamespacePrefixes[prefix])
+	}
+	// Camunda extension attributes need a bound prefix
+	x.camunda = prefixFor(CamundaNamespace, defs.NamespacePrefixes, nil)
+	if x.camunda == "" && usesCamundaExtensions(defs) {
+		x.camunda = "camunda"
+		attrs = append(attrs, "xmlns:camunda", CamundaNamespace)
+	}
+	attrs = append(attrs,
+		"id", defs.ID,
+		"name", defs.Name,
+		"namespace", defs.Namespace,
+		"expressionLanguage", defs.ExpressionLanguage,
+	)
+	x.start("definitions", attrs...)
+
+	for _, imp := range defs.Imports {
+		x.empty("import",
+			"namespace", imp.Namespace,
+			"name", imp.Name,
+			"importType", imp.ImportType,
+			"locationURI", imp.LocationURI,
+		)
+	}
+	for i := range defs.ItemDefinitions {
+		writeItemDefinition(x, "itemDefinition", &defs.ItemDefinitions[i])
+	}
+	for i := range defs.InputData {
+		input := &defs.InputData[i]
+		x.start("inputData", "id", input.ID, "name", input.Name)
+		writeVariable(x, "variable", input.Variable)
+		x.end("inputData")
+	}
+	for i := range defs.BusinessKnowledgeModels {
+		writeBusinessKnowledgeModel(x, &defs.BusinessKnowledgeModels[i])
+	}
+	for i := range defs.Decisions {
+		writeDecision(x, &defs.Decisions[i])
+	}
+	for i := range defs.DecisionServices {
+		writeDecisionService(x, &defs.DecisionServices[i])
+	}
+	if defs.DMNDI != nil {
+		writeDMNDI(x, defs.DMNDI, defs.NamespacePrefixes)
+	}
+
+	x.end("definitions")
+	return x.buf.Bytes(), nil
+}
+
+func writeItemDefinition(x *xmlWriter, name string, item *ItemDefinition) {
+	attrs := []string{"id", item.ID, "name", item.Name}
+	if item.IsCollection {
+		attrs = append(attrs, "isCollection", "true")
+	}
+	if item.TypeRef == "" && len(item.ItemComponents) == 0 {
+		x.empty(name, attrs...)
+		return
+	}
+
+	x.start(name, attrs...)
+	if item.TypeRef != "" {
+		x.text("typeRef", item.TypeRef)
+	}
+	for i := range item.ItemComponents {
+		writeItemDefinition(x, "itemComponent", &item.ItemComponents[i])
+	}
+	x.end(name)
+}
+
+func writeVariable(x *xmlWriter, name string, v *Variable) {
+	if v != nil {
+		x.empty(name, "id", v.ID, "name", v.Name, "typeRef", v.TypeRef)
+	}
+}
+
+func writeDecision(x *xmlWriter, d *Decision) {
+	x.start("decision",
+		"id", d.ID,
+		"name", d.Name,
+		x.camunda+":versionTag", d.VersionTag,
+		x.camunda+":historyTimeToLive", d.HistoryTimeToLive,
+	)
+	writeVariable(x, "variable", d.Variable)
+	for _, req := range d.InformationRequirements {
+		x.start("informationRequirement", "id", req.ID)
+		if req.RequiredDecision != nil {
+			x.empty("requiredDecision", "href", req.RequiredDecision.Href)
+		}
+		if req.RequiredInput != nil {
+			x.empty("requiredInput", "href", req.RequiredInput.Href)
+		}
+		x.end("informationRequirement")
+	}
+	writeKnowledgeRequirements(x, d.KnowledgeRequirements)
+	if d.DecisionTable != nil {
+		writeDecisionTable(x, d.DecisionTable)
+	}
+	if d.LiteralExpression != nil {
+		writeLiteralExpression(x, "literalExpression", d.LiteralExpression)
+	}
+	x.end("decision")
+}
+
+func writeKnowledgeRequirements(x *xmlWriter, reqs []KnowledgeRequirement) {
+	for _, req := range reqs {
+		x.start("knowledgeRequirement", "id", req.ID)
+		if req.RequiredKnowledge != nil {
+			x.empty("requiredKnowledge", "href", req.RequiredKnowledge.Href)
+		}
+		x.end("knowledgeRequirement")
+	}
+}
+
+func writeBusinessKnowledgeModel(x *xmlWriter, bkm *BusinessKnowledgeModel) {
+	x.start("businessKnowledgeModel", "id", bkm.ID, "name", bkm.Name)
+	writeVariable(x, "variable", bkm.Variable)
+	if logic := bkm.EncapsulatedLogic; logic != nil {
+		x.start("encapsulatedLogic", "kind", logic.Kind)
+		for i := range logic.FormalParameters {
+			writeVariable(x, "formalParameter", &logic.FormalParameters[i])
+		}
+		if logic.DecisionTable != nil {
+			writeDecisionTable(x, logic.DecisionTable)
+		}
+		if logic.LiteralExpression != nil {
+			writeLiteralExpression(x, "literalExpression", logic.LiteralExpression)
+		}
+		x.end("encapsulatedLogic")
+	}
+	writeKnowledgeRequirements(x, bkm.KnowledgeRequirements)
+	x.end("businessKnowledgeModel")
+}
+
+func writeDecisionService(x *xmlWriter, svc *DecisionService) {
+	x.start("decisionService", "id", svc.ID, "name", svc.Name)
+	writeVariable(x, "variable", svc.Variable)
+	refs := []struct {
+		name string
+		refs []ElementReference
+	}{
+		{"outputDecision", svc.OutputDecisions},
+		{"encapsulatedDecision", svc.EncapsulatedDecisions},
+		{"inputDecision", svc.InputDecisions},
+		{"inputData", svc.InputData},
+	}
+	for _, group := range refs {
+		for _, ref := range group.refs {
+			x.empty(group.name, "href", ref.Href)
+		}
+	}
+	x.end("decisionService")
+}
+
+func writeDecisionTable(x *xmlWriter, dt *DecisionTable) {
+	hitPolicy := dt.HitPolicy
+	if dt.hitPolicyOmitted {
+		hitPolicy = ""
+	}
+	x.start("decisionTable",
+		"id", dt.ID,
+		"hitPolicy", hitPolicy,
+		"aggregation", dt.Aggregation,
+		"preferredOrientation", dt.PreferredOrientation,
+	)
+
+	for _, in := range dt.Inputs {
+		x.start("input", "id", in.ID, "label", in.Label, x.camunda+":inputVariable", in.InputVariable)
+		x.start("inputExpression", "id", in.InputExpression.ID, "typeRef", in.InputExpression.TypeRef)
+		x.text("text", in.InputExpression.Text)
+		x.end("inputExpression")
+		if in.InputValues != nil {
+			x.start("inputValues")
+			x.text("text", in.InputValues.Text)
+			x.end("inputValues")
+		}
+		x.end("input")
+	}
+
+	for _, out := range dt.Outputs {
+		attrs := []string{"id", out.ID, "label", out.Label, "name", out.Name, "typeRef", out.TypeRef}
+		if out.OutputValues == nil && out.DefaultOutputEntry == nil {
+			x.empty("output", attrs...)
+			continue
+		}
+		x.start("output", attrs...)
+		if out.OutputValues != nil {
+			x.start("outputValues")
+			x.text("text", out.OutputValues.Text)
+			x.end("outputValues")
+		}
+		if out.DefaultOutputEntry != nil {
+			writeLiteralExpression(x, "defaultOutputEntry", out.DefaultOutputEntry)
+		}
+		x.end("output")
+	}
+
+	for _, a := range dt.Annotations {
+		x.empty("annotation", "name", a.Name)
+	}
+
+	for _, rule := range dt.Rules {
+		x.start("rule", "id", rule.ID)
+		if rule.Description != "" {
+			x.text("description", rule.Description)
+		}
+		for _, entry := range rule.InputEntries {
+			x.start("inputEntry", "id", entry.ID)
+			x.text("text", entry.Text)
+			x.end("inputEntry")
+		}
+		for _, entry := range rule.OutputEntries {
+			x.start("outputEntry", "id", entry.ID)
+			x.text("text", entry.Text)
+			x.end("outputEntry")
+		}
+		for _, entry := range rule.AnnotationEntries {
+			x.start("annotationEntry")
+			x.text("text", entry.Text)
+			x.end("annotationEntry")
+		}
+		x.end("rule")
+	}
+
+	x.end("decisionTable")
+}
+
+func writeLiteralExpression(x *xmlWriter, name string, expr *LiteralExpression) {
+	x.start(name, "id", expr.ID, "typeRef", expr.TypeRef)
+	x.text("text", expr.Text)
+	x.end(name)
+}
+
+// writeDMNDI writes the diagram section verbatim. Its content uses the
+// prefixes declared on the root element, which Write preserves
+func writeDMNDI(x *xmlWriter, d *DMNDI, prefixes map[string]string) {
+	name := d.XMLName.Local
+	var attrs []string
+	if prefix := prefixFor(d.XMLName.Space, prefixes, d.Attrs); prefix != "" {
+		name = prefix + ":" + name
+	} else if d.XMLName.Space != "" {
+		attrs = append(attrs, "xmlns", d.XMLName.Space)
+	}
+
+	for _, a := range d.Attrs {
+		switch {
+		case a.Name.Space == "xmlns":
+			attrs = append(attrs, "xmlns:"+a.Name.Local, a.Value)
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			// the default namespace is declared above
+		case a.Name.Space == "":
+			attrs = append(attrs, a.Name.Local, a.Value)
+		default:
+			if prefix := prefixFor(a.Name.Space, prefixes, d.Attrs); prefix != "" {
+				attrs = append(attrs, prefix+":"+a.Name.Local, a.Value)
+			}
+		}
+	}
+
+	x.open(name, attrs...)
+	x.buf.WriteString(">")
+	x.buf.WriteString(d.InnerXML)
+	x.buf.WriteString("</" + name + ">\n")
+}
+
+// usesCamundaExtensions reports whether the model sets any Camunda attribute
+func usesCamundaExtensions(defs *Definitions) bool {
+	for _, d := range defs.Decisions {
+		if d.VersionTag != "" || d.HistoryTimeToLive != "" {
+			return true
+		}
+	}
+	found := false
+	forEachTable(defs, func(_ string, dt *DecisionTable) {
+		for _, in := range dt.Inputs {
+			found = found || in.InputVariable != ""
+		}
+	})
+	return found
+}
+
+// prefixFor returns the prefix bound to a namespace by the root element or
+// by the element's own declarations
+func prefixFor(namespace string, prefixes map[string]string, attrs []xml.Attr) string {
+	for _, a := range attrs {
+		if a.Name.Space == "xmlns" && a.Value == namespace {
+			return a.Name.Local
+		}
+	}
+	for prefix, ns := range prefixes {
+		if ns == namespace {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// xmlWriter writes indented XML. Attributes are given as name/value pairs;
+// empty values are omitted
+type xmlWriter struct {
+	buf     bytes.Buffer
+	indent  string
+	depth   int
+	camunda string // prefix of the Camunda namespace
+}
+
+func (x *xmlWriter) open(name string, attrs ...string) {
+	x.buf.WriteString(strings.Repeat(x.indent, x.depth))
+	x.buf.WriteString("<" + name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] == "" {
+			continue
+		}
+		x.buf.WriteString(" " + attrs[i] + `="`)
+		xml.EscapeText(&x.buf, []byte(attrs[i+1]))
+		x.buf.WriteString(`"`)
+	}
+}
+
+func (x *xmlWriter) start(name string, attrs ...string) {
+	x.open(name, attrs...)
+	x.buf.WriteString(">\n")
+	x.depth++
+}
+
+func (x *xmlWriter) end(name string) {
+	x.depth--
+	x.buf.WriteString(strings.Repeat(x.indent, x.depth))
+	x.buf.WriteString("</" + name + ">\n")
+}
+
+func (x *xmlWriter) empty(name string, attrs ...string) {
+	x.open(name, attrs...)
+	x.buf.WriteString("/>\n")
+}
+
+// text writes an element with character data. Line breaks are kept as they
+// are, so multi-line FEEL stays readable
+func (x *xmlWriter) text(name, value string) {
+	x.open(name)
+	x.buf.WriteString(">")
+	x.buf.WriteString(textEscaper.Replace(value))
+	x.buf.WriteString("</" + name + ">\n")
+}
+
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
@@ -0,0 +1,76 @@
+package dmn
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// clearPositions zeroes every Position field reachable from v, since
+// written documents are laid out differently from their source
+func clearPositions(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			clearPositions(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			clearPositions(v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(Position{}) {
+			if v.CanSet() {
+				v.Set(reflect.Zero(v.Type()))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				clearPositions(v.Field(i))
+			}
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../../testdata/dmn/*.dmn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test models found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			want, err := NewParser().ParseFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := NewWriter().WriteBytes(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewParser().ParseBytes(data)
+			if err != nil {
+				t.Fatalf("written model does not parse: %v\n%s", err, data)
+			}
+
+			clearPositions(reflect.ValueOf(want))
+			clearPositions(reflect.ValueOf(got))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("model changed by a round trip:\n got %+v\nwant %+v\n%s", got, want, data)
+			}
+
+			// Writing is stable
+			again, err := NewWriter().WriteBytes(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(data) {
+				t.Errorf("second write differs:\n%s\n---\n%s", again, data)
+			}
+		})
+	}
+}
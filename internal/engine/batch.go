@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchRequest evaluates one decision for several sets of variables
+type BatchRequest struct {
+	DecisionKey string
+	Version     *int
+	TenantID    string
+	Items       []map[string]interface{} // variables of each evaluation
+	Workers     int                      // items evaluated concurrently, at least 1
+}
+
+// BatchItem is the outcome of one item of a batch
+type BatchItem struct {
+	Index  int // position of the item in the request
+	Result *EvaluateResult
+	Err    error
+}
+
+// BatchResult holds the outcome of every item in request order
+type BatchResult struct {
+	DecisionKey string
+	Version     int
+	Items       []BatchItem
+	Failed      int
+	DurationNs  int64
+}
+
+// EvaluateBatch resolves the decision once and evaluates it for every item
+// on a bounded pool of workers. An item that fails does not fail the batch;
+// an error is returned only if the decision cannot be resolved. Items not
+// started before ctx is done fail with the context's error
+func (e *Engine) EvaluateBatch(ctx context.Context, req *BatchRequest) (*BatchResult, error) {
+	start := time.Now()
+
+	model, err := e.definition(ctx, decisionRef(req.TenantID, req.DecisionKey, req.Version))
+	if err != nil {
+		return nil, fmt.Errorf("decision %s not found: %w", req.DecisionKey, err)
+	}
+
+	workers := req.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(req.Items) {
+		workers = len(req.Items)
+	}
+
+	items := make([]BatchItem, len(req.Items))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				items[i] = e.evaluateBatchItem(ctx, model, req, i)
+			}
+		}()
+	}
+
+feed:
+	for i := range req.Items {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			for j := i; j < len(req.Items); j++ {
+				items[j] = BatchItem{Index: j, Err: ctx.Err()}
+			}
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	result := &BatchResult{
+		DecisionKey: req.DecisionKey,
+		Version:     model.def.Version,
+		Items:       items,
+	}
+	for _, item := range items {
+		if item.Err != nil {
+			result.Failed++
+		}
+	}
+	result.DurationNs = time.Since(start).Nanoseconds()
+	return result, nil
+}
+
+// evaluateBatchItem evaluates the item at index i of a batch
+func (e *Engine) evaluateBatchItem(ctx context.Context, model *compiledModel, req *BatchRequest, i int) BatchItem {
+	variables := req.Items[i]
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+
+	res, err := e.evaluateModel(ctx, model, &EvaluateRequest{
+		DecisionKey: req.DecisionKey,
+		Version:     req.Version,
+		Variables:   variables,
+		TenantID:    req.TenantID,
+	}, time.Now())
+	return BatchItem{Index: i, Result: res, Err: err}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import "math/bits"
+
+// bitset is a set of rule indexes
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+// fill adds the indexes 0..n-1
+func (b bitset) fill(n int) {
+	for i := range b {
+		b[i] = ^uint64(0)
+	}
+	if rem := n % 64; rem != 0 {
+		b[len(b)-1] = (uint64(1) << rem) - 1
+	}
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (uint(i) % 64)
+}
+
+func (b bitset) has(i int) bool {
+	return b[i/64]&(1<<(uint(i)%64)) != 0
+}
+
+// and keeps the indexes also in o
+func (b bitset) and(o bitset) {
+	for i := range b {
+		b[i] &= o[i]
+	}
+}
+
+// empty reports whether the set has no indexes
+func (b bitset) empty() bool {
+	for _, w := range b {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// next returns the smallest index >= i in the set, or -1
+func (b bitset) next(i int) int {
+	w := i / 64
+	if w >= len(b) {
+		return -1
+	}
+	word := b[w] >> (uint(i) % 64)
+	if word != 0 {
+		return i + bits.TrailingZeros64(word)
+	}
+	for w++; w < len(b); w++ {
+		if b[w] != 0 {
+			return w*64 + bits.TrailingZeros64(b[w])
+		}
+	}
+	return -1
+}
@@ -0,0 +1,227 @@
+package engine
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of compiled definitions kept by NewEngine
+const DefaultCacheSize = 256
+
+// CacheStats describes the compiled-definition cache
+type CacheStats struct {
+	Size          int    `json:"size"`          // compiled definitions in the cache
+	Capacity      int    `json:"capacity"`      // maximum number of compiled definitions
+	Hits          uint64 `json:"hits"`          // lookups served without reading the repository
+	Misses        uint64 `json:"misses"`        // lookups that read the repository
+	Evictions     uint64 `json:"evictions"`     // compiled definitions dropped to stay within capacity
+	Invalidations uint64 `json:"invalidations"` // resolutions and definitions dropped on deploy or delete
+}
+
+// cacheKey identifies a deployed definition version
+type cacheKey struct {
+	tenantID string
+	key      string
+	version  int
+}
+
+// Kinds of references resolved to a definition
+const (
+	refDecision  = iota // decision or decision service ID
+	refNamespace        // model namespace, used for imports
+)
+
+// resolveKey is a reference to a definition as it appears in a request: a
+// decision ID or a namespace, with a version or zero for the latest
+type resolveKey struct {
+	tenantID string
+	kind     int
+	ref      string
+	version  int
+}
+
+// resolution is the definition version a reference resolved to
+type resolution struct {
+	key     cacheKey
+	expires time.Time // zero if the resolution does not expire
+}
+
+// definitionCache is a bounded LRU cache of compiled definitions. Lookups
+// by decision ID or namespace are remembered as resolutions to a definition
+// version; resolutions of the latest version are dropped when a definition
+// of the tenant is deployed or deleted
+type definitionCache struct {
+	mu          sync.Mutex
+	capacity    int
+	latestTTL   time.Duration
+	entries     map[cacheKey]*list.Element // values are *compiledModel
+	lru         *list.List                 // most recently used at the front
+	resolutions map[resolveKey]resolution
+	generation  uint64 // incremented on every invalidation
+
+	hits, misses, evictions, invalidations uint64
+}
+
+func newDefinitionCache(capacity int, latestTTL time.Duration) *definitionCache {
+	return &definitionCache{
+		capacity:    capacity,
+		latestTTL:   latestTTL,
+		entries:     make(map[cacheKey]*list.Element),
+		lru:         list.New(),
+		resolutions: make(map[resolveKey]resolution),
+	}
+}
+
+// lookup returns the compiled definition a reference resolves to. A miss is
+// counted when the caller has to read the repository; the returned
+// generation is passed on to add
+func (c *definitionCache) lookup(rk resolveKey) (*compiledModel, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res, ok := c.resolutions[rk]
+	if ok && !res.expires.IsZero() && time.Now().After(res.expires) {
+		delete(c.resolutions, rk)
+		ok = false
+	}
+	if ok {
+		if el, found := c.entries[res.key]; found {
+			c.lru.MoveToFront(el)
+			c.hits++
+			return el.Value.(*compiledModel), c.generation, true
+		}
+	}
+	c.misses++
+	return nil, c.generation, false
+}
+
+// get returns a compiled definition version without counting a lookup
+func (c *definitionCache) get(key cacheKey) (*compiledModel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*compiledModel), true
+}
+
+// add stores a compiled definition and the resolution of the reference that
+// led to it, evicting the least recently used definitions over capacity.
+// Nothing is stored if the cache was invalidated since the lookup, as the
+// repository may have been read before the change
+func (c *definitionCache) add(rk resolveKey, generation uint64, m *compiledModel) {
+	if c.capacity <= 0 {
+		return
+	}
+	key := cacheKey{tenantID: m.def.TenantID, key: m.def.Key, version: m.def.Version}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	res := resolution{key: key}
+	if rk.version == 0 && c.latestTTL > 0 {
+		res.expires = time.Now().Add(c.latestTTL)
+	}
+	c.resolutions[rk] = res
+	c.store(m)
+}
+
+// store adds or replaces a compiled definition, evicting the least recently
+// used definitions over capacity. The caller holds c.mu
+func (c *definitionCache) store(m *compiledModel) {
+	key := cacheKey{tenantID: m.def.TenantID, key: m.def.Key, version: m.def.Version}
+	if el, ok := c.entries[key]; ok {
+		el.Value = m
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(m)
+
+	for c.lru.Len() > c.capacity {
+		el := c.lru.Back()
+		old := el.Value.(*compiledModel)
+		oldKey := cacheKey{tenantID: old.def.TenantID, key: old.def.Key, version: old.def.Version}
+		c.lru.Remove(el)
+		delete(c.entries, oldKey)
+		c.evictions++
+
+		for rk, res := range c.resolutions {
+			if res.key == oldKey {
+				delete(c.resolutions, rk)
+			}
+		}
+	}
+}
+
+// put stores a compiled definition that no reference resolves to yet;
+// lookups find it once a reference is resolved to its version
+func (c *definitionCache) put(m *compiledModel) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(m)
+}
+
+// deployed drops the tenant's resolutions of latest versions, as a new
+// version may now be the latest for any decision ID or namespace
+func (c *definitionCache) deployed(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for rk := range c.resolutions {
+		if rk.tenantID == tenantID && rk.version == 0 {
+			delete(c.resolutions, rk)
+			c.invalidations++
+		}
+	}
+}
+
+// deleted drops all versions of a definition and all of the tenant's
+// resolutions. Versions are numbered again from 1 after a delete, so even
+// resolutions of explicit versions may change
+func (c *definitionCache) deleted(tenantID, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for rk := range c.resolutions {
+		if rk.tenantID == tenantID {
+			delete(c.resolutions, rk)
+			c.invalidations++
+		}
+	}
+	for ck, el := range c.entries {
+		if ck.tenantID == tenantID && ck.key == key {
+			c.lru.Remove(el)
+			delete(c.entries, ck)
+			c.invalidations++
+		}
+	}
+}
+
+// stats returns a snapshot of the cache counters
+func (c *definitionCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Size:          c.lru.Len(),
+		Capacity:      c.capacity,
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Evictions:     c.evictions,
+		Invalidations: c.invalidations,
+	}
+}
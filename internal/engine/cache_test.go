@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// literalModel is a model whose decision id evaluates to value
+func literalModel(id, value string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs_` + id + `" name="` + id + `" namespace="http://example.org/` + id + `">
+  <decision id="` + id + `" name="` + id + `">
+    <variable name="` + id + `"/>
+    <literalExpression><text>` + value + `</text></literalExpression>
+  </decision>
+</definitions>`
+}
+
+// evaluateLiteral evaluates a decision of a literalModel and returns its value
+func evaluateLiteral(t *testing.T, e *Engine, id string, version *int) interface{} {
+	t.Helper()
+	res, err := e.Evaluate(context.Background(), &EvaluateRequest{DecisionKey: id, Version: version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.Outputs[0][id]
+}
+
+func TestCache(t *testing.T) {
+	type step struct {
+		op      string // "eval", "deploy", "delete" or "sleep"
+		id      string
+		value   string // deployed value, or expected value of eval
+		version int    // version to evaluate, 0 for the latest
+	}
+	tests := []struct {
+		name  string
+		opts  Options
+		steps []step
+		want  CacheStats
+	}{
+		{
+			name:  "hit after miss",
+			opts:  Options{CacheSize: 2},
+			steps: []step{{op: "eval", id: "a", value: "1"}, {op: "eval", id: "a", value: "1"}},
+			want:  CacheStats{Size: 1, Capacity: 2, Hits: 1, Misses: 1},
+		},
+		{
+			name: "least recently used is evicted",
+			opts: Options{CacheSize: 2},
+			steps: []step{
+				{op: "eval", id: "a", value: "1"},
+				{op: "eval", id: "b", value: "2"},
+				{op: "eval", id: "a", value: "1"}, // b is now least recently used
+				{op: "eval", id: "c", value: "3"},
+				{op: "eval", id: "a", value: "1"},
+				{op: "eval", id: "b", value: "2"},
+			},
+			want: CacheStats{Size: 2, Capacity: 2, Hits: 2, Misses: 4, Evictions: 2},
+		},
+		{
+			name: "deploy invalidates the latest version",
+			opts: Options{CacheSize: 4},
+			steps: []step{
+				{op: "eval", id: "a", value: "1"},
+				{op: "eval", id: "a", value: "1", version: 1},
+				{op: "deploy", id: "a", value: "10"},
+				{op: "eval", id: "a", value: "10"},
+				{op: "eval", id: "a", value: "1", version: 1}, // explicit versions stay cached
+			},
+			want: CacheStats{Size: 2, Capacity: 4, Hits: 1, Misses: 3, Invalidations: 1},
+		},
+		{
+			name: "delete drops every version",
+			opts: Options{CacheSize: 4},
+			steps: []step{
+				{op: "eval", id: "a", value: "1"},
+				{op: "eval", id: "b", value: "2"},
+				{op: "delete", id: "a"},
+				{op: "deploy", id: "a", value: "5"},
+				{op: "eval", id: "a", value: "5", version: 1},
+				{op: "eval", id: "b", value: "2"},
+			},
+			// Both resolutions and the compiled version of a are dropped
+			want: CacheStats{Size: 2, Capacity: 4, Misses: 4, Invalidations: 3},
+		},
+		{
+			name: "latest version expires",
+			opts: Options{CacheSize: 2, LatestTTL: time.Millisecond},
+			steps: []step{
+				{op: "eval", id: "a", value: "1"},
+				{op: "sleep"},
+				{op: "eval", id: "a", value: "1"},
+			},
+			want: CacheStats{Size: 1, Capacity: 2, Misses: 2},
+		},
+		{
+			name: "disabled",
+			opts: Options{},
+			steps: []step{
+				{op: "eval", id: "a", value: "1"},
+				{op: "eval", id: "a", value: "1"},
+			},
+			want: CacheStats{Misses: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := storage.NewMemoryRepository()
+			e := NewEngineWithOptions(repo, tt.opts)
+			deployed := make(map[string]bool)
+
+			for i, s := range tt.steps {
+				switch s.op {
+				case "eval":
+					if !deployed[s.id] {
+						deploy(t, repo, "", literalModel(s.id, s.value))
+						deployed[s.id] = true
+					}
+					var version *int
+					if s.version > 0 {
+						version = &s.version
+					}
+					want, _ := strconv.ParseFloat(s.value, 64)
+					if got := evaluateLiteral(t, e, s.id, version); got != want {
+						t.Fatalf("step %d: %s = %v, want %s", i, s.id, got, s.value)
+					}
+				case "deploy":
+					deploy(t, repo, "", literalModel(s.id, s.value))
+					e.DefinitionDeployed("")
+				case "delete":
+					if err := repo.Delete(ctx, s.id, ""); err != nil {
+						t.Fatal(err)
+					}
+					e.DefinitionDeleted("", s.id)
+				case "sleep":
+					time.Sleep(5 * time.Millisecond)
+				}
+			}
+
+			if got := e.CacheStats(); got != tt.want {
+				t.Errorf("stats = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheCompile(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	e := NewEngine(repo)
+	def := deploy(t, repo, "", literalModel("a", "1"))
+
+	e.Compile(def)
+	warmed, ok := e.cache.get(cacheKey{key: "a", version: 1})
+	if !ok {
+		t.Fatal("Compile did not cache the definition")
+	}
+
+	// The first evaluation resolves the decision to the compiled version
+	model, err := e.definition(context.Background(), decisionRef("", "a", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model != warmed {
+		t.Error("evaluation compiled the definition again")
+	}
+	if got := e.CacheStats(); got.Misses != 1 || got.Size != 1 {
+		t.Errorf("stats = %+v", got)
+	}
+}
+
+func TestCacheStaleAdd(t *testing.T) {
+	// A definition read before a deploy must not be cached as the latest
+	c := newDefinitionCache(4, 0)
+	rk := resolveKey{kind: refDecision, ref: "a"}
+	_, generation, _ := c.lookup(rk)
+	c.deployed("")
+	c.add(rk, generation, &compiledModel{def: &storage.Definition{Key: "a", Version: 1}})
+
+	if _, _, ok := c.lookup(rk); ok {
+		t.Error("stale definition was cached")
+	}
+	if got := c.stats(); got.Size != 0 {
+		t.Errorf("size = %d, want 0", got.Size)
+	}
+}
+
+func TestCacheTenants(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	e := NewEngine(repo)
+	deploy(t, repo, "acme", literalModel("a", "1"))
+	deploy(t, repo, "beta", literalModel("a", "2"))
+
+	eval := func(tenantID string) interface{} {
+		res, err := e.Evaluate(context.Background(), &EvaluateRequest{DecisionKey: "a", TenantID: tenantID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Outputs[0]["a"]
+	}
+	if eval("acme") != 1.0 || eval("beta") != 2.0 {
+		t.Fatal("tenants share a cached definition")
+	}
+
+	// A deploy of one tenant keeps the other's resolutions
+	e.DefinitionDeployed("acme")
+	eval("acme")
+	eval("beta")
+	if got := e.CacheStats(); got.Hits != 1 || got.Misses != 3 {
+		t.Errorf("stats = %+v, want 1 hit and 3 misses", got)
+	}
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// missing stands for a variable the request does not set
+var missing = &struct{}{}
+
+// TestUnaryTestCompatibility pins how input entries match compared to the
+// string matcher FEEL replaced. Cases marked as changed are the intended
+// differences listed in the README
+func TestUnaryTestCompatibility(t *testing.T) {
+	tests := []struct {
+		entry string
+		value interface{}
+		want  bool
+	}{
+		{"-", 5, true},
+		{"", "A", true},
+		{`"A"`, "A", true},
+		{`"A"`, "B", false},
+		{`"A","B"`, "B", true},
+		{`"A","B"`, "C", false},
+		{"5", 5, true},
+		{"5", 5.0, true},
+		{"5", 6, false},
+		{"&lt; 18", 17, true},
+		{"&lt; 18", 18, false},
+		{"&lt;= 18", 18, true},
+		{"&gt; 65", 66, true},
+		{"&gt;= 65", 65, true},
+		{"= 5", 5, true},
+		{"[18..65]", 18, true},
+		{"[18..65]", 65, true},
+		{"[18..65]", 66, false},
+		{"]18..65[", 18, false},
+		{"(18..65)", 30, true},
+		{"[18..65)", 65, false},
+
+		// Changed: a missing variable is null, which "-" accepts
+		{"-", missing, true},
+		// Changed: strings and numbers are never equal
+		{`"5"`, 5, false},
+		{"5", "5", false},
+		// Changed: unquoted text is a name, not a string
+		{"A", "A", false},
+		{"A, B", "B", false},
+		// Changed: != is a FEEL comparison
+		{"!= 5", 6, true},
+		// Changed: comparing a string with a range is false rather than an error
+		{"[18..65]", "30", false},
+	}
+
+	for _, tt := range tests {
+		repo := storage.NewMemoryRepository()
+		deploy(t, repo, "", tableModel("FIRST", [3]string{tt.entry, "-", "1"}))
+		vars := map[string]interface{}{"category": "A"}
+		if tt.value != missing {
+			vars["age"] = tt.value
+		}
+
+		res, err := NewEngine(repo).Evaluate(context.Background(), &EvaluateRequest{DecisionKey: "d", Variables: vars})
+		if err != nil {
+			t.Errorf("%s against %v: %v", tt.entry, tt.value, err)
+			continue
+		}
+		if got := len(res.MatchedRules) == 1; got != tt.want {
+			t.Errorf("%s against %v matched %v, want %v", tt.entry, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSimpleDecision(t *testing.T) {
+	xml, err := os.ReadFile("../../testdata/dmn/simple_decision.dmn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", string(xml))
+	e := NewEngine(repo)
+
+	tests := []struct {
+		vars    map[string]interface{}
+		want    []map[string]interface{}
+		rule    string
+		wantErr string
+	}{
+		{map[string]interface{}{"age": 10}, []map[string]interface{}{{"result": "Not Eligible - Minor"}}, "rule1", ""},
+		{map[string]interface{}{"age": 18}, []map[string]interface{}{{"result": "Eligible"}}, "rule2", ""},
+		{map[string]interface{}{"age": 65.0}, []map[string]interface{}{{"result": "Eligible"}}, "rule2", ""},
+		{map[string]interface{}{"age": 66}, []map[string]interface{}{{"result": "Requires Review"}}, "rule3", ""},
+		// No rule matches a missing age, as before FEEL
+		{map[string]interface{}{}, nil, "", ""},
+		// Changed: a null age matches no rule instead of failing the range
+		{map[string]interface{}{"age": nil}, nil, "", ""},
+		// Values that are not numbers fail against the input's typeRef
+		{map[string]interface{}{"age": "30"}, nil, "", "expected number, got string"},
+		{map[string]interface{}{"age": true}, nil, "", "expected number, got boolean"},
+	}
+
+	for _, tt := range tests {
+		res, err := e.Evaluate(context.Background(), &EvaluateRequest{DecisionKey: "eligibility", Variables: tt.vars})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%v: error = %v, want %q", tt.vars, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.vars, err)
+			continue
+		}
+		if len(res.Outputs) != len(tt.want) || (len(tt.want) > 0 && !reflect.DeepEqual(res.Outputs, tt.want)) {
+			t.Errorf("%v: outputs = %v, want %v", tt.vars, res.Outputs, tt.want)
+		}
+		if tt.rule != "" && (len(res.MatchedRules) != 1 || res.MatchedRules[0].RuleID != tt.rule) {
+			t.Errorf("%v: matched rules = %+v, want %s", tt.vars, res.MatchedRules, tt.rule)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/konstantin/dmn-engine-go/internal/dmn"
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// compiledModel is a deployed definition with its FEEL text parsed ahead of
+// evaluation. It is shared between concurrent evaluations and must not be
+// modified after compile returns
+type compiledModel struct {
+	def      *storage.Definition
+	tables   map[*dmn.DecisionTable]*compiledTable
+	literals map[*dmn.LiteralExpression]expression
+}
+
+// compiledTable holds the parsed cells of a decision table
+type compiledTable struct {
+	inputs   []expression   // input expressions
+	types    []string       // declared typeRef of each input expression
+	entries  [][]unaryTests // input entries by rule, then column
+	outputs  [][]expression // output entries by rule, then column
+	defaults []expression   // default output entries by column
+	index    *ruleIndex     // nil for tables that are scanned rule by rule
+	policy   *HitPolicyTable
+}
+
+// expression is a parsed FEEL expression. Syntax errors are kept and
+// reported when the expression is evaluated, as they were before parsing
+// moved ahead of evaluation
+type expression struct {
+	node feel.Node // nil for empty literals, which evaluate to null
+	err  error
+}
+
+// unaryTests is a parsed input entry bound to its column's input variable
+type unaryTests struct {
+	tests *feel.UnaryTests
+	err   error
+	text  string // source text, reported in traces
+}
+
+// compile parses the FEEL text of all decision tables and literal
+// expressions of a definition, including those of its BKMs
+func compile(def *storage.Definition) *compiledModel {
+	m := &compiledModel{
+		def:      def,
+		tables:   make(map[*dmn.DecisionTable]*compiledTable),
+		literals: make(map[*dmn.LiteralExpression]expression),
+	}
+
+	defs := def.ParsedModel
+	for i := range defs.Decisions {
+		m.addTable(defs.Decisions[i].DecisionTable)
+		m.addLiteral(defs.Decisions[i].LiteralExpression)
+	}
+	for i := range defs.BusinessKnowledgeModels {
+		if logic := defs.BusinessKnowledgeModels[i].EncapsulatedLogic; logic != nil {
+			m.addTable(logic.DecisionTable)
+			m.addLiteral(logic.LiteralExpression)
+		}
+	}
+	return m
+}
+
+func (m *compiledModel) addTable(table *dmn.DecisionTable) {
+	if table != nil {
+		m.tables[table] = compileTable(table)
+	}
+}
+
+func (m *compiledModel) addLiteral(literal *dmn.LiteralExpression) {
+	if literal != nil {
+		m.literals[literal] = parseLiteral(literal.Text)
+	}
+}
+
+// table returns the compiled form of a decision table of the model
+func (m *compiledModel) table(table *dmn.DecisionTable) *compiledTable {
+	if ct, ok := m.tables[table]; ok {
+		return ct
+	}
+	return compileTable(table)
+}
+
+// literal returns the compiled form of a literal expression of the model
+func (m *compiledModel) literal(literal *dmn.LiteralExpression) expression {
+	if x, ok := m.literals[literal]; ok {
+		return x
+	}
+	return parseLiteral(literal.Text)
+}
+
+func compileTable(table *dmn.DecisionTable) *compiledTable {
+	ct := &compiledTable{
+		inputs:   make([]expression, len(table.Inputs)),
+		types:    make([]string, len(table.Inputs)),
+		entries:  make([][]unaryTests, len(table.Rules)),
+		outputs:  make([][]expression, len(table.Rules)),
+		defaults: make([]expression, len(table.Outputs)),
+	}
+
+	for i := range table.Inputs {
+		ct.inputs[i] = parseExpression(table.Inputs[i].InputExpression.Text)
+		ct.types[i] = table.Inputs[i].InputExpression.TypeRef
+	}
+	for i := range table.Outputs {
+		if entry := table.Outputs[i].DefaultOutputEntry; entry != nil {
+			ct.defaults[i] = parseLiteral(entry.Text)
+		}
+	}
+
+	for r := range table.Rules {
+		rule := &table.Rules[r]
+
+		ct.entries[r] = make([]unaryTests, len(rule.InputEntries))
+		for i, entry := range rule.InputEntries {
+			var inputVariable string
+			if i < len(table.Inputs) {
+				inputVariable = table.Inputs[i].InputVariableName()
+			}
+			ct.entries[r][i] = parseUnaryTests(entry.Text, inputVariable)
+		}
+
+		ct.outputs[r] = make([]expression, len(rule.OutputEntries))
+		for i, entry := range rule.OutputEntries {
+			ct.outputs[r][i] = parseLiteral(entry.Text)
+		}
+	}
+
+	ct.index = buildRuleIndex(ct)
+	ct.policy = newHitPolicyTable(table)
+	return ct
+}
+
+// newHitPolicyTable describes a decision table to its hit policy
+func newHitPolicyTable(table *dmn.DecisionTable) *HitPolicyTable {
+	pt := &HitPolicyTable{
+		Table:       table,
+		HitPolicy:   table.HitPolicy,
+		Aggregation: table.Aggregation,
+		Outputs:     make([]OutputColumn, len(table.Outputs)),
+	}
+	if pt.HitPolicy == "" {
+		pt.HitPolicy = dmn.HitPolicyUnique
+	}
+	for i, out := range table.Outputs {
+		pt.Outputs[i] = OutputColumn{Index: i, Name: outputName(&out), Label: out.Label, TypeRef: out.TypeRef}
+		if out.OutputValues != nil {
+			pt.Outputs[i].Values = literalValues(out.OutputValues.Text)
+		}
+	}
+	return pt
+}
+
+// literalValues returns the values of a list of literals such as
+// "HIGH","MEDIUM","LOW", or nil if the text is anything else
+func literalValues(text string) []interface{} {
+	tests, err := feel.ParseUnaryTests(text)
+	if err != nil || tests.Any || tests.Negated {
+		return nil
+	}
+	values := make([]interface{}, 0, len(tests.Tests))
+	for _, test := range tests.Tests {
+		if test.Kind != feel.TestExpr {
+			return nil
+		}
+		v, ok := indexLiteral(test.Expr)
+		if !ok {
+			return nil
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func parseExpression(text string) expression {
+	node, err := feel.ParseExpression(text)
+	return expression{node: node, err: err}
+}
+
+// parseLiteral parses an output or literal expression; empty text is null
+func parseLiteral(text string) expression {
+	if strings.TrimSpace(text) == "" {
+		return expression{}
+	}
+	return parseExpression(text)
+}
+
+func parseUnaryTests(text, inputVariable string) unaryTests {
+	tests, err := feel.ParseUnaryTests(text)
+	if err != nil {
+		return unaryTests{err: err, text: strings.TrimSpace(text)}
+	}
+	tests.InputVariable = inputVariable
+	return unaryTests{tests: tests, text: strings.TrimSpace(text)}
+}
+
+// eval evaluates the expression in the scope
+func (x expression) eval(scope *feel.Scope) (interface{}, error) {
+	if x.err != nil {
+		return nil, x.err
+	}
+	if x.node == nil {
+		return nil, nil
+	}
+	return feel.Eval(x.node, scope)
+}
+
+// match tests the value against the input entry
+func (u unaryTests) match(input interface{}, scope *feel.Scope) (bool, error) {
+	if u.err != nil {
+		return false, u.err
+	}
+	return u.tests.Match(input, scope)
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// inputVariableModel is a decision "d" with a table over amount whose input
+// is named by the attribute, e.g. camunda:inputVariable="amt"
+func inputVariableModel(attr, entry string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" xmlns:camunda="http://camunda.org/schema/1.0/dmn" id="defs" name="Input Variable" namespace="http://example.org/input">
+  <inputData id="in_amount" name="amount"><variable name="amount"/></inputData>
+  <decision id="d" name="D">
+    <variable name="D"/>
+    <informationRequirement><requiredInput href="#in_amount"/></informationRequirement>
+    <decisionTable hitPolicy="FIRST">
+      <input ` + attr + `><inputExpression typeRef="number"><text>amount</text></inputExpression></input>
+      <output name="r"/>
+      <rule id="r0"><inputEntry><text>` + entry + `</text></inputEntry><outputEntry><text>"large"</text></outputEntry></rule>
+      <rule id="r1"><inputEntry><text>-</text></inputEntry><outputEntry><text>"small"</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+}
+
+func TestInputVariable(t *testing.T) {
+	tests := []struct {
+		name   string
+		attr   string
+		entry  string
+		amount interface{}
+		want   string
+	}{
+		{"named", `camunda:inputVariable="amt"`, "amt &gt; 1000", 1500, "large"},
+		{"named no match", `camunda:inputVariable="amt"`, "amt &gt; 1000", 500, "small"},
+		{"named in a function", `camunda:inputVariable="amt"`, "odd(amt)", 7, "large"},
+		{"named with ?", `camunda:inputVariable="amt"`, "? &gt; 1000 and amt &lt; 2000", 2500, "small"},
+		{"default", "", "cellInput &gt; 1000", 1500, "large"},
+		{"default no match", "", "cellInput &gt; 1000", 500, "small"},
+		{"default replaced", `camunda:inputVariable="amt"`, "cellInput &gt; 1000", 1500, "small"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storage.NewMemoryRepository()
+			deploy(t, repo, "", inputVariableModel(tt.attr, tt.entry))
+
+			res, err := NewEngine(repo).Evaluate(context.Background(), &EvaluateRequest{
+				DecisionKey: "d",
+				Variables:   map[string]interface{}{"amount": tt.amount},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Outputs) != 1 || res.Outputs[0]["r"] != tt.want {
+				t.Errorf("outputs = %v, want r = %s", res.Outputs, tt.want)
+			}
+		})
+	}
+}
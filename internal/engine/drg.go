@@ -0,0 +1,391 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/konstantin/dmn-engine-go/internal/dmn"
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+)
+
+// decisionResult holds the outcome of evaluating a single decision
+type decisionResult struct {
+	decision     *dmn.Decision
+	outputs      []map[string]interface{}
+	matchedRules []RuleMatch
+	defaulted    bool // no rule matched and the default output entries were used
+	multiHit     bool // the outputs of several rules, returned as a list
+}
+
+// Value returns the decision result as seen by dependent decisions:
+// a single value for one-column single-hit tables, a context for several
+// columns and a list for multi-hit tables
+func (r *decisionResult) Value() interface{} {
+	values := make([]interface{}, len(r.outputs))
+	for i, out := range r.outputs {
+		if len(out) == 1 {
+			for _, v := range out {
+				values[i] = v
+			}
+		} else {
+			values[i] = out
+		}
+	}
+
+	if r.multiHit {
+		return values
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// decisionVariableName returns the name under which a decision's result is
+// visible to the decisions that require it
+func decisionVariableName(d *dmn.Decision) string {
+	if d.Variable != nil && d.Variable.Name != "" {
+		return d.Variable.Name
+	}
+	return d.Name
+}
+
+// bkmVariableName returns the name under which a BKM is invoked from FEEL
+func bkmVariableName(bkm *dmn.BusinessKnowledgeModel) string {
+	if bkm.Variable != nil && bkm.Variable.Name != "" {
+		return bkm.Variable.Name
+	}
+	return bkm.Name
+}
+
+// maxCallDepth bounds nested BKM invocations to stop runaway recursion
+const maxCallDepth = 64
+
+// drgEvaluation evaluates decisions of one definitions model, resolving
+// required decisions first and memoizing their results
+type drgEvaluation struct {
+	engine    *Engine
+	model     *compiledModel
+	defs      *dmn.Definitions
+	tenantID  string
+	variables map[string]interface{}
+	results   map[string]*decisionResult
+	active    map[string]bool // decisions on the current path, guards against cycles
+	boundary  map[string]bool // decisions evaluated inside a decision service; nil means no boundary
+
+	scope     *feel.Scope               // the model's BKMs and imports on top of the input variables
+	imports   map[string]*drgEvaluation // evaluations of imported models by namespace, shared across the graph
+	callDepth *int                      // current BKM nesting depth, shared across the graph
+
+	namespace string // namespace of an imported model, empty for the evaluated one
+	trace     *Trace // nil unless the evaluation is explained, shared across the graph
+}
+
+func newDRGEvaluation(e *Engine, model *compiledModel, tenantID string, variables map[string]interface{}) *drgEvaluation {
+	return &drgEvaluation{
+		engine:    e,
+		model:     model,
+		defs:      model.def.ParsedModel,
+		tenantID:  tenantID,
+		variables: variables,
+		results:   make(map[string]*decisionResult),
+		active:    make(map[string]bool),
+		imports:   make(map[string]*drgEvaluation),
+		callDepth: new(int),
+	}
+}
+
+// evaluate evaluates a decision after all of its required decisions
+func (g *drgEvaluation) evaluate(ctx context.Context, decision *dmn.Decision) (*decisionResult, error) {
+	if res, ok := g.results[decision.ID]; ok {
+		return res, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if g.active[decision.ID] {
+		return nil, fmt.Errorf("cyclic dependency detected at decision %s", decision.ID)
+	}
+	g.active[decision.ID] = true
+	defer delete(g.active, decision.ID)
+
+	modelScope, err := g.modelScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := make(map[string]interface{}, len(decision.InformationRequirements))
+	for _, req := range decision.InformationRequirements {
+		if req.RequiredDecision == nil {
+			continue
+		}
+
+		namespace, depID := dmn.SplitHref(req.RequiredDecision.Href)
+		if namespace != "" && namespace != g.defs.Namespace {
+			if err := g.bindImportedDecision(ctx, deps, namespace, depID); err != nil {
+				return nil, fmt.Errorf("decision %s: %w", decision.ID, err)
+			}
+			continue
+		}
+
+		dep := g.defs.GetDecision(depID)
+		if dep == nil {
+			return nil, fmt.Errorf("decision %s requires unknown decision %s", decision.ID, depID)
+		}
+
+		// Decisions outside a service boundary are inputs supplied by the caller
+		if g.boundary != nil && !g.boundary[depID] {
+			continue
+		}
+
+		depResult, err := g.evaluate(ctx, dep)
+		if err != nil {
+			return nil, err
+		}
+		deps[decisionVariableName(dep)] = depResult.Value()
+	}
+
+	var dt *DecisionTrace
+	if g.trace != nil {
+		dt = g.trace.add(&DecisionTrace{ID: decision.ID, Name: decision.Name, Kind: TraceKindDecision, Namespace: g.namespace})
+	}
+
+	res, err := g.engine.evaluateDecision(ctx, g.model, decision, modelScope.With(deps), dt)
+	if dt != nil {
+		dt.record(res, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decision %s: %w", decision.ID, err)
+	}
+	g.results[decision.ID] = res
+	return res, nil
+}
+
+// bindImportedDecision evaluates a decision of an imported model and binds
+// its result under the import name, e.g. "lib.Risk Score"
+func (g *drgEvaluation) bindImportedDecision(ctx context.Context, deps map[string]interface{}, namespace, id string) error {
+	imp := g.defs.GetImport(namespace)
+	if imp == nil {
+		return fmt.Errorf("namespace %s is not imported", namespace)
+	}
+
+	imported, err := g.imported(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	dep := imported.defs.GetDecision(id)
+	if dep == nil {
+		return fmt.Errorf("decision %s not found in imported namespace %s", id, namespace)
+	}
+
+	res, err := imported.evaluate(ctx, dep)
+	if err != nil {
+		return fmt.Errorf("import %s: %w", imp.Name, err)
+	}
+
+	// Merge with the imported BKMs already visible under the import name
+	qualified := make(map[string]interface{})
+	if existing, ok := deps[imp.Name].(map[string]interface{}); ok {
+		qualified = existing
+	} else if base, ok := g.scope.Lookup(imp.Name); ok {
+		if ctxValue, ok := base.(map[string]interface{}); ok {
+			for k, v := range ctxValue {
+				qualified[k] = v
+			}
+		}
+	}
+	qualified[decisionVariableName(dep)] = res.Value()
+	deps[imp.Name] = qualified
+	return nil
+}
+
+// modelScope returns the scope shared by all decisions of the model: the
+// model's BKMs and imports on top of the input variables
+func (g *drgEvaluation) modelScope(ctx context.Context) (*feel.Scope, error) {
+	if g.scope != nil {
+		return g.scope, nil
+	}
+
+	model := make(map[string]interface{}, len(g.defs.BusinessKnowledgeModels)+len(g.defs.Imports))
+	for i := range g.defs.BusinessKnowledgeModels {
+		bkm := &g.defs.BusinessKnowledgeModels[i]
+		model[bkmVariableName(bkm)] = g.bkmFunction(ctx, bkm)
+	}
+
+	for _, imp := range g.defs.Imports {
+		if !imp.IsDMN() {
+			continue
+		}
+		imported, err := g.imported(ctx, imp.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		// Imported BKMs are invoked through the import name, e.g. lib.myBkm(x)
+		functions := make(map[string]interface{}, len(imported.defs.BusinessKnowledgeModels))
+		for i := range imported.defs.BusinessKnowledgeModels {
+			bkm := &imported.defs.BusinessKnowledgeModels[i]
+			functions[bkmVariableName(bkm)] = imported.bkmFunction(ctx, bkm)
+		}
+		model[imp.Name] = functions
+	}
+
+	// Registered functions sit below the input variables, so a variable of
+	// the same name shadows them as it does built-ins
+	functions := feel.NewScope(g.engine.functions.scope(g.tenantID))
+	g.scope = functions.With(g.variables).WithContext(ctx).With(model)
+	return g.scope, nil
+}
+
+// ImportError is returned when the model of an imported namespace cannot
+// be resolved. It wraps storage.ErrNotFound when no deployed model has the
+// namespace, which is not the same as the evaluated decision being missing
+type ImportError struct {
+	Name      string // import name in the importing model
+	Namespace string
+	Err       error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("import %s (%s): %v", e.Name, e.Namespace, e.Err)
+}
+
+func (e *ImportError) Unwrap() error { return e.Err }
+
+// imported returns the evaluation of the latest deployed model with the
+// given namespace. Input variables qualified with the import name, e.g.
+// {"lib": {"age": 30}}, are visible unqualified inside the imported model
+func (g *drgEvaluation) imported(ctx context.Context, namespace string) (*drgEvaluation, error) {
+	if ev, ok := g.imports[namespace]; ok {
+		return ev, nil
+	}
+
+	imp := g.defs.GetImport(namespace)
+	if imp == nil {
+		return nil, fmt.Errorf("namespace %s is not imported", namespace)
+	}
+
+	model, err := g.engine.definition(ctx, resolveKey{tenantID: g.tenantID, kind: refNamespace, ref: namespace})
+	if err != nil {
+		return nil, &ImportError{Name: imp.Name, Namespace: namespace, Err: err}
+	}
+
+	variables := make(map[string]interface{}, len(g.variables))
+	for k, v := range g.variables {
+		variables[k] = v
+	}
+	if qualified, ok := g.variables[imp.Name].(map[string]interface{}); ok {
+		for k, v := range qualified {
+			variables[k] = v
+		}
+	}
+
+	ev := newDRGEvaluation(g.engine, model, g.tenantID, variables)
+	ev.imports = g.imports
+	ev.callDepth = g.callDepth
+	ev.namespace = namespace
+	ev.trace = g.trace
+	g.imports[namespace] = ev
+	return ev, nil
+}
+
+// bkmFunction returns a BKM as a FEEL function. The body is evaluated in the
+// scope of the BKM's own model with the formal parameters bound to arguments
+func (g *drgEvaluation) bkmFunction(ctx context.Context, bkm *dmn.BusinessKnowledgeModel) *feel.Function {
+	fn := &feel.Function{Name: bkmVariableName(bkm)}
+	logic := bkm.EncapsulatedLogic
+	if logic == nil {
+		fn.Call = func([]interface{}) (interface{}, error) {
+			return nil, fmt.Errorf("business knowledge model %s has no encapsulated logic", bkm.ID)
+		}
+		return fn
+	}
+
+	for _, param := range logic.FormalParameters {
+		fn.Params = append(fn.Params, param.Name)
+	}
+
+	fn.Call = func(args []interface{}) (interface{}, error) {
+		if *g.callDepth >= maxCallDepth {
+			return nil, fmt.Errorf("maximum call depth %d exceeded", maxCallDepth)
+		}
+		*g.callDepth++
+		defer func() { *g.callDepth-- }()
+
+		modelScope, err := g.modelScope(ctx)
+		if err != nil {
+			return nil, err
+		}
+		params := make(map[string]interface{}, len(fn.Params))
+		for i, name := range fn.Params {
+			if i < len(args) {
+				params[name] = args[i]
+			} else {
+				params[name] = nil
+			}
+		}
+		scope := modelScope.With(params)
+
+		var dt *DecisionTrace
+		if g.trace != nil {
+			dt = g.trace.add(&DecisionTrace{ID: bkm.ID, Name: fn.Name, Kind: TraceKindBKM, Namespace: g.namespace})
+		}
+
+		switch {
+		case logic.LiteralExpression != nil:
+			value, err := g.model.literal(logic.LiteralExpression).eval(scope)
+			if dt != nil {
+				dt.Expression = strings.TrimSpace(logic.LiteralExpression.Text)
+				dt.recordValue(value, err)
+			}
+			return value, err
+		case logic.DecisionTable != nil:
+			res, err := g.engine.evaluateDecisionTable(ctx, logic.DecisionTable, g.model.table(logic.DecisionTable), scope, dt)
+			if dt != nil {
+				dt.record(res, err)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return res.Value(), nil
+		default:
+			return nil, fmt.Errorf("business knowledge model %s has no literal expression or decision table", bkm.ID)
+		}
+	}
+	return fn
+}
+
+// evaluateService evaluates the encapsulated sub-graph of a decision service
+// and returns a single context holding the results of its output decisions.
+// The result is reported as defaulted if any output decision was defaulted
+func (g *drgEvaluation) evaluateService(ctx context.Context, svc *dmn.DecisionService) ([]map[string]interface{}, []RuleMatch, bool, error) {
+	g.boundary = make(map[string]bool, len(svc.OutputDecisions)+len(svc.EncapsulatedDecisions))
+	for _, ref := range svc.OutputDecisions {
+		g.boundary[ref.ElementID()] = true
+	}
+	for _, ref := range svc.EncapsulatedDecisions {
+		g.boundary[ref.ElementID()] = true
+	}
+
+	result := make(map[string]interface{}, len(svc.OutputDecisions))
+	matchedRules := []RuleMatch{}
+	var defaulted bool
+
+	for _, ref := range svc.OutputDecisions {
+		decision := g.defs.GetDecision(ref.ElementID())
+		if decision == nil {
+			return nil, nil, false, fmt.Errorf("decision service %s references unknown decision %s", svc.ID, ref.ElementID())
+		}
+
+		res, err := g.evaluate(ctx, decision)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		result[decisionVariableName(decision)] = res.Value()
+		matchedRules = append(matchedRules, res.matchedRules...)
+		defaulted = defaulted || res.defaulted
+	}
+
+	return []map[string]interface{}{result}, matchedRules, defaulted, nil
+}
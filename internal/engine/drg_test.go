@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/dmn"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// deploy parses a model and stores it the way the server deploys it: keyed
+// by its first decision and addressable by every decision ID
+func deploy(t testing.TB, repo *storage.MemoryRepository, tenantID, xml string) *storage.Definition {
+	t.Helper()
+	defs, err := dmn.NewParser().ParseBytes([]byte(xml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def := &storage.Definition{
+		Key:         defs.Decisions[0].ID,
+		Name:        defs.Name,
+		Source:      xml,
+		ParsedModel: defs,
+		Namespace:   defs.Namespace,
+		TenantID:    tenantID,
+	}
+	for _, d := range defs.Decisions {
+		def.DecisionIDs = append(def.DecisionIDs, d.ID)
+	}
+	for _, svc := range defs.DecisionServices {
+		def.DecisionIDs = append(def.DecisionIDs, svc.ID)
+	}
+	if err := repo.Deploy(context.Background(), def); err != nil {
+		t.Fatal(err)
+	}
+	return def
+}
+
+const libModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="lib" name="Lib" namespace="http://example.org/lib">
+  <inputData id="age" name="age"><variable name="age" typeRef="number"/></inputData>
+  <businessKnowledgeModel id="double" name="double">
+    <variable name="double"/>
+    <encapsulatedLogic>
+      <formalParameter name="x" typeRef="number"/>
+      <literalExpression><text>x * 2</text></literalExpression>
+    </encapsulatedLogic>
+  </businessKnowledgeModel>
+  <decision id="riskScore" name="Risk Score">
+    <variable name="Risk Score" typeRef="number"/>
+    <informationRequirement><requiredInput href="#age"/></informationRequirement>
+    <literalExpression><text>age + 1</text></literalExpression>
+  </decision>
+</definitions>`
+
+const importingModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="main" name="Main" namespace="http://example.org/main">
+  <import namespace="http://example.org/lib" name="lib" importType="https://www.omg.org/spec/DMN/20191111/MODEL/"/>
+  <decision id="result" name="Result">
+    <variable name="Result" typeRef="number"/>
+    <informationRequirement><requiredDecision href="http://example.org/lib#riskScore"/></informationRequirement>
+    <knowledgeRequirement><requiredKnowledge href="http://example.org/lib#double"/></knowledgeRequirement>
+    <literalExpression><text>lib.double(lib.Risk Score)</text></literalExpression>
+  </decision>
+</definitions>`
+
+func TestEvaluateImports(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		tenantID  string
+		variables map[string]interface{}
+		want      interface{}
+	}{
+		{"qualified input", "", map[string]interface{}{"lib": map[string]interface{}{"age": 30}}, 62.0},
+		{"unqualified input", "", map[string]interface{}{"age": 10}, 22.0},
+		{"other tenant", "acme", map[string]interface{}{"age": 10}, nil},
+	}
+
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", libModel)
+	deploy(t, repo, "", importingModel)
+	deploy(t, repo, "acme", importingModel) // without the library
+	e := NewEngine(repo)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := e.Evaluate(ctx, &EvaluateRequest{DecisionKey: "result", TenantID: tt.tenantID, Variables: tt.variables})
+			if tt.want == nil {
+				var importErr *ImportError
+				if !errors.As(err, &importErr) || !errors.Is(err, storage.ErrNotFound) {
+					t.Fatalf("error = %v, want a missing import", err)
+				}
+				if importErr.Namespace != "http://example.org/lib" || importErr.Name != "lib" {
+					t.Errorf("import error names %s (%s)", importErr.Name, importErr.Namespace)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []map[string]interface{}{{"Result": tt.want}}
+			if !reflect.DeepEqual(res.Outputs, want) {
+				t.Errorf("outputs = %v, want %v", res.Outputs, want)
+			}
+		})
+	}
+}
+
+func TestEvaluateDeletedImport(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemoryRepository()
+	lib := deploy(t, repo, "", libModel)
+	deploy(t, repo, "", importingModel)
+	e := NewEngine(repo)
+
+	req := &EvaluateRequest{DecisionKey: "result", Variables: map[string]interface{}{"age": 1}}
+	if _, err := e.Evaluate(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	// The importing model stays cached; its import is resolved again
+	if err := repo.Delete(ctx, lib.Key, ""); err != nil {
+		t.Fatal(err)
+	}
+	e.DefinitionDeleted("", lib.Key)
+
+	_, err := e.Evaluate(ctx, req)
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("error = %v, want a missing import", err)
+	}
+}
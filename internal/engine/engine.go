@@ -2,34 +2,94 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/konstantin/dmn-engine-go/internal/dmn"
+	"github.com/konstantin/dmn-engine-go/internal/feel"
 	"github.com/konstantin/dmn-engine-go/internal/storage"
 )
 
-// Engine is the main DMN evaluation engine
+// Engine is the main DMN evaluation engine. An engine is safe for concurrent
+// use and is meant to live as long as the repository it reads from
 type Engine struct {
-	repo        storage.DefinitionRepository
-	hitPolicies map[string]HitPolicyStrategy
+	repo        Source
+	hitPolicies *hitPolicies
+	cache       *definitionCache
+	functions   *FunctionRegistry
+	timeout     time.Duration
+	linear      bool // rule indexes are not used
 }
 
-// NewEngine creates a new evaluation engine
-func NewEngine(repo storage.DefinitionRepository) *Engine {
+// Source is the part of a definition repository the engine reads from.
+// Every storage.DefinitionRepository is a Source
+type Source interface {
+	// GetByDecisionID returns the most recently deployed definition containing the decision
+	GetByDecisionID(ctx context.Context, decisionID string, tenantID string) (*storage.Definition, error)
+
+	// GetByDecisionIDAndVersion returns the definition version containing the decision
+	GetByDecisionIDAndVersion(ctx context.Context, decisionID string, version int, tenantID string) (*storage.Definition, error)
+
+	// GetByNamespace returns the most recently deployed definition with the model namespace
+	GetByNamespace(ctx context.Context, namespace string, tenantID string) (*storage.Definition, error)
+}
+
+// ErrTimeout is returned, together with context.DeadlineExceeded, when an
+// evaluation does not finish before its deadline
+var ErrTimeout = errors.New("evaluation timed out")
+
+// cancelCheckInterval is how many rules of a decision table are tested
+// between checks for cancellation
+const cancelCheckInterval = 64
+
+// Options configures an engine
+type Options struct {
+	// CacheSize is the number of compiled definitions kept in memory.
+	// Zero disables the cache
+	CacheSize int
+
+	// LatestTTL bounds how long a resolution of the latest version is
+	// trusted. Deploys and deletes through this engine invalidate
+	// resolutions immediately; the TTL covers changes made by other
+	// processes sharing the repository. Zero means no expiry
+	LatestTTL time.Duration
+
+	// Timeout bounds every evaluation, including each item of a batch.
+	// Evaluation stops at the next cancellation check once it is exceeded.
+	// Zero means no limit beyond the caller's context
+	Timeout time.Duration
+
+	// DisableRuleIndex tests every rule of a decision table in order
+	// instead of looking up candidate rules in the table's index. Results
+	// are the same either way; the option exists for comparison
+	DisableRuleIndex bool
+
+	// Functions are the Go functions FEEL expressions may call. Nil gives
+	// the engine an empty registry of its own
+	Functions *FunctionRegistry
+}
+
+// NewEngine creates a new evaluation engine with a cache of
+// DefaultCacheSize compiled definitions
+func NewEngine(repo Source) *Engine {
+	return NewEngineWithOptions(repo, Options{CacheSize: DefaultCacheSize})
+}
+
+// NewEngineWithOptions creates a new evaluation engine with the given options
+func NewEngineWithOptions(repo Source, opts Options) *Engine {
 	e := &Engine{
 		repo:        repo,
-		hitPolicies: make(map[string]HitPolicyStrategy),
+		hitPolicies: newHitPolicies(),
+		cache:       newDefinitionCache(opts.CacheSize, opts.LatestTTL),
+		timeout:     opts.Timeout,
+		functions:   opts.Functions,
+		linear:      opts.DisableRuleIndex,
+	}
+	if e.functions == nil {
+		e.functions = NewFunctionRegistry()
 	}
-
-	// Register hit policies
-	e.hitPolicies[dmn.HitPolicyUnique] = &UniqueHitPolicy{}
-	e.hitPolicies[dmn.HitPolicyFirst] = &FirstHitPolicy{}
-	e.hitPolicies[dmn.HitPolicyAny] = &AnyHitPolicy{}
-	e.hitPolicies[dmn.HitPolicyCollect] = &CollectHitPolicy{}
-	e.hitPolicies[dmn.HitPolicyPriority] = &PriorityHitPolicy{}
-	e.hitPolicies[dmn.HitPolicyRuleOrder] = &RuleOrderHitPolicy{}
-	e.hitPolicies[dmn.HitPolicyOutputOrder] = &OutputOrderHitPolicy{}
 
 	return e
 }
@@ -40,128 +100,358 @@ type EvaluateRequest struct {
 	Version     *int                   `json:"version,omitempty"`
 	Variables   map[string]interface{} `json:"variables"`
 	TenantID    string                 `json:"tenantId,omitempty"`
+	Explain     bool                   `json:"explain,omitempty"` // return a trace of the evaluation
 }
 
 // EvaluateResult is the result of a decision evaluation
 type EvaluateResult struct {
+	DefinitionID string                   `json:"definitionId"`
 	DecisionKey  string                   `json:"decisionKey"`
 	DecisionName string                   `json:"decisionName"`
 	Version      int                      `json:"version"`
 	Outputs      []map[string]interface{} `json:"outputs"`
-	MatchedRules []string                 `json:"matchedRules"`
+	MatchedRules []RuleMatch              `json:"matchedRules"`
+	Defaulted    bool                     `json:"defaulted,omitempty"` // outputs come from defaultOutputEntry
 	EvaluatedAt  time.Time                `json:"evaluatedAt"`
 	DurationNs   int64                    `json:"durationNs"`
+	Trace        *Trace                   `json:"trace,omitempty"` // set when the request asks to explain
+}
+
+// RuleMatch describes a rule that matched during evaluation
+type RuleMatch struct {
+	RuleID      string           `json:"ruleId"`
+	RuleIndex   int              `json:"ruleIndex"` // position of the rule in the table, starting at 0
+	Description string           `json:"description,omitempty"`
+	Annotations []RuleAnnotation `json:"annotations,omitempty"`
+}
+
+// RuleAnnotation is the value of an annotation column for a matched rule
+type RuleAnnotation struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
 }
 
-// Evaluate evaluates a decision
+// Evaluate evaluates a decision. Evaluation stops when ctx is done or the
+// engine's timeout is exceeded
 func (e *Engine) Evaluate(ctx context.Context, req *EvaluateRequest) (*EvaluateResult, error) {
 	start := time.Now()
 
-	// 1. Get definition
-	var def *storage.Definition
-	var err error
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
 
-	if req.Version != nil && *req.Version > 0 {
-		def, err = e.repo.GetByKeyAndVersion(ctx, req.DecisionKey, *req.Version, req.TenantID)
-	} else {
-		def, err = e.repo.GetByKey(ctx, req.DecisionKey, req.TenantID)
+	// 1. Get the compiled definition containing the decision
+	model, err := e.definition(ctx, decisionRef(req.TenantID, req.DecisionKey, req.Version))
+	if err != nil {
+		return nil, timedOut(fmt.Errorf("decision %s not found: %w", req.DecisionKey, err))
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("definition not found: %w", err)
+	return e.evaluateModel(ctx, model, req, start)
+}
+
+// withTimeout applies the engine's evaluation timeout to ctx
+func (e *Engine) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, e.timeout)
+}
 
-	// 2. Find the decision
-	decision := def.ParsedModel.GetDecision(req.DecisionKey)
-	if decision == nil {
-		// If not found by decision ID, use the first decision
-		if len(def.ParsedModel.Decisions) > 0 {
-			decision = &def.ParsedModel.Decisions[0]
-		} else {
-			return nil, fmt.Errorf("no decision found in definition")
-		}
+// timedOut marks an error caused by an exceeded deadline with ErrTimeout
+func timedOut(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrTimeout) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
+// decisionRef builds the cache reference of a decision ID and an optional
+// version
+func decisionRef(tenantID, decisionID string, version *int) resolveKey {
+	rk := resolveKey{tenantID: tenantID, kind: refDecision, ref: decisionID}
+	if version != nil && *version > 0 {
+		rk.version = *version
+	}
+	return rk
+}
+
+// evaluateModel evaluates a decision of a resolved definition
+func (e *Engine) evaluateModel(ctx context.Context, model *compiledModel, req *EvaluateRequest, start time.Time) (*EvaluateResult, error) {
+	def := model.def
+
+	// 2. Resolve the evaluation target: a decision service or a single decision
+	drg := newDRGEvaluation(e, model, req.TenantID, req.Variables)
+	if req.Explain {
+		drg.trace = &Trace{Decisions: []*DecisionTrace{}}
 	}
 
-	// 3. Evaluate the decision
-	outputs, matchedRules, err := e.evaluateDecision(ctx, decision, req.Variables)
+	var name string
+	var outputs []map[string]interface{}
+	var matchedRules []RuleMatch
+	var defaulted bool
+	var err error
+
+	if svc := def.ParsedModel.GetDecisionService(req.DecisionKey); svc != nil {
+		name = svc.Name
+		outputs, matchedRules, defaulted, err = drg.evaluateService(ctx, svc)
+	} else {
+		decision := def.ParsedModel.GetDecision(req.DecisionKey)
+		if decision == nil {
+			return nil, fmt.Errorf("decision %s not found in definition %s: %w", req.DecisionKey, def.Key, storage.ErrNotFound)
+		}
+		name = decision.Name
+
+		// 3. Evaluate the decision together with its required decisions
+		var res *decisionResult
+		res, err = drg.evaluate(ctx, decision)
+		if res != nil {
+			outputs, matchedRules, defaulted = res.outputs, res.matchedRules, res.defaulted
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("evaluation failed: %w", err)
+		return nil, withTrace(timedOut(fmt.Errorf("evaluation failed: %w", err)), drg.trace)
 	}
 
 	// 4. Build result
 	result := &EvaluateResult{
-		DecisionKey:  def.Key,
-		DecisionName: decision.Name,
+		DefinitionID: def.ID,
+		DecisionKey:  req.DecisionKey,
+		DecisionName: name,
 		Version:      def.Version,
 		Outputs:      outputs,
 		MatchedRules: matchedRules,
+		Defaulted:    defaulted,
 		EvaluatedAt:  time.Now(),
 		DurationNs:   time.Since(start).Nanoseconds(),
+		Trace:        drg.trace,
 	}
 
 	return result, nil
 }
 
-// evaluateDecision evaluates a single decision
-func (e *Engine) evaluateDecision(ctx context.Context, decision *dmn.Decision, variables map[string]interface{}) ([]map[string]interface{}, []string, error) {
-	// For now, only support decision tables
-	if decision.DecisionTable == nil {
-		return nil, nil, fmt.Errorf("decision must have a decision table (literal expressions not yet supported)")
+// definition returns the compiled definition a decision ID or namespace
+// refers to, reading and compiling it on a cache miss
+func (e *Engine) definition(ctx context.Context, rk resolveKey) (*compiledModel, error) {
+	model, generation, ok := e.cache.lookup(rk)
+	if ok {
+		return model, nil
+	}
+
+	var def *storage.Definition
+	var err error
+	switch {
+	case rk.kind == refNamespace:
+		def, err = e.repo.GetByNamespace(ctx, rk.ref, rk.tenantID)
+	case rk.version > 0:
+		def, err = e.repo.GetByDecisionIDAndVersion(ctx, rk.ref, rk.version, rk.tenantID)
+	default:
+		def, err = e.repo.GetByDecisionID(ctx, rk.ref, rk.tenantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Another reference may have compiled the same version already
+	model, ok = e.cache.get(cacheKey{tenantID: def.TenantID, key: def.Key, version: def.Version})
+	if !ok || model.def.ID != def.ID {
+		model = compile(def)
+	}
+	e.cache.add(rk, generation, model)
+	return model, nil
+}
+
+// Compile parses the FEEL of a deployed definition and caches the result,
+// so the first evaluation of the definition does not pay for it. It does
+// nothing when the cache is disabled
+func (e *Engine) Compile(def *storage.Definition) {
+	e.cache.put(compile(def))
+}
+
+// RegisterHitPolicy adds a hit policy that decision tables may name in
+// their hitPolicy attribute, or replaces a custom one registered before.
+// Built-in hit policies cannot be replaced
+func (e *Engine) RegisterHitPolicy(name string, strategy HitPolicyStrategy) error {
+	return e.hitPolicies.register(name, strategy)
+}
+
+// HitPolicies returns the names of the registered custom hit policies
+func (e *Engine) HitPolicies() []string {
+	return e.hitPolicies.custom()
+}
+
+// Functions returns the registry of Go functions callable from FEEL
+func (e *Engine) Functions() *FunctionRegistry {
+	return e.functions
+}
+
+// DefinitionDeployed invalidates the tenant's resolutions of latest versions.
+// It must be called after a definition is deployed
+func (e *Engine) DefinitionDeployed(tenantID string) {
+	e.cache.deployed(tenantID)
+}
+
+// DefinitionDeleted drops all cached versions of a definition. It must be
+// called after a definition is deleted
+func (e *Engine) DefinitionDeleted(tenantID, key string) {
+	e.cache.deleted(tenantID, key)
+}
+
+// CacheStats returns the compiled-definition cache statistics
+func (e *Engine) CacheStats() CacheStats {
+	return e.cache.stats()
+}
+
+// evaluateDecision evaluates a single decision of a compiled model. The
+// evaluation is recorded in dt unless it is nil
+func (e *Engine) evaluateDecision(ctx context.Context, model *compiledModel, decision *dmn.Decision, scope *feel.Scope, dt *DecisionTrace) (*decisionResult, error) {
+	var res *decisionResult
+	var err error
+
+	switch {
+	case decision.DecisionTable != nil:
+		res, err = e.evaluateDecisionTable(ctx, decision.DecisionTable, model.table(decision.DecisionTable), scope, dt)
+	case decision.LiteralExpression != nil:
+		if dt != nil {
+			dt.Expression = strings.TrimSpace(decision.LiteralExpression.Text)
+		}
+		var value interface{}
+		value, err = model.literal(decision.LiteralExpression).eval(scope)
+		res = &decisionResult{
+			outputs:      []map[string]interface{}{{decisionVariableName(decision): value}},
+			matchedRules: []RuleMatch{},
+		}
+	default:
+		return nil, fmt.Errorf("decision must have a decision table or a literal expression")
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return e.evaluateDecisionTable(ctx, decision.DecisionTable, variables)
+	res.decision = decision
+	return res, nil
 }
 
-// evaluateDecisionTable evaluates a decision table
-func (e *Engine) evaluateDecisionTable(ctx context.Context, table *dmn.DecisionTable, variables map[string]interface{}) ([]map[string]interface{}, []string, error) {
-	// Find matching rules
+// evaluateDecisionTable evaluates a decision table using its compiled cells.
+// The input values, tested rules and hit policy are recorded in dt unless
+// it is nil
+func (e *Engine) evaluateDecisionTable(ctx context.Context, table *dmn.DecisionTable, compiled *compiledTable, scope *feel.Scope, dt *DecisionTrace) (*decisionResult, error) {
+	inputValues, err := evaluateInputs(compiled, scope)
+	if err != nil {
+		return nil, err
+	}
+	if dt != nil {
+		newTableTrace(dt, table, inputValues)
+	}
+
+	// Find matching rules. An explained evaluation tests the same candidate
+	// rules, so the trace shows what was actually evaluated
+	var candidates bitset
+	if compiled.index != nil && !e.linear {
+		candidates = compiled.index.candidates(inputValues)
+	}
+
 	var matchedRules []MatchedRule
+	stoppedEarly := false
 
-	for _, rule := range table.Rules {
-		matched, outputs, err := e.evaluateRule(ctx, &rule, table.Inputs, table.Outputs, variables)
+	tested := 0
+	for i := nextCandidate(candidates, 0, len(table.Rules)); i >= 0; i = nextCandidate(candidates, i+1, len(table.Rules)) {
+		if tested%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		tested++
+		rule := table.Rules[i]
+
+		var rt *RuleTrace
+		if dt != nil {
+			dt.Rules = append(dt.Rules, RuleTrace{RuleID: rule.ID, RuleIndex: i})
+			rt = &dt.Rules[len(dt.Rules)-1]
+		}
+
+		matched, outputs, err := e.evaluateRule(ctx, compiled, i, inputValues, table.Outputs, scope, rt)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error evaluating rule %s: %w", rule.ID, err)
+			return nil, fmt.Errorf("error evaluating rule %s: %w", rule.ID, err)
 		}
 
 		if matched {
 			matchedRules = append(matchedRules, MatchedRule{
-				RuleID:  rule.ID,
-				Outputs: outputs,
+				RuleID:    rule.ID,
+				RuleIndex: i,
+				Outputs:   outputs,
 			})
 
 			// Stop on first match for FIRST policy
-			hitPolicy := table.HitPolicy
-			if hitPolicy == "" {
-				hitPolicy = dmn.HitPolicyUnique
-			}
-			if hitPolicy == dmn.HitPolicyFirst {
+			if compiled.policy.HitPolicy == dmn.HitPolicyFirst {
+				stoppedEarly = i < len(table.Rules)-1
 				break
 			}
 		}
 	}
 
 	// Apply hit policy
-	hitPolicy := table.HitPolicy
-	if hitPolicy == "" {
-		hitPolicy = dmn.HitPolicyUnique
+	policy := compiled.policy
+	strategy, ok := e.hitPolicies.get(policy.HitPolicy)
+	if !ok {
+		return nil, fmt.Errorf("unsupported hit policy: %s", policy.HitPolicy)
 	}
 
-	strategy, ok := e.hitPolicies[hitPolicy]
-	if !ok {
-		return nil, nil, fmt.Errorf("unsupported hit policy: %s", hitPolicy)
+	// Single-hit tables fall back to the declared default outputs
+	defaulted := len(matchedRules) == 0 && singleHit(strategy, policy) && table.HasDefaultOutputs()
+	if dt != nil {
+		dt.HitPolicy = newHitPolicyTrace(table, policy.HitPolicy, matchedRules, stoppedEarly, defaulted)
+	}
+	if defaulted {
+		defaults, err := evaluateDefaultOutputs(compiled, table.Outputs, scope)
+		if err != nil {
+			return nil, err
+		}
+		return &decisionResult{
+			outputs:      []map[string]interface{}{defaults},
+			matchedRules: []RuleMatch{},
+			defaulted:    true,
+		}, nil
 	}
 
-	outputs, err := strategy.Apply(matchedRules, table.Aggregation)
+	applied, err := strategy.Apply(policy, matchedRules)
 	if err != nil {
-		return nil, nil, fmt.Errorf("hit policy error: %w", err)
+		return nil, fmt.Errorf("hit policy error: %w", err)
+	}
+	if dt != nil {
+		dt.HitPolicy.SelectedRules = append(dt.HitPolicy.SelectedRules, applied.Selected...)
 	}
 
-	// Collect matched rule IDs
-	ruleIDs := make([]string, len(matchedRules))
+	// Describe matched rules with their descriptions and annotations
+	ruleMatches := make([]RuleMatch, len(matchedRules))
 	for i, r := range matchedRules {
-		ruleIDs[i] = r.RuleID
+		ruleMatches[i] = newRuleMatch(table, r.RuleIndex)
 	}
 
-	return outputs, ruleIDs, nil
+	return &decisionResult{
+		outputs:      applied.Outputs,
+		matchedRules: ruleMatches,
+		multiHit:     strategy.MultiHit(policy),
+	}, nil
 }
 
+// newRuleMatch builds the result entry for the rule at the given index
+func newRuleMatch(table *dmn.DecisionTable, index int) RuleMatch {
+	rule := &table.Rules[index]
+	match := RuleMatch{
+		RuleID:      rule.ID,
+		RuleIndex:   index,
+		Description: strings.TrimSpace(rule.Description),
+	}
+
+	for i, entry := range rule.AnnotationEntries {
+		name := fmt.Sprintf("annotation%d", i+1)
+		if i < len(table.Annotations) && table.Annotations[i].Name != "" {
+			name = table.Annotations[i].Name
+		}
+		match.Annotations = append(match.Annotations, RuleAnnotation{
+			Name: name,
+			Text: strings.TrimSpace(entry.Text),
+		})
+	}
+
+	return match
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// defaultsModel is a decision "d" with a table over age whose
+// risk output declares the given default output entry
+func defaultsModel(hitPolicy, defaults string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Defaults" namespace="http://example.org/defaults">
+  <inputData id="in_age" name="age"><variable name="age"/></inputData>
+  <decision id="d" name="D">
+    <variable name="D"/>
+    <informationRequirement><requiredInput href="#in_age"/></informationRequirement>
+    <decisionTable hitPolicy="` + hitPolicy + `">
+      <input><inputExpression typeRef="number"><text>age</text></inputExpression></input>
+      <output name="risk">` + defaults + `</output>
+      <output name="note"/>
+      <rule id="r0"><inputEntry><text>&lt; 18</text></inputEntry><outputEntry><text>"HIGH"</text></outputEntry><outputEntry><text>"minor"</text></outputEntry></rule>
+      <rule id="r1"><inputEntry><text>&gt; 65</text></inputEntry><outputEntry><text>"MEDIUM"</text></outputEntry><outputEntry><text>"senior"</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+}
+
+func TestEvaluateDefaultOutputs(t *testing.T) {
+	const withDefault = `<defaultOutputEntry><text>"LOW"</text></defaultOutputEntry>`
+
+	tests := []struct {
+		name      string
+		hitPolicy string
+		defaults  string
+		age       int
+		want      []map[string]interface{}
+		defaulted bool
+	}{
+		{"FIRST without a match", "FIRST", withDefault, 30, []map[string]interface{}{{"risk": "LOW", "note": nil}}, true},
+		{"FIRST with a match", "FIRST", withDefault, 10, []map[string]interface{}{{"risk": "HIGH", "note": "minor"}}, false},
+		{"FIRST without defaults", "FIRST", "", 30, nil, false},
+		{"UNIQUE without a match", "UNIQUE", withDefault, 30, []map[string]interface{}{{"risk": "LOW", "note": nil}}, true},
+		{"RULE ORDER without a match", "RULE ORDER", withDefault, 30, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storage.NewMemoryRepository()
+			deploy(t, repo, "", defaultsModel(tt.hitPolicy, tt.defaults))
+
+			res, err := NewEngine(repo).Evaluate(context.Background(), &EvaluateRequest{
+				DecisionKey: "d",
+				Variables:   map[string]interface{}{"age": tt.age},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res.Outputs, tt.want) {
+				t.Errorf("outputs = %v, want %v", res.Outputs, tt.want)
+			}
+			if res.Defaulted != tt.defaulted {
+				t.Errorf("defaulted = %v, want %v", res.Defaulted, tt.defaulted)
+			}
+			if tt.defaulted && len(res.MatchedRules) != 0 {
+				t.Errorf("defaulted result matched rules %v", res.MatchedRules)
+			}
+		})
+	}
+}
+
+// annotatedModel is a decision "d" whose rules carry descriptions and the
+// values of two annotation columns, the second one unnamed
+const annotatedModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Annotated" namespace="http://example.org/annotated">
+  <inputData id="in_age" name="age"><variable name="age"/></inputData>
+  <decision id="d" name="D">
+    <variable name="D"/>
+    <informationRequirement><requiredInput href="#in_age"/></informationRequirement>
+    <decisionTable hitPolicy="RULE ORDER">
+      <input><inputExpression typeRef="number"><text>age</text></inputExpression></input>
+      <output name="risk"/>
+      <annotation name="source"/>
+      <annotation/>
+      <rule id="minor">
+        <description>
+          Minors are always high risk
+        </description>
+        <inputEntry><text>&lt; 18</text></inputEntry>
+        <outputEntry><text>"HIGH"</text></outputEntry>
+        <annotationEntry><text> policy 4.2 </text></annotationEntry>
+        <annotationEntry><text>reviewed</text></annotationEntry>
+      </rule>
+      <rule id="any">
+        <inputEntry><text>-</text></inputEntry>
+        <outputEntry><text>"LOW"</text></outputEntry>
+      </rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+
+func TestMatchedRuleAnnotations(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", annotatedModel)
+
+	res, err := NewEngine(repo).Evaluate(context.Background(), &EvaluateRequest{
+		DecisionKey: "d",
+		Variables:   map[string]interface{}{"age": 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []RuleMatch{
+		{
+			RuleID:      "minor",
+			RuleIndex:   0,
+			Description: "Minors are always high risk",
+			Annotations: []RuleAnnotation{{Name: "source", Text: "policy 4.2"}, {Name: "annotation2", Text: "reviewed"}},
+		},
+		{RuleID: "any", RuleIndex: 1},
+	}
+	if !reflect.DeepEqual(res.MatchedRules, want) {
+		t.Errorf("matched rules = %+v, want %+v", res.MatchedRules, want)
+	}
+}
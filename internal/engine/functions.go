@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+)
+
+// FunctionRegistry holds Go functions callable from FEEL by name, after
+// the variables of the evaluation and the built-ins. Functions registered
+// for the empty tenant are visible to every tenant; a tenant's own function
+// of the same name takes precedence. A registry is safe for concurrent use;
+// registrations apply to evaluations that start afterwards
+type FunctionRegistry struct {
+	mu      sync.RWMutex
+	tenants map[string]map[string]*feel.Function // by tenant, then name
+	scopes  map[string]map[string]interface{}    // resolved functions by tenant, rebuilt after changes
+}
+
+// NewFunctionRegistry creates an empty registry
+func NewFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{
+		tenants: make(map[string]map[string]*feel.Function),
+		scopes:  make(map[string]map[string]interface{}),
+	}
+}
+
+// Register adds a function for a tenant, or for all tenants when tenantID
+// is empty, replacing a function of the same name. See feel.NewFunction for
+// how arguments are checked against params
+func (r *FunctionRegistry) Register(tenantID, name string, params []feel.Param, call func(args []interface{}) (interface{}, error)) error {
+	fn, err := feel.NewFunction(name, params, call)
+	if err != nil {
+		return fmt.Errorf("register function: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	functions, ok := r.tenants[tenantID]
+	if !ok {
+		functions = make(map[string]*feel.Function)
+		r.tenants[tenantID] = functions
+	}
+	functions[fn.Name] = fn
+	clear(r.scopes)
+	return nil
+}
+
+// Unregister removes a function registered for the tenant and reports
+// whether there was one
+func (r *FunctionRegistry) Unregister(tenantID, name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tenants[tenantID][name]; !ok {
+		return false
+	}
+	delete(r.tenants[tenantID], name)
+	clear(r.scopes)
+	return true
+}
+
+// Functions returns the functions visible to a tenant by name
+func (r *FunctionRegistry) Functions(tenantID string) map[string]*feel.Function {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	functions := make(map[string]*feel.Function, len(r.tenants[""])+len(r.tenants[tenantID]))
+	for name, fn := range r.tenants[""] {
+		functions[name] = fn
+	}
+	for name, fn := range r.tenants[tenantID] {
+		functions[name] = fn
+	}
+	return functions
+}
+
+// scope returns the functions visible to a tenant as scope variables. The
+// map is shared between evaluations and must not be modified
+func (r *FunctionRegistry) scope(tenantID string) map[string]interface{} {
+	r.mu.RLock()
+	vars, ok := r.scopes[tenantID]
+	r.mu.RUnlock()
+	if ok {
+		return vars
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if vars, ok := r.scopes[tenantID]; ok {
+		return vars
+	}
+	vars = make(map[string]interface{}, len(r.tenants[""])+len(r.tenants[tenantID]))
+	for name, fn := range r.tenants[""] {
+		vars[name] = fn
+	}
+	for name, fn := range r.tenants[tenantID] {
+		vars[name] = fn
+	}
+	r.scopes[tenantID] = vars
+	return vars
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// discount returns a function that takes rate off an amount and counts
+// its calls
+func discount(rate float64, calls *int) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		*calls++
+		return args[0].(float64) * (1 - rate), nil
+	}
+}
+
+func TestFunctionRegistryRegister(t *testing.T) {
+	call := func(args []interface{}) (interface{}, error) { return nil, nil }
+	tests := []struct {
+		name    string
+		fn      string
+		params  []feel.Param
+		call    func(args []interface{}) (interface{}, error)
+		wantErr string
+	}{
+		{"valid", "discount", []feel.Param{{Name: "amount", Type: "number"}, {Name: "code", Type: "string", Optional: true}}, call, ""},
+		{"no name", " ", nil, call, "function name is required"},
+		{"built-in", "sum", nil, call, "sum is a built-in function"},
+		{"no implementation", "discount", nil, nil, "implementation is required"},
+		{"unnamed parameter", "discount", []feel.Param{{Type: "number"}}, call, "parameter 0 has no name"},
+		{"duplicate parameter", "discount", []feel.Param{{Name: "a", Type: "number"}, {Name: "a", Type: "string"}}, call, `duplicate parameter "a"`},
+		{"unknown type", "discount", []feel.Param{{Name: "a", Type: "decimal"}}, call, `unknown type "decimal"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewFunctionRegistry()
+			err := r.Register("acme", tt.fn, tt.params, tt.call)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r.Functions("acme")[tt.fn] == nil {
+					t.Errorf("%s is not registered", tt.fn)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if len(r.Functions("acme")) != 0 {
+				t.Error("invalid function was registered")
+			}
+		})
+	}
+}
+
+func TestFunctionRegistryTenants(t *testing.T) {
+	var globalCalls, acmeCalls int
+	r := NewFunctionRegistry()
+	amount := []feel.Param{{Name: "amount", Type: "number"}}
+	for _, reg := range []struct {
+		tenantID, name string
+		call           func(args []interface{}) (interface{}, error)
+	}{
+		{"", "discount", discount(0.1, &globalCalls)},
+		{"acme", "discount", discount(0.5, &acmeCalls)},
+		{"beta", "bonus", discount(-0.5, new(int))},
+	} {
+		if err := r.Register(reg.tenantID, reg.name, amount, reg.call); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(tenantID string) string {
+		var list []string
+		for name := range r.Functions(tenantID) {
+			list = append(list, name)
+		}
+		sort.Strings(list)
+		return strings.Join(list, ",")
+	}
+	for tenantID, want := range map[string]string{"": "discount", "acme": "discount", "beta": "bonus,discount", "gamma": "discount"} {
+		if got := names(tenantID); got != want {
+			t.Errorf("functions of %q = %s, want %s", tenantID, got, want)
+		}
+	}
+	if r.Functions("acme")["discount"] == r.Functions("")["discount"] {
+		t.Error("acme does not see its own discount")
+	}
+
+	if r.Unregister("gamma", "discount") {
+		t.Error("unregistered a function gamma does not own")
+	}
+	if !r.Unregister("acme", "discount") {
+		t.Fatal("acme's discount was not unregistered")
+	}
+	if r.Functions("acme")["discount"] != r.Functions("")["discount"] {
+		t.Error("acme does not fall back to the global discount")
+	}
+}
+
+func TestEvaluateFunctions(t *testing.T) {
+	var globalCalls, acmeCalls int
+	functions := NewFunctionRegistry()
+	amount := []feel.Param{{Name: "amount", Type: "number"}}
+	if err := functions.Register("", "discount", amount, discount(0.1, &globalCalls)); err != nil {
+		t.Fatal(err)
+	}
+	if err := functions.Register("acme", "discount", amount, discount(0.5, &acmeCalls)); err != nil {
+		t.Fatal(err)
+	}
+	if err := functions.Register("beta", "bonus", amount, discount(-0.5, new(int))); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := storage.NewMemoryRepository()
+	expressions := map[string]string{
+		"price":    "discount(100)",
+		"bonus":    "bonus(100)",
+		"mistyped": `discount("100")`,
+		"extra":    "discount(100, 1)",
+		"shadowed": "discount",
+	}
+	for _, tenantID := range []string{"", "acme", "beta"} {
+		for id, expr := range expressions {
+			deploy(t, repo, tenantID, literalModel(id, expr))
+		}
+	}
+	e := NewEngineWithOptions(repo, Options{CacheSize: 16, Functions: functions})
+
+	tests := []struct {
+		tenantID string
+		decision string
+		vars     map[string]interface{}
+		want     interface{}
+		wantErr  string
+	}{
+		{"", "price", nil, 90.0, ""},
+		{"acme", "price", nil, 50.0, ""},
+		{"beta", "price", nil, 90.0, ""},
+		{"beta", "bonus", nil, 150.0, ""},
+		{"acme", "bonus", nil, nil, "bonus is not a function"},
+		{"", "bonus", nil, nil, "bonus is not a function"},
+		// Arguments are checked like those of built-ins
+		{"", "mistyped", nil, nil, ""},
+		{"", "extra", nil, nil, "expected at most 1 arguments, got 2"},
+		// Variables of the evaluation come first
+		{"acme", "shadowed", map[string]interface{}{"discount": 5}, 5, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tenantID+"/"+tt.decision, func(t *testing.T) {
+			res, err := e.Evaluate(context.Background(), &EvaluateRequest{DecisionKey: tt.decision, TenantID: tt.tenantID, Variables: tt.vars})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Outputs[0][tt.decision]; got != tt.want {
+				t.Errorf("%s = %v (%T), want %v", tt.decision, got, got, tt.want)
+			}
+		})
+	}
+
+	if globalCalls != 2 || acmeCalls != 1 {
+		t.Errorf("global discount called %d times, acme's %d, want 2 and 1", globalCalls, acmeCalls)
+	}
+
+	// Registrations apply to evaluations that start afterwards
+	functions.Unregister("acme", "discount")
+	res, err := e.Evaluate(context.Background(), &EvaluateRequest{DecisionKey: "price", TenantID: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Outputs[0]["price"]; got != 90.0 {
+		t.Errorf("price after unregistering = %v, want 90", got)
+	}
+}
@@ -2,85 +2,244 @@ package engine
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/konstantin/dmn-engine-go/internal/dmn"
 )
 
 // MatchedRule represents a rule that matched the input
 type MatchedRule struct {
-	RuleID  string
-	Outputs map[string]interface{}
+	RuleID    string
+	RuleIndex int // position of the rule in the table, starting at 0
+	Outputs   map[string]interface{}
+}
+
+// HitPolicyResult is what a hit policy makes of the matched rules
+type HitPolicyResult struct {
+	Outputs []map[string]interface{}
+
+	// Selected are the indexes of the rules the outputs come from, in the
+	// order of the outputs; every matched rule for an aggregation
+	Selected []int
 }
 
 // HitPolicyStrategy defines the interface for hit policy implementations
 type HitPolicyStrategy interface {
-	Apply(matched []MatchedRule, aggregation string) ([]map[string]interface{}, error)
+	// Apply returns the outputs of the table for the rules that matched,
+	// which are given in rule order, together with the rules it selected.
+	// No rules matched when matched is empty
+	Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error)
+
+	// MultiHit reports whether the result of the table is the list of all
+	// outputs Apply returns, rather than the only one. Default output
+	// entries apply to tables of custom hit policies that are not multi-hit
+	MultiHit(table *HitPolicyTable) bool
+}
+
+// HitPolicyTable is the compiled decision table a hit policy is applied to
+type HitPolicyTable struct {
+	Table       *dmn.DecisionTable // the parsed table, with its rules and annotations
+	HitPolicy   string             // never empty; UNIQUE when the table omits it
+	Aggregation string
+	Outputs     []OutputColumn
+}
+
+// OutputColumn describes an output column of a decision table
+type OutputColumn struct {
+	Index   int
+	Name    string
+	Label   string
+	TypeRef string
+
+	// Values are the literal values of outputValues in declared order,
+	// the order PRIORITY and OUTPUT ORDER rank outputs by. Nil when the
+	// column declares none or they are not a list of literals
+	Values []interface{}
+}
+
+// hitPolicies holds the hit policy strategies of an engine by name
+type hitPolicies struct {
+	mu         sync.RWMutex
+	strategies map[string]HitPolicyStrategy
+}
+
+func newHitPolicies() *hitPolicies {
+	return &hitPolicies{strategies: map[string]HitPolicyStrategy{
+		dmn.HitPolicyUnique:      &UniqueHitPolicy{},
+		dmn.HitPolicyFirst:       &FirstHitPolicy{},
+		dmn.HitPolicyAny:         &AnyHitPolicy{},
+		dmn.HitPolicyCollect:     &CollectHitPolicy{},
+		dmn.HitPolicyPriority:    &PriorityHitPolicy{},
+		dmn.HitPolicyRuleOrder:   &RuleOrderHitPolicy{},
+		dmn.HitPolicyOutputOrder: &OutputOrderHitPolicy{},
+	}}
+}
+
+func (h *hitPolicies) get(name string) (HitPolicyStrategy, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	strategy, ok := h.strategies[name]
+	return strategy, ok
+}
+
+func (h *hitPolicies) register(name string, strategy HitPolicyStrategy) error {
+	if name == "" || strategy == nil {
+		return fmt.Errorf("hit policy name and strategy are required")
+	}
+	if isBuiltinHitPolicy(name) {
+		return fmt.Errorf("%s is a built-in hit policy", name)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.strategies[name] = strategy
+	return nil
+}
+
+// custom returns the names of the registered custom hit policies
+func (h *hitPolicies) custom() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var names []string
+	for name := range h.strategies {
+		if !isBuiltinHitPolicy(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isBuiltinHitPolicy reports whether name is a hit policy of the DMN
+// specification
+func isBuiltinHitPolicy(name string) bool {
+	switch name {
+	case dmn.HitPolicyUnique, dmn.HitPolicyFirst, dmn.HitPolicyPriority, dmn.HitPolicyAny,
+		dmn.HitPolicyCollect, dmn.HitPolicyRuleOrder, dmn.HitPolicyOutputOrder:
+		return true
+	}
+	return false
+}
+
+// singleHit reports whether the table falls back to its default output
+// entries when no rule matches
+func singleHit(strategy HitPolicyStrategy, table *HitPolicyTable) bool {
+	if isBuiltinHitPolicy(table.HitPolicy) {
+		return dmn.IsSingleHit(table.HitPolicy)
+	}
+	return !strategy.MultiHit(table)
+}
+
+// selectRules returns the outputs of the given rules in the given order
+func selectRules(rules ...MatchedRule) HitPolicyResult {
+	result := HitPolicyResult{
+		Outputs:  make([]map[string]interface{}, len(rules)),
+		Selected: make([]int, len(rules)),
+	}
+	for i, m := range rules {
+		result.Outputs[i] = m.Outputs
+		result.Selected[i] = m.RuleIndex
+	}
+	return result
 }
 
 // UniqueHitPolicy - exactly one rule must match
 type UniqueHitPolicy struct{}
 
-func (p *UniqueHitPolicy) Apply(matched []MatchedRule, aggregation string) ([]map[string]interface{}, error) {
+func (p *UniqueHitPolicy) MultiHit(table *HitPolicyTable) bool { return false }
+
+func (p *UniqueHitPolicy) Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error) {
 	if len(matched) == 0 {
-		return nil, nil
+		return HitPolicyResult{}, nil
 	}
 	if len(matched) > 1 {
-		return nil, fmt.Errorf("UNIQUE hit policy violated: %d rules matched (expected 1)", len(matched))
+		return HitPolicyResult{}, fmt.Errorf("UNIQUE hit policy violated: %d rules matched (expected 1)", len(matched))
 	}
-	return []map[string]interface{}{matched[0].Outputs}, nil
+	return selectRules(matched[0]), nil
 }
 
 // FirstHitPolicy - return the first matching rule
 type FirstHitPolicy struct{}
 
-func (p *FirstHitPolicy) Apply(matched []MatchedRule, aggregation string) ([]map[string]interface{}, error) {
+func (p *FirstHitPolicy) MultiHit(table *HitPolicyTable) bool { return false }
+
+func (p *FirstHitPolicy) Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error) {
 	if len(matched) == 0 {
-		return nil, nil
+		return HitPolicyResult{}, nil
 	}
-	return []map[string]interface{}{matched[0].Outputs}, nil
+	return selectRules(matched[0]), nil
 }
 
-// AnyHitPolicy - any matching rule (all must have same output)
+// AnyHitPolicy - any matching rule; all matching rules must have the same
+// outputs
 type AnyHitPolicy struct{}
 
-func (p *AnyHitPolicy) Apply(matched []MatchedRule, aggregation string) ([]map[string]interface{}, error) {
+func (p *AnyHitPolicy) MultiHit(table *HitPolicyTable) bool { return false }
+
+func (p *AnyHitPolicy) Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error) {
 	if len(matched) == 0 {
-		return nil, nil
+		return HitPolicyResult{}, nil
 	}
-	
-	// In a strict implementation, we should verify all outputs are the same
-	// For simplicity, we just return the first one
-	return []map[string]interface{}{matched[0].Outputs}, nil
+	for _, m := range matched[1:] {
+		if !outputsEqual(table, matched[0].Outputs, m.Outputs) {
+			return HitPolicyResult{}, fmt.Errorf("ANY hit policy violated: rules %s and %s have different outputs", ruleName(matched[0]), ruleName(m))
+		}
+	}
+	return selectRules(matched[0]), nil
 }
 
-// PriorityHitPolicy - return highest priority rule (based on output order)
+// PriorityHitPolicy - return the matching rule whose outputs come first in
+// the output values of the table; the first such rule on a tie
 type PriorityHitPolicy struct{}
 
-func (p *PriorityHitPolicy) Apply(matched []MatchedRule, aggregation string) ([]map[string]interface{}, error) {
+func (p *PriorityHitPolicy) MultiHit(table *HitPolicyTable) bool { return false }
+
+func (p *PriorityHitPolicy) Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error) {
 	if len(matched) == 0 {
-		return nil, nil
+		return HitPolicyResult{}, nil
+	}
+	if err := checkPriorities(table); err != nil {
+		return HitPolicyResult{}, err
 	}
-	// For simplicity, return the first matched rule
-	// Full implementation would need output value ordering
-	return []map[string]interface{}{matched[0].Outputs}, nil
+	best := matched[0]
+	for _, m := range matched[1:] {
+		if comparePriority(table, m.Outputs, best.Outputs) < 0 {
+			best = m
+		}
+	}
+	return selectRules(best), nil
 }
 
 // CollectHitPolicy - collect all matching rules
 type CollectHitPolicy struct{}
 
-func (p *CollectHitPolicy) Apply(matched []MatchedRule, aggregation string) ([]map[string]interface{}, error) {
+func (p *CollectHitPolicy) MultiHit(table *HitPolicyTable) bool { return table.Aggregation == "" }
+
+func (p *CollectHitPolicy) Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error) {
 	if len(matched) == 0 {
-		return nil, nil
+		return HitPolicyResult{}, nil
 	}
 
 	// If no aggregation, return all outputs
-	if aggregation == "" {
-		results := make([]map[string]interface{}, len(matched))
-		for i, m := range matched {
-			results[i] = m.Outputs
-		}
-		return results, nil
+	result := selectRules(matched...)
+	if table.Aggregation == "" {
+		return result, nil
 	}
 
-	// Apply aggregation
+	// The aggregate is computed from every matched rule
+	outputs, err := aggregate(table.Aggregation, matched)
+	if err != nil {
+		return HitPolicyResult{}, err
+	}
+	result.Outputs = outputs
+	return result, nil
+}
+
+// aggregate computes the single output of a COLLECT table with an
+// aggregation
+func aggregate(aggregation string, matched []MatchedRule) ([]map[string]interface{}, error) {
 	switch aggregation {
 	case "COUNT":
 		return []map[string]interface{}{
@@ -152,31 +311,100 @@ func (p *CollectHitPolicy) Apply(matched []MatchedRule, aggregation string) ([]m
 // RuleOrderHitPolicy - return all matching rules in order
 type RuleOrderHitPolicy struct{}
 
-func (p *RuleOrderHitPolicy) Apply(matched []MatchedRule, aggregation string) ([]map[string]interface{}, error) {
+func (p *RuleOrderHitPolicy) MultiHit(table *HitPolicyTable) bool { return true }
+
+func (p *RuleOrderHitPolicy) Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error) {
 	if len(matched) == 0 {
-		return nil, nil
+		return HitPolicyResult{}, nil
 	}
-	results := make([]map[string]interface{}, len(matched))
-	for i, m := range matched {
-		results[i] = m.Outputs
-	}
-	return results, nil
+	return selectRules(matched...), nil
 }
 
-// OutputOrderHitPolicy - return all matching rules sorted by output
+// OutputOrderHitPolicy - return all matching rules sorted by the priority
+// of their outputs, as PRIORITY ranks them; rules of equal priority stay
+// in rule order
 type OutputOrderHitPolicy struct{}
 
-func (p *OutputOrderHitPolicy) Apply(matched []MatchedRule, aggregation string) ([]map[string]interface{}, error) {
+func (p *OutputOrderHitPolicy) MultiHit(table *HitPolicyTable) bool { return true }
+
+func (p *OutputOrderHitPolicy) Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error) {
 	if len(matched) == 0 {
-		return nil, nil
+		return HitPolicyResult{}, nil
 	}
-	// For simplicity, same as RULE ORDER
-	// Full implementation would sort by output values
-	results := make([]map[string]interface{}, len(matched))
-	for i, m := range matched {
-		results[i] = m.Outputs
+	if err := checkPriorities(table); err != nil {
+		return HitPolicyResult{}, err
+	}
+	sorted := append([]MatchedRule(nil), matched...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return comparePriority(table, sorted[i].Outputs, sorted[j].Outputs) < 0
+	})
+	return selectRules(sorted...), nil
+}
+
+// checkPriorities reports an error unless an output of the table lists
+// the output values PRIORITY and OUTPUT ORDER rank outputs by
+func checkPriorities(table *HitPolicyTable) error {
+	for _, out := range table.Outputs {
+		if out.Values != nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s hit policy requires outputValues listing literal values on at least one output", table.HitPolicy)
+}
+
+// comparePriority compares the outputs of two rules by the position of
+// their values in the output values of each output in turn; outputs
+// without output values are not compared. A value that is not listed ranks
+// after all listed ones. It returns a negative number when a has the
+// higher priority
+func comparePriority(table *HitPolicyTable, a, b map[string]interface{}) int {
+	for _, out := range table.Outputs {
+		if out.Values == nil {
+			continue
+		}
+		if d := priorityRank(out, a[out.Name]) - priorityRank(out, b[out.Name]); d != 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// priorityRank returns the position of v in the output values of out
+func priorityRank(out OutputColumn, v interface{}) int {
+	if key, ok := indexKey(v); ok {
+		for i, value := range out.Values {
+			if value == key {
+				return i
+			}
+		}
+	}
+	return len(out.Values)
+}
+
+// outputsEqual reports whether two rules have equal values for every
+// output of the table
+func outputsEqual(table *HitPolicyTable, a, b map[string]interface{}) bool {
+	for _, out := range table.Outputs {
+		x, y := a[out.Name], b[out.Name]
+		kx, okx := indexKey(x)
+		ky, oky := indexKey(y)
+		if okx && oky {
+			if kx != ky {
+				return false
+			}
+		} else if !reflect.DeepEqual(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleName names a matched rule by its ID, or its position if it has none
+func ruleName(m MatchedRule) string {
+	if m.RuleID != "" {
+		return m.RuleID
 	}
-	return results, nil
+	return fmt.Sprintf("#%d", m.RuleIndex+1)
 }
 
 // Helper function to convert value to float64
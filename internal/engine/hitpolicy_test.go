@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/dmn"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+func TestHitPolicies(t *testing.T) {
+	// Rules rank by risk first, then by their numeric level
+	ranked := &HitPolicyTable{Outputs: []OutputColumn{
+		{Name: "risk", Values: []interface{}{"HIGH", "MEDIUM", "LOW"}},
+		{Name: "note"},
+		{Name: "level", Values: []interface{}{3.0, 2.0, 1.0}},
+	}}
+	plain := &HitPolicyTable{Outputs: []OutputColumn{{Name: "risk"}, {Name: "note"}, {Name: "level"}}}
+	rule := func(index int, risk string, level float64) MatchedRule {
+		return MatchedRule{RuleID: "r" + string(rune('0'+index)), RuleIndex: index, Outputs: map[string]interface{}{"risk": risk, "note": index, "level": level}}
+	}
+	matched := []MatchedRule{rule(1, "LOW", 3), rule(2, "MEDIUM", 1), rule(4, "HIGH", 1), rule(5, "MEDIUM", 2), rule(7, "HIGH", 1)}
+
+	tests := []struct {
+		name     string
+		strategy HitPolicyStrategy
+		table    *HitPolicyTable
+		matched  []MatchedRule
+		want     []int // selected rules
+		wantErr  string
+	}{
+		{"UNIQUE", &UniqueHitPolicy{}, plain, matched[:1], []int{1}, ""},
+		{"UNIQUE violated", &UniqueHitPolicy{}, plain, matched[:2], nil, "UNIQUE hit policy violated: 2 rules matched"},
+		{"FIRST", &FirstHitPolicy{}, plain, matched, []int{1}, ""},
+		{"ANY", &AnyHitPolicy{}, plain, []MatchedRule{rule(2, "HIGH", 1), {RuleID: "r3", RuleIndex: 3, Outputs: map[string]interface{}{"risk": "HIGH", "note": 2, "level": 1}}}, []int{2}, ""},
+		{"ANY violated", &AnyHitPolicy{}, plain, matched[2:], nil, "ANY hit policy violated: rules r4 and r5 have different outputs"},
+		{"PRIORITY", &PriorityHitPolicy{}, ranked, matched, []int{4}, ""},
+		{"PRIORITY by second output", &PriorityHitPolicy{}, ranked, []MatchedRule{matched[1], matched[3]}, []int{5}, ""},
+		{"PRIORITY of unlisted value", &PriorityHitPolicy{}, ranked, []MatchedRule{rule(0, "NONE", 3), rule(1, "LOW", 1)}, []int{1}, ""},
+		{"PRIORITY without output values", &PriorityHitPolicy{}, &HitPolicyTable{HitPolicy: dmn.HitPolicyPriority, Outputs: plain.Outputs}, matched, nil, "PRIORITY hit policy requires outputValues"},
+		{"OUTPUT ORDER", &OutputOrderHitPolicy{}, ranked, matched, []int{4, 7, 5, 2, 1}, ""},
+		{"OUTPUT ORDER without output values", &OutputOrderHitPolicy{}, &HitPolicyTable{HitPolicy: dmn.HitPolicyOutputOrder, Outputs: plain.Outputs}, matched, nil, "OUTPUT ORDER hit policy requires outputValues"},
+		{"RULE ORDER", &RuleOrderHitPolicy{}, plain, matched, []int{1, 2, 4, 5, 7}, ""},
+		{"COLLECT", &CollectHitPolicy{}, plain, matched, []int{1, 2, 4, 5, 7}, ""},
+		{"COLLECT COUNT", &CollectHitPolicy{}, &HitPolicyTable{Aggregation: "COUNT", Outputs: plain.Outputs}, matched, []int{1, 2, 4, 5, 7}, ""},
+		{"no match", &PriorityHitPolicy{}, plain, nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.strategy.Apply(tt.table, tt.matched)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Selected, tt.want) {
+				t.Errorf("selected = %v, want %v", result.Selected, tt.want)
+			}
+			if tt.table.Aggregation != "" {
+				return
+			}
+			// The outputs are those of the selected rules, in their order
+			byIndex := map[int]map[string]interface{}{}
+			for _, m := range tt.matched {
+				byIndex[m.RuleIndex] = m.Outputs
+			}
+			for i, index := range result.Selected {
+				if !reflect.DeepEqual(result.Outputs[i], byIndex[index]) {
+					t.Errorf("output %d = %v, want that of rule %d", i, result.Outputs[i], index)
+				}
+			}
+		})
+	}
+}
+
+// priorityModel is a decision "d" ranking a risk by the output values
+// "HIGH","MEDIUM","LOW" under the given hit policy
+func priorityModel(hitPolicy string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Risk" namespace="http://example.org/risk">
+  <inputData id="in_age" name="age"><variable name="age"/></inputData>
+  <decision id="d" name="D">
+    <variable name="D"/>
+    <informationRequirement><requiredInput href="#in_age"/></informationRequirement>
+    <decisionTable hitPolicy="` + hitPolicy + `">
+      <input><inputExpression><text>age</text></inputExpression></input>
+      <output name="risk"><outputValues><text>"HIGH","MEDIUM","LOW"</text></outputValues></output>
+      <rule id="low"><inputEntry><text>-</text></inputEntry><outputEntry><text>"LOW"</text></outputEntry></rule>
+      <rule id="high"><inputEntry><text>&lt; 18</text></inputEntry><outputEntry><text>"HIGH"</text></outputEntry></rule>
+      <rule id="medium"><inputEntry><text>&lt; 30</text></inputEntry><outputEntry><text>"MEDIUM"</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+}
+
+func TestEvaluatePriority(t *testing.T) {
+	tests := []struct {
+		hitPolicy string
+		idOnly    bool // the output has an id and no name
+		age       int
+		want      interface{}
+		selected  []int
+	}{
+		{dmn.HitPolicyPriority, false, 10, "HIGH", []int{1}},
+		{dmn.HitPolicyPriority, false, 20, "MEDIUM", []int{2}},
+		{dmn.HitPolicyPriority, false, 40, "LOW", []int{0}},
+		{dmn.HitPolicyPriority, true, 10, "HIGH", []int{1}},
+		{dmn.HitPolicyOutputOrder, false, 10, []interface{}{"HIGH", "MEDIUM", "LOW"}, []int{1, 2, 0}},
+		{dmn.HitPolicyOutputOrder, false, 20, []interface{}{"MEDIUM", "LOW"}, []int{2, 0}},
+		{dmn.HitPolicyOutputOrder, true, 20, []interface{}{"MEDIUM", "LOW"}, []int{2, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hitPolicy, func(t *testing.T) {
+			model := priorityModel(tt.hitPolicy)
+			if tt.idOnly {
+				model = strings.Replace(model, `<output name="risk">`, `<output id="risk">`, 1)
+			}
+			repo := storage.NewMemoryRepository()
+			deploy(t, repo, "", model)
+			dt := explain(t, NewEngine(repo), map[string]interface{}{"age": tt.age})
+
+			if !reflect.DeepEqual(dt.Result, tt.want) {
+				t.Errorf("result = %v, want %v", dt.Result, tt.want)
+			}
+			if !reflect.DeepEqual(dt.HitPolicy.SelectedRules, tt.selected) {
+				t.Errorf("selected rules = %v, want %v", dt.HitPolicy.SelectedRules, tt.selected)
+			}
+		})
+	}
+}
+
+// lastHitPolicy is a custom hit policy returning the last matched rule
+type lastHitPolicy struct{}
+
+func (lastHitPolicy) MultiHit(*HitPolicyTable) bool { return false }
+
+func (lastHitPolicy) Apply(table *HitPolicyTable, matched []MatchedRule) (HitPolicyResult, error) {
+	if len(matched) == 0 {
+		return HitPolicyResult{}, nil
+	}
+	last := matched[len(matched)-1]
+	return HitPolicyResult{Outputs: []map[string]interface{}{last.Outputs}, Selected: []int{last.RuleIndex}}, nil
+}
+
+func TestExplainCustomHitPolicy(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", priorityModel("LAST"))
+	e := NewEngine(repo)
+	if err := e.RegisterHitPolicy("LAST", lastHitPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := e.Evaluate(context.Background(), &EvaluateRequest{DecisionKey: "d", Variables: map[string]interface{}{"age": 10}, Explain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht := res.Trace.Decisions[0].HitPolicy
+	if !reflect.DeepEqual(ht.MatchedRules, []int{0, 1, 2}) || !reflect.DeepEqual(ht.SelectedRules, []int{2}) {
+		t.Errorf("matched rules %v, selected %v, want all matched and rule 2 selected", ht.MatchedRules, ht.SelectedRules)
+	}
+	if got := res.Outputs[0]["risk"]; got != "MEDIUM" {
+		t.Errorf("risk = %v, want MEDIUM", got)
+	}
+}
@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+)
+
+// minIndexedRules is the smallest table that gets a rule index; smaller
+// tables are scanned faster than the index is queried
+const minIndexedRules = 32
+
+// ruleIndex narrows the rules of a decision table to those whose input
+// entries can match the input values. Every rule left out would neither
+// match nor fail when tested, so testing the candidates in rule order gives
+// the same result as testing every rule
+type ruleIndex struct {
+	rules   int
+	columns []columnIndex // columns that narrow at least one rule
+}
+
+// columnIndex holds the entries of one input column
+type columnIndex struct {
+	input         int
+	unconstrained bitset                // rules the column does not narrow, e.g. "-" or not(...)
+	equal         map[interface{}][]int // rules by the number, string or boolean their entry equals
+	intervals     *intervalTree         // rules by the numeric range or comparison of their entry
+}
+
+// buildRuleIndex indexes the input entries of a compiled table. It returns
+// nil if the table is small or no entry can be indexed
+func buildRuleIndex(ct *compiledTable) *ruleIndex {
+	n := len(ct.entries)
+	inputs := len(ct.inputs)
+	if n < minIndexedRules {
+		return nil
+	}
+
+	// A rule may only be left out on a column tested before any entry that
+	// could fail, as a linear scan stops at the first entry not matching
+	limits := make([]int, n)
+	for r, entries := range ct.entries {
+		limits[r] = inputs
+		for c := 0; c < len(entries) && c < inputs; c++ {
+			if !isStatic(entries[c]) {
+				limits[r] = c
+				break
+			}
+		}
+	}
+
+	ix := &ruleIndex{rules: n}
+	for c := 0; c < inputs; c++ {
+		col := columnIndex{input: c, unconstrained: newBitset(n), equal: make(map[interface{}][]int)}
+		var intervals []interval
+		narrows := false
+
+		for r, entries := range ct.entries {
+			if c >= limits[r] || c >= len(entries) {
+				col.unconstrained.set(r)
+				continue
+			}
+			keys, ivs, ok := indexEntry(entries[c])
+			if !ok {
+				col.unconstrained.set(r)
+				continue
+			}
+			narrows = true
+			for _, key := range keys {
+				col.equal[key] = append(col.equal[key], r)
+			}
+			for _, iv := range ivs {
+				iv.rule = r
+				intervals = append(intervals, iv)
+			}
+		}
+
+		if narrows {
+			col.intervals = newIntervalTree(intervals)
+			ix.columns = append(ix.columns, col)
+		}
+	}
+
+	if len(ix.columns) == 0 {
+		return nil
+	}
+	return ix
+}
+
+// candidates returns the rules that may match the input values, or nil if
+// the values cannot be looked up and every rule has to be tested
+func (ix *ruleIndex) candidates(values []interface{}) bitset {
+	set := newBitset(ix.rules)
+	set.fill(ix.rules)
+	pass := newBitset(ix.rules)
+
+	for i := range ix.columns {
+		col := &ix.columns[i]
+		copy(pass, col.unconstrained)
+
+		value := values[col.input]
+		if key, ok := indexKey(value); ok {
+			for _, r := range col.equal[key] {
+				pass.set(r)
+			}
+		}
+		if x, ok := feel.ToNumber(value); ok {
+			if math.IsNaN(x) {
+				return nil
+			}
+			col.intervals.stab(x, pass)
+		}
+
+		set.and(pass)
+		if set.empty() {
+			break
+		}
+	}
+	return set
+}
+
+// nextCandidate returns the first rule at or after i that has to be
+// tested, or -1. A nil set holds every rule of the table
+func nextCandidate(candidates bitset, i, rules int) int {
+	if candidates != nil {
+		return candidates.next(i)
+	}
+	if i < rules {
+		return i
+	}
+	return -1
+}
+
+// indexKey normalizes a value the way FEEL equality compares it
+func indexKey(v interface{}) (interface{}, bool) {
+	if x, ok := feel.ToNumber(v); ok {
+		return x, true
+	}
+	switch v.(type) {
+	case string, bool:
+		return v, true
+	}
+	return nil, false
+}
+
+// isStatic reports whether testing an input entry can never fail: it is
+// "-" or made of literal values only
+func isStatic(u unaryTests) bool {
+	if u.err != nil {
+		return false
+	}
+	if u.tests.Any {
+		return true
+	}
+	for _, t := range u.tests.Tests {
+		switch t.Kind {
+		case feel.TestRange:
+			_, ok1 := indexLiteral(t.Range.Start)
+			_, ok2 := indexLiteral(t.Range.End)
+			if !ok1 || !ok2 {
+				return false
+			}
+		default:
+			if _, ok := indexLiteral(t.Expr); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// indexEntry returns the values and intervals a static input entry matches.
+// ok is false for entries the index cannot narrow: "-", negations, string
+// ranges and inequalities
+func indexEntry(u unaryTests) (keys []interface{}, intervals []interval, ok bool) {
+	if u.err != nil || u.tests.Any || u.tests.Negated {
+		return nil, nil, false
+	}
+
+	for _, t := range u.tests.Tests {
+		switch t.Kind {
+		case feel.TestRange:
+			start, ok1 := indexLiteral(t.Range.Start)
+			end, ok2 := indexLiteral(t.Range.End)
+			from, ok3 := start.(float64)
+			to, ok4 := end.(float64)
+			if !ok1 || !ok2 || !ok3 || !ok4 {
+				return nil, nil, false
+			}
+			// An inverted range matches nothing
+			if from <= to {
+				intervals = append(intervals, interval{
+					start: from, end: to,
+					startIncluded: t.Range.StartIncluded, endIncluded: t.Range.EndIncluded,
+				})
+			}
+
+		case feel.TestComparison, feel.TestExpr:
+			v, ok := indexLiteral(t.Expr)
+			if !ok {
+				return nil, nil, false
+			}
+			op := t.Op
+			if t.Kind == feel.TestExpr {
+				op = "="
+			}
+
+			num, isNum := v.(float64)
+			switch {
+			case op == "=":
+				keys = append(keys, v)
+			case isNum && (op == "<" || op == "<="):
+				intervals = append(intervals, lessThan(num, op == "<="))
+			case isNum && (op == ">" || op == ">="):
+				intervals = append(intervals, greaterThan(num, op == ">="))
+			default:
+				return nil, nil, false
+			}
+
+		default:
+			return nil, nil, false
+		}
+	}
+	return keys, intervals, true
+}
+
+// indexLiteral returns the value of a number, string or boolean literal
+func indexLiteral(n feel.Node) (interface{}, bool) {
+	switch n := n.(type) {
+	case *feel.NumberLit:
+		return n.Value, true
+	case *feel.StringLit:
+		return n.Value, true
+	case *feel.BoolLit:
+		return n.Value, true
+	case *feel.Negation:
+		if num, ok := n.Operand.(*feel.NumberLit); ok {
+			return -num.Value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// categoryCells and amountCells are the input entries of randomTable; they
+// mix entries the index holds with ones it leaves to a full test
+var (
+	categoryCells = []string{"-", `"A"`, `"B"`, `"A","C"`, `not("B")`, `"D"`, `? != "E"`}
+	amountCells   = []string{"-", "5", "[3..8]", "(10..15)", "&gt;= 12", "&lt; 4", "2, 7", `? &gt; 6 and ? &lt; 9`, "[7.5..7.5]"}
+)
+
+// randomTable is a tableModel of the given size with random input entries;
+// the output of each rule is its index
+func randomTable(rnd *rand.Rand, hitPolicy string, rules int) string {
+	rows := make([][3]string, rules)
+	for i := range rows {
+		rows[i] = [3]string{amountCells[rnd.Intn(len(amountCells))], categoryCells[rnd.Intn(len(categoryCells))], fmt.Sprint(i)}
+	}
+	return tableModel(hitPolicy, rows...)
+}
+
+// randomInputs returns variables for a randomTable, including values no
+// entry names and missing values
+func randomInputs(rnd *rand.Rand) map[string]interface{} {
+	vars := map[string]interface{}{}
+	if rnd.Intn(10) > 0 {
+		vars["age"] = []interface{}{rnd.Intn(20), 7.5, "5"}[rnd.Intn(3)]
+	}
+	if rnd.Intn(10) > 0 {
+		vars["category"] = string(rune('A' + rnd.Intn(6)))
+	}
+	return vars
+}
+
+// evaluateJSON evaluates decision key and returns its outputs and matched
+// rules, or its error, as a string
+func evaluateJSON(ctx context.Context, e *Engine, key string, vars map[string]interface{}) string {
+	res, err := e.Evaluate(ctx, &EvaluateRequest{DecisionKey: key, Variables: vars})
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	out, _ := json.Marshal([]interface{}{res.Outputs, res.MatchedRules})
+	return string(out)
+}
+
+func TestRuleIndexEquivalence(t *testing.T) {
+	ctx := context.Background()
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, hitPolicy := range []string{"UNIQUE", "FIRST", "ANY", "PRIORITY", "RULE ORDER", "COLLECT", "COLLECT COUNT"} {
+		for _, rules := range []int{minIndexedRules, 100, 300} {
+			repo := storage.NewMemoryRepository()
+			deploy(t, repo, "", randomTable(rnd, hitPolicy, rules))
+			linear := NewEngineWithOptions(repo, Options{CacheSize: 1, DisableRuleIndex: true})
+			indexed := NewEngineWithOptions(repo, Options{CacheSize: 1})
+
+			for i := 0; i < 200; i++ {
+				vars := randomInputs(rnd)
+				want := evaluateJSON(ctx, linear, "d", vars)
+				if got := evaluateJSON(ctx, indexed, "d", vars); got != want {
+					t.Fatalf("%s, %d rules, %v:\nindexed %s\nlinear  %s", hitPolicy, rules, vars, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestRuleIndexEquivalenceTariff(t *testing.T) {
+	ctx := context.Background()
+	rnd := rand.New(rand.NewSource(1))
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", tariffTable(1000, "RULE ORDER"))
+	linear := NewEngineWithOptions(repo, Options{CacheSize: 1, DisableRuleIndex: true})
+	indexed := NewEngineWithOptions(repo, Options{CacheSize: 1})
+
+	for _, vars := range tariffInputs(rnd, 1000, 500) {
+		want := evaluateJSON(ctx, linear, "tariff", vars)
+		if got := evaluateJSON(ctx, indexed, "tariff", vars); got != want {
+			t.Fatalf("%v:\nindexed %s\nlinear  %s", vars, got, want)
+		}
+	}
+}
+
+// BenchmarkTariffTable compares linear and indexed rule matching on
+// tariff tables like the ones large deployments run
+func BenchmarkTariffTable(b *testing.B) {
+	ctx := context.Background()
+	for _, rules := range []int{100, 1000, 5000, 20000} {
+		for _, hitPolicy := range []string{"FIRST", "RULE ORDER"} {
+			repo := storage.NewMemoryRepository()
+			deploy(b, repo, "", tariffTable(rules, hitPolicy))
+			inputs := tariffInputs(rand.New(rand.NewSource(1)), rules, 1000)
+
+			for _, mode := range []struct {
+				name string
+				opts Options
+			}{
+				{"linear", Options{CacheSize: 1, DisableRuleIndex: true}},
+				{"indexed", Options{CacheSize: 1}},
+			} {
+				e := NewEngineWithOptions(repo, mode.opts)
+				name := fmt.Sprintf("rules=%d/%s/%s", rules, strings.ReplaceAll(hitPolicy, " ", "_"), mode.name)
+				b.Run(name, func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						req := &EvaluateRequest{DecisionKey: "tariff", Variables: inputs[i%len(inputs)]}
+						if _, err := e.Evaluate(ctx, req); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// bands are the amount bands of every product of a tariffTable
+var bands = [][2]int{{0, 1000}, {1000, 5000}, {5000, 20000}, {20000, 100000}, {100000, 1000000}}
+
+// tariffTable builds a table with one rule per product and amount band. A
+// fifth of the products have an extra channel-specific rule first
+func tariffTable(rules int, hitPolicy string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="tariffs" name="Tariffs" namespace="http://example.org/tariffs">
+  <decision id="tariff" name="Tariff">
+    <variable name="Tariff"/>
+    <decisionTable id="tariffTable" hitPolicy="%s">
+      <input label="Product"><inputExpression typeRef="string"><text>productCode</text></inputExpression></input>
+      <input label="Amount"><inputExpression typeRef="number"><text>amount</text></inputExpression></input>
+      <input label="Channel"><inputExpression typeRef="string"><text>channel</text></inputExpression></input>
+      <output name="rate" typeRef="number"/>
+`, hitPolicy)
+
+	n := 0
+	for p := 0; n < rules; p++ {
+		for i, band := range bands {
+			if n == rules {
+				break
+			}
+			channel := "-"
+			if p%5 == 0 && i == 0 {
+				channel = `"online"`
+			}
+			fmt.Fprintf(&b, `      <rule id="r%d"><inputEntry><text>"%s"</text></inputEntry><inputEntry><text>[%d..%d)</text></inputEntry><inputEntry><text>%s</text></inputEntry><outputEntry><text>%d.%d</text></outputEntry></rule>
+`, n, productCode(p), band[0], band[1], channel, 1+i, p%100)
+			n++
+		}
+	}
+	b.WriteString("    </decisionTable>\n  </decision>\n</definitions>\n")
+	return b.String()
+}
+
+// tariffInputs returns n random inputs for a tariffTable of the given size
+func tariffInputs(rnd *rand.Rand, rules, n int) []map[string]interface{} {
+	inputs := make([]map[string]interface{}, n)
+	for i := range inputs {
+		inputs[i] = map[string]interface{}{
+			"productCode": productCode(rnd.Intn(rules / len(bands))),
+			"amount":      rnd.Intn(1200000),
+			"channel":     []string{"online", "branch"}[rnd.Intn(2)],
+		}
+	}
+	return inputs
+}
+
+func productCode(p int) string {
+	return fmt.Sprintf("P%05d", p)
+}
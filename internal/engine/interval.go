@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"math"
+	"sort"
+)
+
+// interval is the numeric input range a rule's entry matches. Comparisons
+// such as "< 5" are open-ended intervals
+type interval struct {
+	start, end                 float64
+	startIncluded, endIncluded bool
+	rule                       int
+}
+
+// contains mirrors feel's comparison of numbers against bounds
+func (iv *interval) contains(x float64) bool {
+	if x < iv.start || (x == iv.start && !iv.startIncluded) {
+		return false
+	}
+	if x > iv.end || (x == iv.end && !iv.endIncluded) {
+		return false
+	}
+	return true
+}
+
+// lessThan returns the interval of "< v" or "<= v"
+func lessThan(v float64, included bool) interval {
+	return interval{start: math.Inf(-1), end: v, startIncluded: true, endIncluded: included}
+}
+
+// greaterThan returns the interval of "> v" or ">= v"
+func greaterThan(v float64, included bool) interval {
+	return interval{start: v, end: math.Inf(1), startIncluded: included, endIncluded: true}
+}
+
+// intervalTree is a centered interval tree answering which intervals
+// contain a point in O(log n + k)
+type intervalTree struct {
+	center      float64
+	byStart     []interval // intervals containing center, by start ascending
+	byEnd       []interval // the same intervals, by end descending
+	left, right *intervalTree
+}
+
+// newIntervalTree builds a tree of intervals whose start is not after
+// their end
+func newIntervalTree(intervals []interval) *intervalTree {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	// The median endpoint lies in at least one interval, so every node
+	// takes at least one interval and the tree is finite
+	endpoints := make([]float64, 0, 2*len(intervals))
+	for _, iv := range intervals {
+		endpoints = append(endpoints, iv.start, iv.end)
+	}
+	sort.Float64s(endpoints)
+
+	t := &intervalTree{center: endpoints[len(endpoints)/2]}
+	var left, right []interval
+	for _, iv := range intervals {
+		switch {
+		case iv.end < t.center:
+			left = append(left, iv)
+		case iv.start > t.center:
+			right = append(right, iv)
+		default:
+			t.byStart = append(t.byStart, iv)
+		}
+	}
+
+	t.byEnd = append([]interval(nil), t.byStart...)
+	sort.Slice(t.byStart, func(i, j int) bool { return t.byStart[i].start < t.byStart[j].start })
+	sort.Slice(t.byEnd, func(i, j int) bool { return t.byEnd[i].end > t.byEnd[j].end })
+	t.left = newIntervalTree(left)
+	t.right = newIntervalTree(right)
+	return t
+}
+
+// stab adds the rules of all intervals containing x to set
+func (t *intervalTree) stab(x float64, set bitset) {
+	for t != nil {
+		switch {
+		case x < t.center:
+			// Every interval here ends at or after center, so only the
+			// start bound can exclude x
+			for i := range t.byStart {
+				if t.byStart[i].start > x {
+					break
+				}
+				if t.byStart[i].contains(x) {
+					set.set(t.byStart[i].rule)
+				}
+			}
+			t = t.left
+		case x > t.center:
+			for i := range t.byEnd {
+				if t.byEnd[i].end < x {
+					break
+				}
+				if t.byEnd[i].contains(x) {
+					set.set(t.byEnd[i].rule)
+				}
+			}
+			t = t.right
+		default:
+			for i := range t.byStart {
+				if t.byStart[i].contains(x) {
+					set.set(t.byStart[i].rule)
+				}
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// slowModel is a decision "d" whose table requires the decision "slow",
+// which iterates far longer than any test deadline
+const slowModel = `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Slow" namespace="http://example.org/slow">
+  <decision id="slow" name="slow">
+    <variable name="slow"/>
+    <literalExpression><text>some i in 1..100000, j in 1..100000 satisfies i + j &lt; 0</text></literalExpression>
+  </decision>
+  <decision id="d" name="D">
+    <variable name="D"/>
+    <informationRequirement><requiredDecision href="#slow"/></informationRequirement>
+    <decisionTable>
+      <input><inputExpression><text>slow</text></inputExpression></input>
+      <output name="r"/>
+      <rule><inputEntry><text>-</text></inputEntry><outputEntry><text>1</text></outputEntry></rule>
+    </decisionTable>
+  </decision>
+</definitions>`
+
+func TestEvaluateTimeout(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", slowModel)
+	e := NewEngineWithOptions(repo, Options{Timeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	_, err := e.Evaluate(context.Background(), &EvaluateRequest{DecisionKey: "d", Explain: true})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("evaluation stopped after %v", elapsed)
+	}
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want a timeout", err)
+	}
+
+	// The trace ends at the decision that was interrupted
+	var evalErr *EvaluationError
+	if !errors.As(err, &evalErr) {
+		t.Fatalf("error %v has no trace", err)
+	}
+	decisions := evalErr.Trace.Decisions
+	if len(decisions) == 0 {
+		t.Fatal("empty trace")
+	}
+	if last := decisions[len(decisions)-1]; last.ID != "slow" || last.Error == "" {
+		t.Errorf("last traced decision = %s, error %q", last.ID, last.Error)
+	}
+}
+
+func TestEvaluateTimeoutBatch(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", slowModel)
+	e := NewEngineWithOptions(repo, Options{Timeout: 20 * time.Millisecond})
+
+	res, err := e.EvaluateBatch(context.Background(), &BatchRequest{DecisionKey: "d", Items: make([]map[string]interface{}, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each item has a deadline of its own
+	for _, item := range res.Items {
+		if !errors.Is(item.Err, ErrTimeout) {
+			t.Errorf("item %d: error = %v, want a timeout", item.Index, item.Err)
+		}
+	}
+}
+
+func TestEvaluateCanceledTable(t *testing.T) {
+	// A table of many rules, each calling a function that cancels the
+	// evaluation on its first call
+	var rules [][3]string
+	for i := 0; i < 4*cancelCheckInterval; i++ {
+		rules = append(rules, [3]string{fmt.Sprintf("tick(%d)", i), "-", "1"})
+	}
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", tableModel("COLLECT", rules...))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	functions := NewFunctionRegistry()
+	err := functions.Register("", "tick", []feel.Param{{Name: "i", Type: "number"}}, func(args []interface{}) (interface{}, error) {
+		calls++
+		cancel()
+		return args[0], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngineWithOptions(repo, Options{Functions: functions, DisableRuleIndex: true})
+
+	_, err = e.Evaluate(ctx, &EvaluateRequest{DecisionKey: "d", Variables: map[string]interface{}{"age": 1}})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrTimeout) {
+		t.Fatalf("error = %v, want a cancellation", err)
+	}
+	if calls > cancelCheckInterval {
+		t.Errorf("%d rules tested after the cancellation", calls-1)
+	}
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/konstantin/dmn-engine-go/internal/dmn"
+)
+
+// Kinds of traced elements
+const (
+	TraceKindDecision = "decision"
+	TraceKindBKM      = "businessKnowledgeModel"
+)
+
+// Trace explains an evaluation: every decision and BKM invocation in the
+// order its evaluation started. Required decisions come before the decisions
+// that require them; a BKM invocation comes after the decision invoking it
+type Trace struct {
+	Decisions []*DecisionTrace `json:"decisions"`
+}
+
+// DecisionTrace is the evaluation of one decision or BKM invocation
+type DecisionTrace struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Kind       string          `json:"kind"`
+	Namespace  string          `json:"namespace,omitempty"`  // set for decisions of imported models
+	Expression string          `json:"expression,omitempty"` // literal expression text
+	Inputs     []InputTrace    `json:"inputs,omitempty"`
+	Rules      []RuleTrace     `json:"rules,omitempty"` // rules tested, in table order; rules the rule index ruled out are not listed
+	HitPolicy  *HitPolicyTrace `json:"hitPolicy,omitempty"`
+	Result     interface{}     `json:"result"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// InputTrace is an input column of a decision table and its value
+type InputTrace struct {
+	Index      int         `json:"index"`
+	Label      string      `json:"label,omitempty"`
+	Expression string      `json:"expression"`
+	Value      interface{} `json:"value"`
+}
+
+// RuleTrace is a rule tested during evaluation. Entries after the first
+// one that did not match are listed as skipped, since they were not tested
+type RuleTrace struct {
+	RuleID    string       `json:"ruleId"`
+	RuleIndex int          `json:"ruleIndex"`
+	Matched   bool         `json:"matched"`
+	Entries   []EntryTrace `json:"entries"`
+}
+
+// EntryTrace is the test of one input entry against its input value
+type EntryTrace struct {
+	Input   int         `json:"input"` // index of the input column
+	Text    string      `json:"text"`
+	Value   interface{} `json:"value"`
+	Matched bool        `json:"matched"`
+	Skipped bool        `json:"skipped,omitempty"` // not tested, an earlier entry did not match
+	Error   string      `json:"error,omitempty"`
+}
+
+// HitPolicyTrace describes how the hit policy turned matched rules into
+// the result
+type HitPolicyTrace struct {
+	HitPolicy     string `json:"hitPolicy"`
+	Aggregation   string `json:"aggregation,omitempty"`
+	MatchedRules  []int  `json:"matchedRules"`  // indexes of the matched rules
+	SelectedRules []int  `json:"selectedRules"` // indexes of the rules the outputs come from
+	StoppedEarly  bool   `json:"stoppedEarly,omitempty"`
+	Defaulted     bool   `json:"defaulted,omitempty"`
+}
+
+// EvaluationError is returned by an evaluation with explain set; it carries
+// the trace up to the failure
+type EvaluationError struct {
+	Err   error
+	Trace *Trace
+}
+
+func (e *EvaluationError) Error() string { return e.Err.Error() }
+
+func (e *EvaluationError) Unwrap() error { return e.Err }
+
+// withTrace attaches the trace to an evaluation error
+func withTrace(err error, trace *Trace) error {
+	var evalErr *EvaluationError
+	if trace == nil || errors.As(err, &evalErr) {
+		return err
+	}
+	return &EvaluationError{Err: err, Trace: trace}
+}
+
+// add appends the trace of a decision about to be evaluated
+func (t *Trace) add(dt *DecisionTrace) *DecisionTrace {
+	if t != nil {
+		t.Decisions = append(t.Decisions, dt)
+	}
+	return dt
+}
+
+// record stores the result or the error of a decision
+func (dt *DecisionTrace) record(res *decisionResult, err error) {
+	if err != nil {
+		dt.Error = err.Error()
+		return
+	}
+	dt.Result = res.Value()
+}
+
+// recordValue stores the value or the error of a literal expression
+func (dt *DecisionTrace) recordValue(value interface{}, err error) {
+	if err != nil {
+		dt.Error = err.Error()
+		return
+	}
+	dt.Result = value
+}
+
+// newTableTrace records the input values of a decision table
+func newTableTrace(dt *DecisionTrace, table *dmn.DecisionTable, inputValues []interface{}) {
+	dt.Inputs = make([]InputTrace, len(table.Inputs))
+	for i := range table.Inputs {
+		dt.Inputs[i] = InputTrace{
+			Index:      i,
+			Label:      table.Inputs[i].Label,
+			Expression: strings.TrimSpace(table.Inputs[i].InputExpression.Text),
+			Value:      inputValues[i],
+		}
+	}
+}
+
+// newHitPolicyTrace describes the hit policy applied to the matched rules.
+// The selected rules are added once the hit policy returns them
+func newHitPolicyTrace(table *dmn.DecisionTable, hitPolicy string, matched []MatchedRule, stoppedEarly, defaulted bool) *HitPolicyTrace {
+	ht := &HitPolicyTrace{
+		HitPolicy:     hitPolicy,
+		Aggregation:   table.Aggregation,
+		MatchedRules:  make([]int, len(matched)),
+		SelectedRules: []int{},
+		StoppedEarly:  stoppedEarly,
+		Defaulted:     defaulted,
+	}
+	for i, m := range matched {
+		ht.MatchedRules[i] = m.RuleIndex
+	}
+	return ht
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// tableModel is a decision "d" with a table over the inputs age and
+// category; each rule is its two input entries and its output
+func tableModel(hitPolicy string, rules ...[3]string) string {
+	var b strings.Builder
+	b.WriteString(`<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs" name="Table" namespace="http://example.org/table">
+  <inputData id="in_age" name="age"><variable name="age"/></inputData>
+  <inputData id="in_category" name="category"><variable name="category"/></inputData>
+  <decision id="d" name="D">
+    <variable name="D"/>
+    <informationRequirement><requiredInput href="#in_age"/></informationRequirement>
+    <informationRequirement><requiredInput href="#in_category"/></informationRequirement>
+    <decisionTable hitPolicy="` + hitPolicy + `">
+      <input><inputExpression><text>age</text></inputExpression></input>
+      <input><inputExpression><text>category</text></inputExpression></input>
+      <output name="r"/>
+`)
+	for i, r := range rules {
+		fmt.Fprintf(&b, `      <rule id="r%d"><inputEntry><text>%s</text></inputEntry><inputEntry><text>%s</text></inputEntry><outputEntry><text>%s</text></outputEntry></rule>
+`, i, r[0], r[1], r[2])
+	}
+	b.WriteString(`    </decisionTable>
+  </decision>
+</definitions>`)
+	return b.String()
+}
+
+// explain evaluates decision d with a trace. It fails the test unless the
+// result equals that of an evaluation without a trace
+func explain(t *testing.T, e *Engine, variables map[string]interface{}) *DecisionTrace {
+	t.Helper()
+	ctx := context.Background()
+	res, err := e.Evaluate(ctx, &EvaluateRequest{DecisionKey: "d", Variables: variables, Explain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := e.Evaluate(ctx, &EvaluateRequest{DecisionKey: "d", Variables: variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Outputs, plain.Outputs) || !reflect.DeepEqual(res.MatchedRules, plain.MatchedRules) {
+		t.Errorf("explained outputs %v, evaluated %v", res.Outputs, plain.Outputs)
+	}
+	return res.Trace.Decisions[0]
+}
+
+func TestExplainEntries(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	deploy(t, repo, "", tableModel("FIRST",
+		[3]string{"&lt; 18", `"A"`, "1"},
+		[3]string{"-", `"A"`, "2"},
+		[3]string{"&gt;= 18", `"B"`, "3"},
+		[3]string{"&gt;= 18", "-", "4"},
+	))
+	dt := explain(t, NewEngine(repo), map[string]interface{}{"age": 25, "category": "B"})
+
+	want := []RuleTrace{
+		{RuleID: "r0", RuleIndex: 0, Entries: []EntryTrace{
+			{Input: 0, Text: "< 18", Value: 25, Matched: false},
+			{Input: 1, Text: `"A"`, Value: "B", Skipped: true},
+		}},
+		{RuleID: "r1", RuleIndex: 1, Entries: []EntryTrace{
+			{Input: 0, Text: "-", Value: 25, Matched: true},
+			{Input: 1, Text: `"A"`, Value: "B", Matched: false},
+		}},
+		{RuleID: "r2", RuleIndex: 2, Matched: true, Entries: []EntryTrace{
+			{Input: 0, Text: ">= 18", Value: 25, Matched: true},
+			{Input: 1, Text: `"B"`, Value: "B", Matched: true},
+		}},
+	}
+	if !reflect.DeepEqual(dt.Rules, want) {
+		t.Errorf("rules = %+v\nwant %+v", dt.Rules, want)
+	}
+	if !dt.HitPolicy.StoppedEarly || dt.Result != 3.0 {
+		t.Errorf("hit policy = %+v, result %v", dt.HitPolicy, dt.Result)
+	}
+}
+
+func TestExplainIndexedTable(t *testing.T) {
+	var rules [][3]string
+	for i := 0; i < 2*minIndexedRules; i++ {
+		rules = append(rules, [3]string{fmt.Sprint(i), "-", fmt.Sprint(i)})
+	}
+	rules = append(rules, [3]string{"-", `"A"`, "-1"})
+
+	tests := []struct {
+		name string
+		opts Options
+		vars map[string]interface{}
+		want []int // indexes of the traced rules
+	}{
+		{"indexed", Options{}, map[string]interface{}{"age": 7, "category": "A"}, []int{7, 2 * minIndexedRules}},
+		{"indexed by the second input", Options{}, map[string]interface{}{"age": 1000, "category": "A"}, []int{2 * minIndexedRules}},
+		{"indexed, no candidate", Options{}, map[string]interface{}{"age": 1000, "category": "B"}, []int{}},
+		{"linear", Options{DisableRuleIndex: true}, map[string]interface{}{"age": 7, "category": "A"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storage.NewMemoryRepository()
+			deploy(t, repo, "", tableModel("COLLECT", rules...))
+			dt := explain(t, NewEngineWithOptions(repo, tt.opts), tt.vars)
+
+			traced := []int{}
+			for _, r := range dt.Rules {
+				traced = append(traced, r.RuleIndex)
+			}
+			if tt.want == nil && len(traced) != len(rules) {
+				t.Fatalf("traced %d rules, want all %d", len(traced), len(rules))
+			}
+			if tt.want != nil && !reflect.DeepEqual(traced, tt.want) {
+				t.Fatalf("traced rules %v, want %v", traced, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package feel
+
+// Node is a node of a parsed FEEL expression
+type Node interface {
+	// Pos returns the byte offset of the node in the source text
+	Pos() int
+}
+
+type node struct {
+	pos int
+}
+
+func (n node) Pos() int { return n.pos }
+
+// NumberLit is a numeric literal
+type NumberLit struct {
+	node
+	Value float64
+}
+
+// StringLit is a string literal
+type StringLit struct {
+	node
+	Value string
+}
+
+// BoolLit is a boolean literal
+type BoolLit struct {
+	node
+	Value bool
+}
+
+// NullLit is the null literal
+type NullLit struct {
+	node
+}
+
+// Name is a reference to a variable, function or context entry
+type Name struct {
+	node
+	Name string
+}
+
+// InputRef is "?", the value under test in a unary test
+type InputRef struct {
+	node
+}
+
+// Binary is an arithmetic, logical or comparison operation
+type Binary struct {
+	node
+	Op    string // + - * / ** = != < <= > >= and or
+	Left  Node
+	Right Node
+}
+
+// Negation is an arithmetic negation
+type Negation struct {
+	node
+	Operand Node
+}
+
+// Between is "value between low and high"
+type Between struct {
+	node
+	Value Node
+	Low   Node
+	High  Node
+}
+
+// In is "value in tests"
+type In struct {
+	node
+	Value Node
+	Tests *UnaryTests
+}
+
+// InstanceOf is "value instance of type"
+type InstanceOf struct {
+	node
+	Value Node
+	Type  string
+}
+
+// If is a conditional expression
+type If struct {
+	node
+	Cond Node
+	Then Node
+	Else Node
+}
+
+// Iteration binds a variable to each element of a domain
+type Iteration struct {
+	Var    string
+	Domain Node
+	End    Node // set for numeric ranges "for i in 1..n"
+}
+
+// For is "for x in domain return expr"
+type For struct {
+	node
+	Iterations []Iteration
+	Return     Node
+}
+
+// Quantified is "some|every x in domain satisfies expr"
+type Quantified struct {
+	node
+	Every      bool
+	Iterations []Iteration
+	Satisfies  Node
+}
+
+// Path is a context entry access "base.name"
+type Path struct {
+	node
+	Base Node
+	Name string
+}
+
+// Filter is a list filter or index "base[expr]"
+type Filter struct {
+	node
+	Base Node
+	Expr Node
+}
+
+// Call is a function invocation with positional or named arguments
+type Call struct {
+	node
+	Callee    Node
+	Args      []Node
+	ArgNames  []string // set for named invocation
+	CalleeEnd int      // offset just past the callee, for error reporting
+}
+
+// ListLit is a list literal
+type ListLit struct {
+	node
+	Items []Node
+}
+
+// ContextEntry is a key-value pair of a context literal
+type ContextEntry struct {
+	Key   string
+	Value Node
+}
+
+// ContextLit is a context literal
+type ContextLit struct {
+	node
+	Entries []ContextEntry
+}
+
+// RangeLit is an interval literal such as [1..10] or ]0..1[
+type RangeLit struct {
+	node
+	StartIncluded bool
+	Start         Node
+	End           Node
+	EndIncluded   bool
+}
+
+// FunctionDef is an inline function definition
+type FunctionDef struct {
+	node
+	Params []string
+	Body   Node
+}
+
+// UnaryTest kinds
+const (
+	TestExpr       = iota // value equals expr, is in list/range, or expr is true when it uses "?"
+	TestComparison        // value OP expr
+	TestRange             // value within range
+)
+
+// UnaryTest is a single positive unary test
+type UnaryTest struct {
+	node
+	Kind  int
+	Op    string // for TestComparison: < <= > >= = !=
+	Expr  Node   // for TestExpr and TestComparison
+	Range *RangeLit
+}
+
+// UnaryTests is the content of an input entry
+type UnaryTests struct {
+	node
+	Any     bool // "-"
+	Negated bool // not(...)
+	Tests   []*UnaryTest
+
+	// InputVariable is an additional name of the value under test, such as
+	// Camunda's "cellInput"
+	InputVariable string
+}
+
+// Walk traverses the expression tree in depth-first order, calling fn for
+// each node. Children of a node are skipped when fn returns false
+func Walk(n Node, fn func(Node) bool) {
+	if n == nil || !fn(n) {
+		return
+	}
+
+	switch n := n.(type) {
+	case *Binary:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+	case *Negation:
+		Walk(n.Operand, fn)
+	case *Between:
+		Walk(n.Value, fn)
+		Walk(n.Low, fn)
+		Walk(n.High, fn)
+	case *In:
+		Walk(n.Value, fn)
+		Walk(n.Tests, fn)
+	case *InstanceOf:
+		Walk(n.Value, fn)
+	case *If:
+		Walk(n.Cond, fn)
+		Walk(n.Then, fn)
+		Walk(n.Else, fn)
+	case *For:
+		walkIterations(n.Iterations, fn)
+		Walk(n.Return, fn)
+	case *Quantified:
+		walkIterations(n.Iterations, fn)
+		Walk(n.Satisfies, fn)
+	case *Path:
+		Walk(n.Base, fn)
+	case *Filter:
+		Walk(n.Base, fn)
+		Walk(n.Expr, fn)
+	case *Call:
+		Walk(n.Callee, fn)
+		for _, arg := range n.Args {
+			Walk(arg, fn)
+		}
+	case *ListLit:
+		for _, item := range n.Items {
+			Walk(item, fn)
+		}
+	case *ContextLit:
+		for _, entry := range n.Entries {
+			Walk(entry.Value, fn)
+		}
+	case *RangeLit:
+		Walk(n.Start, fn)
+		Walk(n.End, fn)
+	case *FunctionDef:
+		Walk(n.Body, fn)
+	case *UnaryTests:
+		for _, test := range n.Tests {
+			Walk(test, fn)
+		}
+	case *UnaryTest:
+		if n.Range != nil {
+			Walk(n.Range, fn)
+		} else {
+			Walk(n.Expr, fn)
+		}
+	}
+}
+
+func walkIterations(iterations []Iteration, fn func(Node) bool) {
+	for _, it := range iterations {
+		Walk(it.Domain, fn)
+		if it.End != nil {
+			Walk(it.End, fn)
+		}
+	}
+}
+
+// FreeNames returns the names an expression reads from its scope, skipping
+// names bound inside it by iterations, function parameters and context
+// entries. Filter predicates are skipped as they may refer to item fields
+func FreeNames(n Node) []*Name {
+	var names []*Name
+	collectFreeNames(n, nil, &names)
+	return names
+}
+
+func collectFreeNames(n Node, bound map[string]bool, names *[]*Name) {
+	Walk(n, func(n Node) bool {
+		switch n := n.(type) {
+		case *Name:
+			if !bound[n.Name] {
+				*names = append(*names, n)
+			}
+		case *For:
+			inner := bindNames(bound)
+			collectIterations(n.Iterations, inner, names)
+			collectFreeNames(n.Return, inner, names)
+			return false
+		case *Quantified:
+			inner := bindNames(bound)
+			collectIterations(n.Iterations, inner, names)
+			collectFreeNames(n.Satisfies, inner, names)
+			return false
+		case *FunctionDef:
+			collectFreeNames(n.Body, bindNames(bound, n.Params...), names)
+			return false
+		case *Filter:
+			collectFreeNames(n.Base, bound, names)
+			return false
+		case *ContextLit:
+			inner := bindNames(bound)
+			for _, entry := range n.Entries {
+				collectFreeNames(entry.Value, inner, names)
+				inner[entry.Key] = true
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// collectIterations collects names of iteration domains; each iteration
+// variable is visible to the domains that follow it
+func collectIterations(iterations []Iteration, bound map[string]bool, names *[]*Name) {
+	for _, it := range iterations {
+		collectFreeNames(it.Domain, bound, names)
+		if it.End != nil {
+			collectFreeNames(it.End, bound, names)
+		}
+		bound[it.Var] = true
+	}
+}
+
+// bindNames returns a copy of bound with extra names added
+func bindNames(bound map[string]bool, extra ...string) map[string]bool {
+	inner := make(map[string]bool, len(bound)+len(extra))
+	for name := range bound {
+		inner[name] = true
+	}
+	for _, name := range extra {
+		inner[name] = true
+	}
+	return inner
+}
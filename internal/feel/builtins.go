@@ -0,0 +1,477 @@
+package feel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtins are the FEEL built-in functions available in every scope
+var builtins = map[string]*Function{}
+
+func init() {
+	// Boolean
+	register("not", []string{"negand"}, func(args []interface{}) (interface{}, error) {
+		if b, ok := args[0].(bool); ok {
+			return !b, nil
+		}
+		return nil, nil
+	})
+
+	// Conversion
+	register("string", []string{"from"}, func(args []interface{}) (interface{}, error) {
+		return toString(args[0]), nil
+	})
+	register("number", []string{"from"}, func(args []interface{}) (interface{}, error) {
+		if n, ok := toNumber(args[0]); ok {
+			return n, nil
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, nil
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, nil
+		}
+		return n, nil
+	})
+
+	// Date functions
+	register("date", []string{"from", "month", "day"}, func(args []interface{}) (interface{}, error) {
+		return toDate(args), nil
+	})
+
+	// String functions
+	register("string length", []string{"string"}, stringFunc(func(s string, _ []interface{}) interface{} {
+		return float64(utf8.RuneCountInString(s))
+	}))
+	register("upper case", []string{"string"}, stringFunc(func(s string, _ []interface{}) interface{} {
+		return strings.ToUpper(s)
+	}))
+	register("lower case", []string{"string"}, stringFunc(func(s string, _ []interface{}) interface{} {
+		return strings.ToLower(s)
+	}))
+	register("contains", []string{"string", "match"}, stringFunc(func(s string, args []interface{}) interface{} {
+		if m, ok := args[1].(string); ok {
+			return strings.Contains(s, m)
+		}
+		return nil
+	}))
+	register("starts with", []string{"string", "match"}, stringFunc(func(s string, args []interface{}) interface{} {
+		if m, ok := args[1].(string); ok {
+			return strings.HasPrefix(s, m)
+		}
+		return nil
+	}))
+	register("ends with", []string{"string", "match"}, stringFunc(func(s string, args []interface{}) interface{} {
+		if m, ok := args[1].(string); ok {
+			return strings.HasSuffix(s, m)
+		}
+		return nil
+	}))
+	register("substring", []string{"string", "start position", "length"}, stringFunc(substring))
+	register("matches", []string{"input", "pattern"}, stringFunc(func(s string, args []interface{}) interface{} {
+		pattern, ok := args[1].(string)
+		if !ok {
+			return nil
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil
+		}
+		return re.MatchString(s)
+	}))
+	register("replace", []string{"input", "pattern", "replacement"}, stringFunc(func(s string, args []interface{}) interface{} {
+		pattern, ok1 := args[1].(string)
+		replacement, ok2 := args[2].(string)
+		if !ok1 || !ok2 {
+			return nil
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil
+		}
+		return re.ReplaceAllString(s, strings.ReplaceAll(replacement, "$", "$$"))
+	}))
+	register("split", []string{"string", "delimiter"}, stringFunc(func(s string, args []interface{}) interface{} {
+		delim, ok := args[1].(string)
+		if !ok {
+			return nil
+		}
+		re, err := regexp.Compile(delim)
+		if err != nil {
+			return nil
+		}
+		parts := re.Split(s, -1)
+		result := make([]interface{}, len(parts))
+		for i, p := range parts {
+			result[i] = p
+		}
+		return result
+	}))
+
+	// Numeric functions
+	register("abs", []string{"n"}, numberFunc(math.Abs))
+	register("floor", []string{"n"}, numberFunc(math.Floor))
+	register("ceiling", []string{"n"}, numberFunc(math.Ceil))
+	register("sqrt", []string{"number"}, numberFunc(func(n float64) float64 {
+		return math.Sqrt(n)
+	}))
+	register("decimal", []string{"n", "scale"}, func(args []interface{}) (interface{}, error) {
+		n, ok1 := toNumber(args[0])
+		scale, ok2 := toNumber(args[1])
+		if !ok1 || !ok2 {
+			return nil, nil
+		}
+		factor := math.Pow(10, scale)
+		return math.RoundToEven(n*factor) / factor, nil
+	})
+	register("modulo", []string{"dividend", "divisor"}, func(args []interface{}) (interface{}, error) {
+		a, ok1 := toNumber(args[0])
+		b, ok2 := toNumber(args[1])
+		if !ok1 || !ok2 || b == 0 {
+			return nil, nil
+		}
+		return a - b*math.Floor(a/b), nil
+	})
+	register("odd", []string{"number"}, func(args []interface{}) (interface{}, error) {
+		n, ok := toNumber(args[0])
+		if !ok || n != math.Trunc(n) {
+			return nil, nil
+		}
+		return math.Mod(math.Abs(n), 2) == 1, nil
+	})
+	register("even", []string{"number"}, func(args []interface{}) (interface{}, error) {
+		n, ok := toNumber(args[0])
+		if !ok || n != math.Trunc(n) {
+			return nil, nil
+		}
+		return math.Mod(math.Abs(n), 2) == 0, nil
+	})
+
+	// List functions
+	registerVariadic("min", func(args []interface{}) (interface{}, error) {
+		return extremum(listArgs(args), -1), nil
+	})
+	registerVariadic("max", func(args []interface{}) (interface{}, error) {
+		return extremum(listArgs(args), 1), nil
+	})
+	registerVariadic("sum", func(args []interface{}) (interface{}, error) {
+		sum := 0.0
+		for _, item := range listArgs(args) {
+			n, ok := toNumber(item)
+			if !ok {
+				return nil, nil
+			}
+			sum += n
+		}
+		return sum, nil
+	})
+	registerVariadic("mean", func(args []interface{}) (interface{}, error) {
+		items := listArgs(args)
+		if len(items) == 0 {
+			return nil, nil
+		}
+		sum := 0.0
+		for _, item := range items {
+			n, ok := toNumber(item)
+			if !ok {
+				return nil, nil
+			}
+			sum += n
+		}
+		return sum / float64(len(items)), nil
+	})
+	registerVariadic("all", func(args []interface{}) (interface{}, error) {
+		return allItems(listArgs(args), true), nil
+	})
+	registerVariadic("any", func(args []interface{}) (interface{}, error) {
+		return allItems(listArgs(args), false), nil
+	})
+	register("count", []string{"list"}, listFunc(func(list []interface{}, _ []interface{}) interface{} {
+		return float64(len(list))
+	}))
+	register("list contains", []string{"list", "element"}, listFunc(func(list []interface{}, args []interface{}) interface{} {
+		for _, item := range list {
+			if valuesEqual(item, args[1]) {
+				return true
+			}
+		}
+		return false
+	}))
+	registerVariadic("append", func(args []interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, nil
+		}
+		list, ok := args[0].([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		result := append([]interface{}{}, list...)
+		return append(result, args[1:]...), nil
+	})
+	registerVariadic("concatenate", func(args []interface{}) (interface{}, error) {
+		result := []interface{}{}
+		for _, arg := range args {
+			list, ok := arg.([]interface{})
+			if !ok {
+				return nil, nil
+			}
+			result = append(result, list...)
+		}
+		return result, nil
+	})
+	register("reverse", []string{"list"}, listFunc(func(list []interface{}, _ []interface{}) interface{} {
+		result := make([]interface{}, len(list))
+		for i, item := range list {
+			result[len(list)-1-i] = item
+		}
+		return result
+	}))
+	register("distinct values", []string{"list"}, listFunc(func(list []interface{}, _ []interface{}) interface{} {
+		result := []interface{}{}
+		for _, item := range list {
+			seen := false
+			for _, r := range result {
+				if valuesEqual(item, r) {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				result = append(result, item)
+			}
+		}
+		return result
+	}))
+	register("flatten", []string{"list"}, listFunc(func(list []interface{}, _ []interface{}) interface{} {
+		return flatten(list)
+	}))
+	register("index of", []string{"list", "match"}, listFunc(func(list []interface{}, args []interface{}) interface{} {
+		result := []interface{}{}
+		for i, item := range list {
+			if valuesEqual(item, args[1]) {
+				result = append(result, float64(i+1))
+			}
+		}
+		return result
+	}))
+	register("sort", []string{"list", "precedes"}, func(args []interface{}) (interface{}, error) {
+		list, ok := args[0].([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		result := append([]interface{}{}, list...)
+		var sortErr error
+		if fn, ok := args[1].(*Function); ok {
+			sort.SliceStable(result, func(i, j int) bool {
+				v, err := fn.Call([]interface{}{result[i], result[j]})
+				if err != nil && sortErr == nil {
+					sortErr = err
+				}
+				return v == true
+			})
+		} else {
+			sort.SliceStable(result, func(i, j int) bool {
+				cmp, _ := compare(result[i], result[j])
+				return cmp < 0
+			})
+		}
+		return result, sortErr
+	})
+
+	// Context functions
+	register("get value", []string{"m", "key"}, func(args []interface{}) (interface{}, error) {
+		ctx, ok1 := args[0].(map[string]interface{})
+		key, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, nil
+		}
+		return ctx[key], nil
+	})
+	register("get entries", []string{"m"}, func(args []interface{}) (interface{}, error) {
+		ctx, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		result := make([]interface{}, 0, len(ctx))
+		for _, k := range sortedKeys(ctx) {
+			result = append(result, map[string]interface{}{"key": k, "value": ctx[k]})
+		}
+		return result, nil
+	})
+}
+
+// IsBuiltin reports whether name is a FEEL built-in function
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
+}
+
+// register adds a built-in function with a fixed number of parameters;
+// missing trailing arguments are passed as null
+func register(name string, params []string, call func([]interface{}) (interface{}, error)) {
+	builtins[name] = &Function{
+		Name:   name,
+		Params: params,
+		Call: func(args []interface{}) (interface{}, error) {
+			if len(args) > len(params) {
+				return nil, fmt.Errorf("expected at most %d arguments, got %d", len(params), len(args))
+			}
+			padded := make([]interface{}, len(params))
+			copy(padded, args)
+			return call(padded)
+		},
+	}
+}
+
+// registerVariadic adds a built-in function accepting any number of arguments
+func registerVariadic(name string, call func([]interface{}) (interface{}, error)) {
+	builtins[name] = &Function{Name: name, Variadic: true, Call: call}
+}
+
+func stringFunc(fn func(s string, args []interface{}) interface{}) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, nil
+		}
+		return fn(s, args), nil
+	}
+}
+
+func numberFunc(fn func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		n, ok := toNumber(args[0])
+		if !ok {
+			return nil, nil
+		}
+		return fn(n), nil
+	}
+}
+
+func listFunc(fn func(list []interface{}, args []interface{}) interface{}) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		list, ok := args[0].([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		return fn(list, args), nil
+	}
+}
+
+// listArgs accepts both f(list) and f(a, b, c) invocation styles
+func listArgs(args []interface{}) []interface{} {
+	if len(args) == 1 {
+		if list, ok := args[0].([]interface{}); ok {
+			return list
+		}
+	}
+	return args
+}
+
+// extremum returns the smallest (dir < 0) or largest (dir > 0) item
+func extremum(items []interface{}, dir int) interface{} {
+	if len(items) == 0 {
+		return nil
+	}
+	best := items[0]
+	for _, item := range items[1:] {
+		cmp, ok := compare(item, best)
+		if !ok {
+			return nil
+		}
+		if cmp*dir > 0 {
+			best = item
+		}
+	}
+	return best
+}
+
+// allItems folds booleans with "and" (all) or "or" (any): an item equal to
+// !all decides the result, otherwise any null or non-boolean item makes it
+// null. all of an empty list is true, any of it false
+func allItems(items []interface{}, all bool) interface{} {
+	unknown := false
+	for _, item := range items {
+		b, ok := item.(bool)
+		if !ok {
+			unknown = true
+			continue
+		}
+		if b != all {
+			return b
+		}
+	}
+	if unknown {
+		return nil
+	}
+	return all
+}
+
+func substring(s string, args []interface{}) interface{} {
+	runes := []rune(s)
+	startPos, ok := toNumber(args[1])
+	if !ok {
+		return nil
+	}
+
+	start := int(startPos)
+	if start < 0 {
+		start = len(runes) + start
+	} else {
+		start--
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > len(runes) {
+		return ""
+	}
+
+	end := len(runes)
+	if length, ok := toNumber(args[2]); ok {
+		if start+int(length) < end {
+			end = start + int(length)
+		}
+	}
+	if end < start {
+		return ""
+	}
+	return string(runes[start:end])
+}
+
+func flatten(list []interface{}) []interface{} {
+	result := []interface{}{}
+	for _, item := range list {
+		if nested, ok := item.([]interface{}); ok {
+			result = append(result, flatten(nested)...)
+		} else {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// toString converts a value to its FEEL string representation
+func toString(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case Date:
+		return v.String()
+	}
+	if n, ok := toNumber(v); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,186 @@
+package feel
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustDate(t *testing.T, s string) Date {
+	t.Helper()
+	d, ok := ParseDate(s)
+	if !ok {
+		t.Fatalf("invalid date %s", s)
+	}
+	return d
+}
+
+func TestBuiltins(t *testing.T) {
+	list := func(items ...interface{}) []interface{} { return items }
+
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		// Boolean and conversion
+		{`not(true)`, false},
+		{`not(1)`, nil},
+		{`string(1.5)`, "1.5"},
+		{`string(true)`, "true"},
+		{`string(null)`, nil},
+		{`number(" 42 ")`, 42.0},
+		{`number("x")`, nil},
+
+		// Strings
+		{`string length("héllo")`, 5.0},
+		{`upper case("abc")`, "ABC"},
+		{`lower case("ABC")`, "abc"},
+		{`contains("foobar", "oba")`, true},
+		{`starts with("foobar", "foo")`, true},
+		{`ends with("foobar", "foo")`, false},
+		{`substring("foobar", 3)`, "obar"},
+		{`substring("foobar", 3, 2)`, "ob"},
+		{`substring("foobar", -2, 1)`, "a"},
+		{`substring(string: "foobar", start position: 4)`, "bar"},
+		{`matches("abc123", "^[a-z]+[0-9]+$")`, true},
+		{`matches("abc", "[")`, nil},
+		{`replace("abcb", "b", "$x")`, "a$xc$x"},
+		{`split("a,b,,c", ",")`, list("a", "b", "", "c")},
+		{`upper case(1)`, nil},
+
+		// Numbers
+		{`abs(-2)`, 2.0},
+		{`floor(1.7)`, 1.0},
+		{`ceiling(1.2)`, 2.0},
+		{`sqrt(16)`, 4.0},
+		{`decimal(0.125, 2)`, 0.12}, // half to even
+		{`decimal(0.375, 2)`, 0.38},
+		{`modulo(-12, 5)`, 3.0},
+		{`modulo(12, 0)`, nil},
+		{`odd(5)`, true},
+		{`even(5)`, false},
+		{`odd(1.5)`, nil},
+
+		// Lists
+		{`min([3, 1, 2])`, 1.0},
+		{`max(3, 1, 2)`, 3.0},
+		{`min([])`, nil},
+		{`max("b", "a")`, "b"},
+		{`sum([1, 2, 3])`, 6.0},
+		{`sum([1, "a"])`, nil},
+		{`mean([1, 2, 3, 4])`, 2.5},
+		{`mean([])`, nil},
+		{`count([1, 2])`, 2.0},
+		{`list contains([1, 2], 2)`, true},
+		{`list contains([1, 2], 3)`, false},
+		{`append([1], 2, 3)`, list(1.0, 2.0, 3.0)},
+		{`concatenate([1], [], [2, 3])`, list(1.0, 2.0, 3.0)},
+		{`reverse([1, 2, 3])`, list(3.0, 2.0, 1.0)},
+		{`distinct values([1, 2, 1, "a", "a"])`, list(1.0, 2.0, "a")},
+		{`flatten([1, [2, [3]], []])`, list(1.0, 2.0, 3.0)},
+		{`index of([1, 2, 1], 1)`, list(1.0, 3.0)},
+		{`index of([1, 2], 3)`, []interface{}{}},
+		{`index of(list: [1, 2], match: 2)`, list(2.0)},
+		{`sort([3, 1, 2])`, list(1.0, 2.0, 3.0)},
+		{`sort([3, 1, 2], function(a, b) a > b)`, list(3.0, 2.0, 1.0)},
+		{`all([true, true])`, true},
+		{`all([true, false, null])`, false},
+		{`all([true, null])`, nil},
+		{`all([true, 1])`, nil},
+		{`all([])`, true},
+		{`all(true, true)`, true},
+		{`any([false, true])`, true},
+		{`any([false, null])`, nil},
+		{`any([false, false])`, false},
+		{`any([])`, false},
+
+		// Contexts
+		{`get value({a: 1}, "a")`, 1.0},
+		{`get value({a: 1}, "b")`, nil},
+		{`get entries({b: 2, a: 1})`, list(
+			map[string]interface{}{"key": "a", "value": 1.0},
+			map[string]interface{}{"key": "b", "value": 2.0},
+		)},
+
+		// Dates
+		{`date("2024-02-29").year`, 2024.0},
+		{`date("2024-02-29").month`, 2.0},
+		{`date("2024-02-29").day`, 29.0},
+		{`date("2024-02-29").weekday`, 4.0},
+		{`date("2024-03-03").weekday`, 7.0},
+		{`date("2023-02-29")`, nil},
+		{`date("24-1-1")`, nil},
+		{`date(2024, 2, 30)`, nil},
+		{`date(2024, 1.5, 1)`, nil},
+		{`date(from: "2024-01-01") = date(2024, 1, 1)`, true},
+		{`date(date("2024-01-01")) = date("2024-01-01")`, true},
+		{`date("2024-01-01") < date("2024-01-02")`, true},
+		{`date("2024-01-01") > "2024-01-02"`, nil},
+		{`date("2024-01-01") = "2024-01-01"`, false},
+		{`date("2024-01-15") in [date("2024-01-01")..date("2024-01-31")]`, true},
+		{`string(date(2024, 1, 5))`, "2024-01-05"},
+		{`date("2024-01-01") instance of date`, true},
+		{`"2024-01-01" instance of date`, false},
+		{`max([date("2024-01-01"), date("2023-12-31")]) = date("2024-01-01")`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			got, err := Evaluate(tt.src, NewScope(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateValue(t *testing.T) {
+	got, err := Evaluate(`date(2024, 3, 1)`, NewScope(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := got.(Date)
+	if !ok {
+		t.Fatalf("date(2024, 3, 1) = %#v, want a Date", got)
+	}
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !d.Time().Equal(want) {
+		t.Errorf("time = %v, want %v", d.Time(), want)
+	}
+	if d != mustDate(t, "2024-03-01") {
+		t.Errorf("%v differs from the parsed date", d)
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"due": d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"due":"2024-03-01"}` {
+		t.Errorf("JSON = %s", data)
+	}
+}
+
+func TestBuiltinArity(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`abs(1, 2)`, "expected at most 1 arguments, got 2"},
+		{`date("2024-01-01", 1, 2, 3)`, "expected at most 3 arguments, got 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Evaluate(tt.src, NewScope(nil))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.want) {
+				t.Errorf("error = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package feel
+
+import (
+	"strconv"
+	"time"
+)
+
+// dateLayout is the ISO 8601 form of a FEEL date literal
+const dateLayout = "2006-01-02"
+
+// Date is a FEEL date value: a calendar day without time or time zone
+type Date struct {
+	t time.Time // midnight UTC
+}
+
+// NewDate returns the date of the year, month and day; ok is false when
+// they do not name a valid calendar day
+func NewDate(year int, month time.Month, day int) (Date, bool) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || t.Month() != month || t.Day() != day {
+		return Date{}, false
+	}
+	return Date{t: t}, true
+}
+
+// ParseDate parses a date in the form yyyy-mm-dd
+func ParseDate(s string) (Date, bool) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, false
+	}
+	return Date{t: t}, true
+}
+
+func (d Date) Year() int         { return d.t.Year() }
+func (d Date) Month() time.Month { return d.t.Month() }
+func (d Date) Day() int          { return d.t.Day() }
+
+// Time returns the date as midnight UTC
+func (d Date) Time() time.Time { return d.t }
+
+// String returns the date in the form yyyy-mm-dd
+func (d Date) String() string { return d.t.Format(dateLayout) }
+
+// MarshalJSON encodes the date as a yyyy-mm-dd string
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// compareDates orders two dates
+func compareDates(a, b Date) int {
+	return a.t.Compare(b.t)
+}
+
+// datePart reads a property of a date, as in birthday.year
+func datePart(d Date, name string) interface{} {
+	switch name {
+	case "year":
+		return float64(d.Year())
+	case "month":
+		return float64(d.Month())
+	case "day":
+		return float64(d.Day())
+	case "weekday":
+		// ISO 8601 numbering: Monday is 1, Sunday is 7
+		wd := d.t.Weekday()
+		if wd == time.Sunday {
+			return float64(7)
+		}
+		return float64(wd)
+	}
+	return nil
+}
+
+// toDate builds a date from date(from) or date(year, month, day)
+// arguments; nil when they do not describe a valid date
+func toDate(args []interface{}) interface{} {
+	if args[1] == nil && args[2] == nil {
+		switch from := args[0].(type) {
+		case Date:
+			return from
+		case string:
+			if d, ok := ParseDate(from); ok {
+				return d
+			}
+		}
+		return nil
+	}
+
+	year, ok1 := toNumber(args[0])
+	month, ok2 := toNumber(args[1])
+	day, ok3 := toNumber(args[2])
+	if !ok1 || !ok2 || !ok3 || year != float64(int(year)) || month != float64(int(month)) || day != float64(int(day)) {
+		return nil
+	}
+	if d, ok := NewDate(int(year), time.Month(month), int(day)); ok {
+		return d
+	}
+	return nil
+}
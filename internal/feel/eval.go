@@ -0,0 +1,839 @@
+package feel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// InputName is the scope name of the value under test in unary tests ("?")
+const InputName = "?"
+
+// Eval evaluates a parsed expression. Type mismatches evaluate to null as
+// FEEL prescribes; errors are returned for invalid invocations
+func Eval(n Node, scope *Scope) (interface{}, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		return n.Value, nil
+	case *StringLit:
+		return n.Value, nil
+	case *BoolLit:
+		return n.Value, nil
+	case *NullLit:
+		return nil, nil
+
+	case *Name:
+		if v, ok := scope.Lookup(n.Name); ok {
+			return v, nil
+		}
+		if fn, ok := builtins[n.Name]; ok {
+			return fn, nil
+		}
+		return nil, nil
+
+	case *InputRef:
+		v, _ := scope.Lookup(InputName)
+		return v, nil
+
+	case *Negation:
+		v, err := Eval(n.Operand, scope)
+		if err != nil {
+			return nil, err
+		}
+		if num, ok := toNumber(v); ok {
+			return -num, nil
+		}
+		return nil, nil
+
+	case *Binary:
+		return evalBinary(n, scope)
+
+	case *Between:
+		v, err := Eval(n.Value, scope)
+		if err != nil {
+			return nil, err
+		}
+		low, err := Eval(n.Low, scope)
+		if err != nil {
+			return nil, err
+		}
+		high, err := Eval(n.High, scope)
+		if err != nil {
+			return nil, err
+		}
+		r := &Range{Start: low, End: high, StartIncluded: true, EndIncluded: true}
+		if contains, ok := r.Contains(v); ok {
+			return contains, nil
+		}
+		return nil, nil
+
+	case *In:
+		v, err := Eval(n.Value, scope)
+		if err != nil {
+			return nil, err
+		}
+		return n.Tests.Match(v, scope)
+
+	case *InstanceOf:
+		v, err := Eval(n.Value, scope)
+		if err != nil {
+			return nil, err
+		}
+		return isInstanceOf(v, n.Type), nil
+
+	case *If:
+		cond, err := Eval(n.Cond, scope)
+		if err != nil {
+			return nil, err
+		}
+		if cond == true {
+			return Eval(n.Then, scope)
+		}
+		return Eval(n.Else, scope)
+
+	case *For:
+		var results []interface{}
+		err := iterate(n.Iterations, scope, func(s *Scope) (bool, error) {
+			v, err := Eval(n.Return, s)
+			if err != nil {
+				return false, err
+			}
+			results = append(results, v)
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if results == nil {
+			results = []interface{}{}
+		}
+		return results, nil
+
+	case *Quantified:
+		result := n.Every
+		err := iterate(n.Iterations, scope, func(s *Scope) (bool, error) {
+			v, err := Eval(n.Satisfies, s)
+			if err != nil {
+				return false, err
+			}
+			if n.Every && v != true {
+				result = false
+				return false, nil
+			}
+			if !n.Every && v == true {
+				result = true
+				return false, nil
+			}
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+
+	case *Path:
+		base, err := Eval(n.Base, scope)
+		if err != nil {
+			return nil, err
+		}
+		return pathValue(base, n.Name), nil
+
+	case *Filter:
+		return evalFilter(n, scope)
+
+	case *Call:
+		return evalCall(n, scope)
+
+	case *ListLit:
+		items := make([]interface{}, len(n.Items))
+		for i, item := range n.Items {
+			v, err := Eval(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return items, nil
+
+	case *ContextLit:
+		// Later entries may refer to earlier ones
+		result := make(map[string]interface{}, len(n.Entries))
+		entryScope := scope.With(result)
+		for _, entry := range n.Entries {
+			v, err := Eval(entry.Value, entryScope)
+			if err != nil {
+				return nil, err
+			}
+			result[entry.Key] = v
+		}
+		return result, nil
+
+	case *RangeLit:
+		start, err := Eval(n.Start, scope)
+		if err != nil {
+			return nil, err
+		}
+		end, err := Eval(n.End, scope)
+		if err != nil {
+			return nil, err
+		}
+		return &Range{Start: start, End: end, StartIncluded: n.StartIncluded, EndIncluded: n.EndIncluded}, nil
+
+	case *FunctionDef:
+		return &Function{
+			Name:   "anonymous function",
+			Params: n.Params,
+			Call: func(args []interface{}) (interface{}, error) {
+				vars := make(map[string]interface{}, len(n.Params))
+				for i, param := range n.Params {
+					if i < len(args) {
+						vars[param] = args[i]
+					} else {
+						vars[param] = nil
+					}
+				}
+				return Eval(n.Body, scope.With(vars))
+			},
+		}, nil
+
+	case *UnaryTests:
+		v, _ := scope.Lookup(InputName)
+		return n.Match(v, scope)
+	}
+
+	return nil, fmt.Errorf("unsupported expression %T", n)
+}
+
+// Match tests input against the unary tests
+func (t *UnaryTests) Match(input interface{}, scope *Scope) (bool, error) {
+	if t.Any {
+		return true, nil
+	}
+
+	vars := map[string]interface{}{InputName: input}
+	if t.InputVariable != "" {
+		vars[t.InputVariable] = input
+	}
+	testScope := scope.With(vars)
+	matched := false
+	for _, test := range t.Tests {
+		ok, err := test.match(input, testScope, t.InputVariable)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched = true
+			break
+		}
+	}
+
+	if t.Negated {
+		return !matched, nil
+	}
+	return matched, nil
+}
+
+// match tests input against a single positive unary test. inputVariable is
+// the additional name of the value under test, if any
+func (t *UnaryTest) match(input interface{}, scope *Scope, inputVariable string) (bool, error) {
+	switch t.Kind {
+	case TestComparison:
+		v, err := Eval(t.Expr, scope)
+		if err != nil {
+			return false, err
+		}
+		return compareOp(t.Op, input, v) == true, nil
+
+	case TestRange:
+		v, err := Eval(t.Range, scope)
+		if err != nil {
+			return false, err
+		}
+		contains, _ := v.(*Range).Contains(input)
+		return contains, nil
+	}
+
+	v, err := Eval(t.Expr, scope)
+	if err != nil {
+		return false, err
+	}
+
+	// Boolean expressions over the input, e.g. "? > 5" or "odd(?)"
+	if usesInput(t.Expr, inputVariable) {
+		return v == true, nil
+	}
+
+	switch v := v.(type) {
+	case *Range:
+		contains, _ := v.Contains(input)
+		return contains, nil
+	case []interface{}:
+		if list, ok := input.([]interface{}); ok {
+			return valuesEqual(list, v), nil
+		}
+		for _, item := range v {
+			if valuesEqual(input, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return valuesEqual(input, v), nil
+}
+
+// usesInput reports whether an expression refers to the value under test,
+// as "?" or by its input variable name
+func usesInput(expr Node, inputVariable string) bool {
+	found := false
+	Walk(expr, func(n Node) bool {
+		if _, ok := n.(*InputRef); ok {
+			found = true
+		}
+		return !found
+	})
+	if found || inputVariable == "" {
+		return found
+	}
+	for _, name := range FreeNames(expr) {
+		if name.Name == inputVariable {
+			return true
+		}
+	}
+	return false
+}
+
+func evalBinary(n *Binary, scope *Scope) (interface{}, error) {
+	left, err := Eval(n.Left, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Three-valued logic with short-circuiting
+	switch n.Op {
+	case "and":
+		if left == false {
+			return false, nil
+		}
+		right, err := Eval(n.Right, scope)
+		if err != nil {
+			return nil, err
+		}
+		if right == false {
+			return false, nil
+		}
+		if left == true && right == true {
+			return true, nil
+		}
+		return nil, nil
+	case "or":
+		if left == true {
+			return true, nil
+		}
+		right, err := Eval(n.Right, scope)
+		if err != nil {
+			return nil, err
+		}
+		if right == true {
+			return true, nil
+		}
+		if left == false && right == false {
+			return false, nil
+		}
+		return nil, nil
+	}
+
+	right, err := Eval(n.Right, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case "=", "!=", "<", "<=", ">", ">=":
+		return compareOp(n.Op, left, right), nil
+	}
+
+	if n.Op == "+" {
+		if ls, ok := left.(string); ok {
+			if rs, ok := right.(string); ok {
+				return ls + rs, nil
+			}
+			return nil, nil
+		}
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		return nil, nil
+	}
+
+	switch n.Op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, nil
+		}
+		return l / r, nil
+	case "**":
+		return math.Pow(l, r), nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", n.Op)
+}
+
+// compareOp applies a comparison operator; incomparable operands give null
+func compareOp(op string, left, right interface{}) interface{} {
+	switch op {
+	case "=":
+		return valuesEqual(left, right)
+	case "!=":
+		return !valuesEqual(left, right)
+	}
+
+	cmp, ok := compare(left, right)
+	if !ok {
+		return nil
+	}
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return nil
+}
+
+// compare orders two numbers, two strings or two dates
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := a.(Date); ok {
+		y, ok := b.(Date)
+		if !ok {
+			return 0, false
+		}
+		return compareDates(x, y), true
+	}
+
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// valuesEqual compares FEEL values structurally
+func valuesEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		return ok && x == y
+	}
+
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case Date:
+		y, ok := b.(Date)
+		return ok && compareDates(x, y) == 0
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !valuesEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if !valuesEqual(v, y[k]) {
+				return false
+			}
+		}
+		return true
+	case *Range:
+		y, ok := b.(*Range)
+		return ok && x.StartIncluded == y.StartIncluded && x.EndIncluded == y.EndIncluded &&
+			valuesEqual(x.Start, y.Start) && valuesEqual(x.End, y.End)
+	}
+
+	return false
+}
+
+// toNumber converts any Go numeric type to float64
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// iterate binds iteration variables to every combination of domain values
+// and calls fn until it returns false
+func iterate(iterations []Iteration, scope *Scope, fn func(*Scope) (bool, error)) error {
+	if len(iterations) == 0 {
+		_, err := fn(scope)
+		return err
+	}
+
+	it := iterations[0]
+	domain, err := iterationDomain(it, scope)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range domain {
+		if err := scope.Err(); err != nil {
+			return err
+		}
+		s := scope.With(map[string]interface{}{it.Var: item})
+		if len(iterations) == 1 {
+			more, err := fn(s)
+			if err != nil || !more {
+				return err
+			}
+			continue
+		}
+
+		stopped := false
+		err := iterate(iterations[1:], s, func(inner *Scope) (bool, error) {
+			more, err := fn(inner)
+			if !more {
+				stopped = true
+			}
+			return more, err
+		})
+		if err != nil || stopped {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxRangeIteration bounds numeric ranges in for expressions
+const maxRangeIteration = 1000000
+
+// iterationDomain evaluates the values an iteration variable takes
+func iterationDomain(it Iteration, scope *Scope) ([]interface{}, error) {
+	start, err := Eval(it.Domain, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if it.End == nil {
+		if list, ok := start.([]interface{}); ok {
+			return list, nil
+		}
+		if start == nil {
+			return nil, nil
+		}
+		return []interface{}{start}, nil
+	}
+
+	end, err := Eval(it.End, scope)
+	if err != nil {
+		return nil, err
+	}
+	from, ok1 := toNumber(start)
+	to, ok2 := toNumber(end)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("iteration range bounds must be numbers")
+	}
+	if math.Abs(to-from) > maxRangeIteration {
+		return nil, fmt.Errorf("iteration range %v..%v exceeds %d elements", from, to, maxRangeIteration)
+	}
+
+	var values []interface{}
+	if from <= to {
+		for i := from; i <= to; i++ {
+			values = append(values, i)
+		}
+	} else {
+		for i := from; i >= to; i-- {
+			values = append(values, i)
+		}
+	}
+	return values, nil
+}
+
+// pathValue reads a context entry, projecting over lists
+func pathValue(base interface{}, name string) interface{} {
+	switch b := base.(type) {
+	case map[string]interface{}:
+		return b[name]
+	case []interface{}:
+		result := make([]interface{}, len(b))
+		for i, item := range b {
+			result[i] = pathValue(item, name)
+		}
+		return result
+	case Date:
+		return datePart(b, name)
+	case *Range:
+		switch name {
+		case "start":
+			return b.Start
+		case "end":
+			return b.End
+		case "start included":
+			return b.StartIncluded
+		case "end included":
+			return b.EndIncluded
+		}
+	}
+	return nil
+}
+
+// evalFilter evaluates "list[index]" or "list[condition]"
+func evalFilter(n *Filter, scope *Scope) (interface{}, error) {
+	base, err := Eval(n.Base, scope)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		return nil, nil
+	}
+
+	list, ok := base.([]interface{})
+	if !ok {
+		list = []interface{}{base}
+	}
+
+	// A numeric filter is a 1-based index, negative values count from the end
+	if index, err := Eval(n.Expr, scope); err == nil {
+		if num, ok := toNumber(index); ok {
+			i := int(num)
+			if i < 0 {
+				i = len(list) + i + 1
+			}
+			if i < 1 || i > len(list) {
+				return nil, nil
+			}
+			return list[i-1], nil
+		}
+	}
+
+	result := []interface{}{}
+	for _, item := range list {
+		if err := scope.Err(); err != nil {
+			return nil, err
+		}
+		vars := map[string]interface{}{"item": item}
+		if ctx, ok := item.(map[string]interface{}); ok {
+			for k, v := range ctx {
+				vars[k] = v
+			}
+		}
+		keep, err := Eval(n.Expr, scope.With(vars))
+		if err != nil {
+			return nil, err
+		}
+		if keep == true {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// evalCall evaluates a function invocation
+func evalCall(n *Call, scope *Scope) (interface{}, error) {
+	callee, err := Eval(n.Callee, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	fn, ok := callee.(*Function)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a function", calleeName(n.Callee))
+	}
+
+	args := make([]interface{}, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := Eval(arg, scope)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	if n.ArgNames != nil {
+		args, err = bindNamedArgs(fn, n.ArgNames, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := scope.Err(); err != nil {
+		return nil, err
+	}
+	result, err := fn.Call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name, err)
+	}
+	return result, nil
+}
+
+// bindNamedArgs orders named arguments by the function's parameters
+func bindNamedArgs(fn *Function, names []string, values []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(fn.Params))
+	for i, name := range names {
+		idx := -1
+		for j, param := range fn.Params {
+			if param == name {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("%s has no parameter %q", fn.Name, name)
+		}
+		args[idx] = values[i]
+	}
+	return args, nil
+}
+
+// calleeName renders the callee of an invocation for error messages
+func calleeName(n Node) string {
+	switch n := n.(type) {
+	case *Name:
+		return n.Name
+	case *Path:
+		return calleeName(n.Base) + "." + n.Name
+	}
+	return "expression"
+}
+
+// isInstanceOf checks a value against a FEEL type name
+func isInstanceOf(v interface{}, typeName string) bool {
+	switch typeName {
+	case "Any":
+		return v != nil
+	case "Null", "null":
+		return v == nil
+	case "number":
+		_, ok := toNumber(v)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "date":
+		_, ok := v.(Date)
+		return ok
+	case "list":
+		_, ok := v.([]interface{})
+		return ok
+	case "context":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "range":
+		_, ok := v.(*Range)
+		return ok
+	case "function":
+		_, ok := v.(*Function)
+		return ok
+	}
+	return false
+}
+
+// Conforms reports whether a value may be bound to a variable declared
+// with the given typeRef. Null conforms to every type, and typeRefs that
+// name no FEEL built-in type, such as item definitions, accept any value
+func Conforms(v interface{}, typeRef string) bool {
+	typeRef = strings.TrimPrefix(strings.TrimSpace(typeRef), "feel:")
+	switch typeRef {
+	case "number", "string", "boolean", "date", "list", "context", "range", "function":
+		return v == nil || isInstanceOf(v, typeRef)
+	}
+	return true
+}
+
+// TypeOf returns the FEEL type name of a value, for error messages
+func TypeOf(v interface{}) string {
+	for _, name := range []string{"null", "number", "string", "boolean", "date", "list", "context", "range", "function"} {
+		if isInstanceOf(v, name) {
+			return name
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// sortedKeys returns the keys of a context in a stable order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+// Package feel implements the subset of the FEEL expression language used by
+// DMN decision tables, literal expressions and business knowledge models.
+//
+// Values are represented with plain Go types: nil (null), bool, float64 (any
+// Go numeric type is accepted as input), string, Date, []interface{} (list),
+// map[string]interface{} (context), *Range and *Function.
+package feel
+
+import (
+	"context"
+	"fmt"
+)
+
+// SyntaxError is returned for malformed FEEL text
+type SyntaxError struct {
+	Pos     int // byte offset in the source text
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Message)
+}
+
+// Scope resolves names during evaluation. Scopes are chained: a name not
+// found in a scope is looked up in its parent
+type Scope struct {
+	parent *Scope
+	vars   map[string]interface{}
+	ctx    context.Context // checked by iterations and invocations; nil if not cancellable
+}
+
+// NewScope creates a root scope with the given variables
+func NewScope(vars map[string]interface{}) *Scope {
+	return &Scope{vars: vars}
+}
+
+// With returns a child scope that adds vars on top of s
+func (s *Scope) With(vars map[string]interface{}) *Scope {
+	return &Scope{parent: s, vars: vars, ctx: s.ctx}
+}
+
+// WithContext returns a child scope whose evaluation stops with the
+// context's error once ctx is done. Evaluation checks it before every
+// iteration of a for, some/every or filter and before every invocation
+func (s *Scope) WithContext(ctx context.Context) *Scope {
+	return &Scope{parent: s, ctx: ctx}
+}
+
+// Err returns the error of the scope's context once it is done
+func (s *Scope) Err() error {
+	if s.ctx == nil {
+		return nil
+	}
+	return s.ctx.Err()
+}
+
+// Lookup returns the value bound to name
+func (s *Scope) Lookup(name string) (interface{}, bool) {
+	for sc := s; sc != nil; sc = sc.parent {
+		if v, ok := sc.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Function is a callable FEEL value: a built-in, a function implemented in
+// Go, a business knowledge model or an inline function definition
+type Function struct {
+	Name      string
+	Params    []string // formal parameter names, used for named invocation
+	ParamDefs []Param  // typed parameters of a function created with NewFunction
+	Variadic  bool     // accepts any number of arguments
+	Call      func(args []interface{}) (interface{}, error)
+}
+
+// Range is an interval value such as [1..10]
+type Range struct {
+	Start         interface{}
+	End           interface{}
+	StartIncluded bool
+	EndIncluded   bool
+}
+
+// Contains reports whether v lies within the range; ok is false when v is
+// not comparable with the range bounds
+func (r *Range) Contains(v interface{}) (contains bool, ok bool) {
+	if r.Start != nil {
+		cmp, ok := compare(v, r.Start)
+		if !ok {
+			return false, false
+		}
+		if cmp < 0 || (cmp == 0 && !r.StartIncluded) {
+			return false, true
+		}
+	}
+	if r.End != nil {
+		cmp, ok := compare(v, r.End)
+		if !ok {
+			return false, false
+		}
+		if cmp > 0 || (cmp == 0 && !r.EndIncluded) {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// ToNumber converts any Go numeric type to the float64 FEEL numbers are
+// compared as
+func ToNumber(v interface{}) (float64, bool) {
+	return toNumber(v)
+}
+
+// Evaluate parses and evaluates a FEEL expression
+func Evaluate(src string, scope *Scope) (interface{}, error) {
+	expr, err := ParseExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	return Eval(expr, scope)
+}
+
+// EvaluateUnaryTests parses input entry text and tests input against it
+func EvaluateUnaryTests(src string, input interface{}, scope *Scope) (bool, error) {
+	tests, err := ParseUnaryTests(src)
+	if err != nil {
+		return false, err
+	}
+	return tests.Match(input, scope)
+}
+
+// EvaluateUnaryTestsAs is EvaluateUnaryTests with the value under test also
+// bound to name, so "name > 5" behaves like "? > 5"
+func EvaluateUnaryTestsAs(src string, input interface{}, name string, scope *Scope) (bool, error) {
+	tests, err := ParseUnaryTests(src)
+	if err != nil {
+		return false, err
+	}
+	tests.InputVariable = name
+	return tests.Match(input, scope)
+}
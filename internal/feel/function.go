@@ -0,0 +1,130 @@
+package feel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Param is a typed parameter of a function implemented in Go
+type Param struct {
+	Name string
+	Type string // FEEL type of the argument: Any, number, string, boolean, date, list, context, range or function
+
+	// Optional parameters accept null, which is also what a missing
+	// trailing argument is
+	Optional bool
+}
+
+// paramTypes are the types a parameter may declare
+var paramTypes = map[string]bool{
+	"Any": true, "number": true, "string": true, "boolean": true, "date": true,
+	"list": true, "context": true, "range": true, "function": true,
+}
+
+// NewFunction wraps a Go implementation as a FEEL function with typed
+// parameters. Invocations are checked like those of built-ins: more
+// arguments than parameters is an error, while an argument that is not an
+// instance of its parameter's type, or null for a parameter that is not
+// optional, makes the result null without calling call. Numbers are passed
+// to call as float64
+func NewFunction(name string, params []Param, call func(args []interface{}) (interface{}, error)) (*Function, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("function name is required")
+	}
+	if IsBuiltin(name) {
+		return nil, fmt.Errorf("%s is a built-in function", name)
+	}
+	if call == nil {
+		return nil, fmt.Errorf("%s: implementation is required", name)
+	}
+
+	names := make([]string, len(params))
+	seen := make(map[string]bool, len(params))
+	for i, p := range params {
+		if p.Name == "" {
+			return nil, fmt.Errorf("%s: parameter %d has no name", name, i)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("%s: duplicate parameter %q", name, p.Name)
+		}
+		if !paramTypes[p.Type] {
+			return nil, fmt.Errorf("%s: parameter %q has unknown type %q", name, p.Name, p.Type)
+		}
+		seen[p.Name] = true
+		names[i] = p.Name
+	}
+	params = append(make([]Param, 0, len(params)), params...) // non-nil, marks the function as typed
+
+	return &Function{
+		Name:      name,
+		Params:    names,
+		ParamDefs: params,
+		Call: func(args []interface{}) (interface{}, error) {
+			if len(args) > len(params) {
+				return nil, fmt.Errorf("expected at most %d arguments, got %d", len(params), len(args))
+			}
+			typed := make([]interface{}, len(params))
+			for i, p := range params {
+				var arg interface{}
+				if i < len(args) {
+					arg = args[i]
+				}
+				if arg == nil {
+					if !p.Optional {
+						return nil, nil
+					}
+					continue
+				}
+				if !isInstanceOf(arg, p.Type) {
+					return nil, nil
+				}
+				if n, ok := toNumber(arg); ok {
+					arg = n
+				}
+				typed[i] = arg
+			}
+			return call(typed)
+		},
+	}, nil
+}
+
+// CheckArguments reports why an invocation of fn with the given number of
+// positional arguments, or with the given argument names, can never
+// succeed. It returns "" when the invocation is valid or fn has no typed
+// parameters
+func CheckArguments(fn *Function, positional int, names []string) string {
+	if fn.ParamDefs == nil || fn.Variadic {
+		return ""
+	}
+
+	given := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !hasParam(fn, name) {
+			return fmt.Sprintf("%s has no parameter %q", fn.Name, name)
+		}
+		given[name] = true
+	}
+	if names == nil && positional > len(fn.ParamDefs) {
+		return fmt.Sprintf("%s expects at most %d arguments, got %d", fn.Name, len(fn.ParamDefs), positional)
+	}
+
+	for i, p := range fn.ParamDefs {
+		if p.Optional {
+			continue
+		}
+		if (names != nil && !given[p.Name]) || (names == nil && i >= positional) {
+			return fmt.Sprintf("%s: missing argument %q", fn.Name, p.Name)
+		}
+	}
+	return ""
+}
+
+func hasParam(fn *Function, name string) bool {
+	for _, param := range fn.Params {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package feel
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TokenKind is the kind of a lexical token
+type TokenKind int
+
+// Token kinds
+const (
+	TokenEOF TokenKind = iota
+	TokenNumber
+	TokenString
+	TokenName
+	TokenKeyword
+	TokenOperator
+)
+
+// Token is a lexical token with its byte offset in the source text
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+func (t Token) String() string {
+	if t.Kind == TokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.Text)
+}
+
+// keywords cannot be part of a multi-word name
+var keywords = map[string]bool{
+	"and": true, "or": true, "true": true, "false": true, "null": true,
+	"if": true, "then": true, "else": true, "for": true, "in": true, "return": true,
+	"some": true, "every": true, "satisfies": true, "between": true,
+	"instance": true, "of": true, "function": true,
+}
+
+// keywordNames are names of built-in functions that contain a keyword.
+// They are matched before the words are split at the keyword
+var keywordNames = [][]string{
+	{"index", "of"},
+}
+
+// operators are ordered so that longer operators are matched first
+var operators = []string{
+	"..", "<=", ">=", "!=", "**",
+	"(", ")", "[", "]", "{", "}", ",", ".", ":",
+	"+", "-", "*", "/", "=", "<", ">", "?",
+}
+
+// lex splits FEEL source into tokens. Consecutive words that are not
+// keywords are joined into a single name, so "string length" and
+// "Applicant Age" are names as the FEEL grammar allows
+func lex(src string) ([]Token, error) {
+	var tokens []Token
+	i := 0
+
+	for i < len(src) {
+		r := rune(src[i])
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case isDigit(src[i]) || (src[i] == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			start := i
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			// A dot followed by another dot is a range, not a fraction
+			if i < len(src) && src[i] == '.' && !(i+1 < len(src) && src[i+1] == '.') {
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Text: src[start:i], Pos: start})
+
+		case src[i] == '"':
+			start := i
+			text, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, Token{Kind: TokenString, Text: text, Pos: start})
+
+		case src[i] == '`':
+			start := i
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, &SyntaxError{Pos: start, Message: "unterminated quoted name"}
+			}
+			i = i + 1 + end + 1
+			tokens = append(tokens, Token{Kind: TokenName, Text: src[start+1 : i-1], Pos: start})
+
+		case isNameStart(r):
+			start := i
+			if name, end, ok := lexKeywordName(src, i); ok {
+				i = end
+				tokens = append(tokens, Token{Kind: TokenName, Text: name, Pos: start})
+				continue
+			}
+
+			word, end := lexWord(src, i)
+			i = end
+
+			if keywords[word] {
+				tokens = append(tokens, Token{Kind: TokenKeyword, Text: word, Pos: start})
+				continue
+			}
+
+			// Join following non-keyword words into a multi-word name
+			name := word
+			for {
+				j := i
+				for j < len(src) && (src[j] == ' ' || src[j] == '\t') {
+					j++
+				}
+				if j == i || j >= len(src) || !isNameStart(rune(src[j])) {
+					break
+				}
+				next, end := lexWord(src, j)
+				if keywords[next] {
+					break
+				}
+				name += " " + next
+				i = end
+			}
+			tokens = append(tokens, Token{Kind: TokenName, Text: name, Pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, Token{Kind: TokenOperator, Text: op, Pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &SyntaxError{Pos: i, Message: fmt.Sprintf("unexpected character %q", src[i])}
+			}
+		}
+	}
+
+	tokens = append(tokens, Token{Kind: TokenEOF, Pos: len(src)})
+	return tokens, nil
+}
+
+// lexString reads a string literal starting at the opening quote
+func lexString(src string, start int) (string, int, error) {
+	var sb strings.Builder
+	i := start + 1
+	for i < len(src) {
+		c := src[i]
+		switch c {
+		case '"':
+			return sb.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(src) {
+				return "", 0, &SyntaxError{Pos: i, Message: "unterminated escape sequence"}
+			}
+			switch src[i+1] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			default:
+				sb.WriteByte(src[i+1])
+			}
+			i += 2
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, &SyntaxError{Pos: start, Message: "unterminated string literal"}
+}
+
+// lexKeywordName reads one of keywordNames, its words separated by any
+// spaces or tabs
+func lexKeywordName(src string, start int) (string, int, bool) {
+	for _, words := range keywordNames {
+		i := start
+		matched := true
+		for w, want := range words {
+			if w > 0 {
+				j := i
+				for j < len(src) && (src[j] == ' ' || src[j] == '\t') {
+					j++
+				}
+				if j == i {
+					matched = false
+					break
+				}
+				i = j
+			}
+			word, end := lexWord(src, i)
+			if word != want {
+				matched = false
+				break
+			}
+			i = end
+		}
+		if matched {
+			return strings.Join(words, " "), i, true
+		}
+	}
+	return "", 0, false
+}
+
+// lexWord reads a single word of a name
+func lexWord(src string, start int) (string, int) {
+	i := start
+	for i < len(src) && isNamePart(rune(src[i])) {
+		i++
+	}
+	return src[start:i], i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r > unicode.MaxASCII
+}
+
+func isNamePart(r rune) bool {
+	return isNameStart(r) || unicode.IsDigit(r) || r == '\''
+}
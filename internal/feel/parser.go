@@ -0,0 +1,749 @@
+package feel
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parser is a recursive descent parser over FEEL tokens
+type parser struct {
+	tokens []Token
+	pos    int
+
+	// inRangeEnd disables filters while parsing the end of an interval,
+	// so that the "[" of "]0..1[" closes the interval
+	inRangeEnd bool
+}
+
+// ParseExpression parses a FEEL expression such as an output entry,
+// an input expression or the text of a literal expression
+func ParseExpression(src string) (Node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Kind != TokenEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	return expr, nil
+}
+
+// ParseUnaryTests parses the content of an input entry: "-", a comma
+// separated list of positive unary tests, or not(...) of such a list
+func ParseUnaryTests(src string) (*UnaryTests, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	start := p.peek().Pos
+
+	// Empty cells behave like "-"
+	if p.peek().Kind == TokenEOF {
+		return &UnaryTests{node: node{start}, Any: true}, nil
+	}
+	if p.isOp("-") && p.peekAt(1).Kind == TokenEOF {
+		return &UnaryTests{node: node{start}, Any: true}, nil
+	}
+
+	negated := false
+	if tok := p.peek(); tok.Kind == TokenName && tok.Text == "not" && p.peekAt(1).Text == "(" {
+		if closing := p.matchingClose(p.pos + 1); closing >= 0 && p.tokens[closing+1].Kind == TokenEOF {
+			negated = true
+			p.pos += 2
+		}
+	}
+
+	tests, err := p.parsePositiveTests()
+	if err != nil {
+		return nil, err
+	}
+
+	if negated {
+		if _, err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+	}
+	if tok := p.peek(); tok.Kind != TokenEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+
+	return &UnaryTests{node: node{start}, Negated: negated, Tests: tests}, nil
+}
+
+// parsePositiveTests parses comma separated positive unary tests
+func (p *parser) parsePositiveTests() ([]*UnaryTest, error) {
+	var tests []*UnaryTest
+	for {
+		test, err := p.parsePositiveTest()
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+
+		if !p.isOp(",") {
+			return tests, nil
+		}
+		p.pos++
+	}
+}
+
+// parsePositiveTest parses a comparison, an interval or an expression
+func (p *parser) parsePositiveTest() (*UnaryTest, error) {
+	tok := p.peek()
+
+	if tok.Kind == TokenOperator {
+		switch tok.Text {
+		case "<", "<=", ">", ">=", "=", "!=":
+			p.pos++
+			expr, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &UnaryTest{node: node{tok.Pos}, Kind: TestComparison, Op: tok.Text, Expr: expr}, nil
+		}
+	}
+
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := expr.(*RangeLit); ok {
+		return &UnaryTest{node: node{tok.Pos}, Kind: TestRange, Range: r}, nil
+	}
+	return &UnaryTest{node: node{tok.Pos}, Kind: TestExpr, Expr: expr}, nil
+}
+
+func (p *parser) parseExpression() (Node, error) {
+	return p.parseDisjunction()
+}
+
+func (p *parser) parseDisjunction() (Node, error) {
+	left, err := p.parseConjunction()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		tok := p.next()
+		right, err := p.parseConjunction()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{node: node{tok.Pos}, Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseConjunction() (Node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		tok := p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{node: node{tok.Pos}, Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch {
+	case tok.Kind == TokenOperator && isComparisonOp(tok.Text):
+		p.pos++
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{node: node{tok.Pos}, Op: tok.Text, Left: left, Right: right}, nil
+
+	case p.isKeyword("between"):
+		p.pos++
+		low, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expectKeyword("and"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &Between{node: node{tok.Pos}, Value: left, Low: low, High: high}, nil
+
+	case p.isKeyword("in"):
+		p.pos++
+		tests, err := p.parseInTests()
+		if err != nil {
+			return nil, err
+		}
+		return &In{node: node{tok.Pos}, Value: left, Tests: tests}, nil
+
+	case p.isKeyword("instance"):
+		p.pos++
+		if _, err := p.expectKeyword("of"); err != nil {
+			return nil, err
+		}
+		typeTok := p.next()
+		if typeTok.Kind != TokenName && !(typeTok.Kind == TokenKeyword && typeTok.Text == "null") {
+			return nil, p.errorf(typeTok, "expected type name, got %s", typeTok)
+		}
+		return &InstanceOf{node: node{tok.Pos}, Value: left, Type: typeTok.Text}, nil
+	}
+
+	return left, nil
+}
+
+// parseInTests parses the right side of "in": a single positive unary
+// test or a parenthesized list of them
+func (p *parser) parseInTests() (*UnaryTests, error) {
+	start := p.peek()
+	if p.isOp("(") {
+		if closing := p.matchingClose(p.pos); closing >= 0 && p.hasTopLevelComma(p.pos+1, closing) {
+			p.pos++
+			tests, err := p.parsePositiveTests()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return &UnaryTests{node: node{start.Pos}, Tests: tests}, nil
+		}
+	}
+
+	test, err := p.parsePositiveTest()
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryTests{node: node{start.Pos}, Tests: []*UnaryTest{test}}, nil
+}
+
+func (p *parser) parseAdditive() (Node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		tok := p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{node: node{tok.Pos}, Op: tok.Text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (Node, error) {
+	left, err := p.parseExponent()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") {
+		tok := p.next()
+		right, err := p.parseExponent()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{node: node{tok.Pos}, Op: tok.Text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseExponent() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("**") {
+		tok := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{node: node{tok.Pos}, Op: tok.Text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.isOp("-") {
+		tok := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Negation{node: node{tok.Pos}, Operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (Node, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		switch {
+		case p.isOp("."):
+			p.pos++
+			nameTok := p.next()
+			if nameTok.Kind != TokenName {
+				return nil, p.errorf(nameTok, "expected name after \".\", got %s", nameTok)
+			}
+			expr = &Path{node: node{expr.Pos()}, Base: expr, Name: nameTok.Text}
+
+		case p.isOp("[") && !p.inRangeEnd:
+			p.pos++
+			filter, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			expr = &Filter{node: node{expr.Pos()}, Base: expr, Expr: filter}
+
+		case p.isOp("("):
+			call, err := p.parseCall(expr, tok.Pos)
+			if err != nil {
+				return nil, err
+			}
+			expr = call
+
+		default:
+			return expr, nil
+		}
+	}
+}
+
+// parseCall parses the argument list of an invocation
+func (p *parser) parseCall(callee Node, calleeEnd int) (Node, error) {
+	p.pos++ // (
+	call := &Call{node: node{callee.Pos()}, Callee: callee, CalleeEnd: calleeEnd}
+
+	if p.isOp(")") {
+		p.pos++
+		return call, nil
+	}
+
+	named := p.peek().Kind == TokenName && p.peekAt(1).Text == ":"
+	for {
+		if named {
+			nameTok := p.next()
+			if nameTok.Kind != TokenName {
+				return nil, p.errorf(nameTok, "expected parameter name, got %s", nameTok)
+			}
+			if _, err := p.expectOp(":"); err != nil {
+				return nil, err
+			}
+			call.ArgNames = append(call.ArgNames, nameTok.Text)
+		}
+
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		if p.isOp(",") {
+			p.pos++
+			continue
+		}
+		if _, err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return call, nil
+	}
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+
+	switch tok.Kind {
+	case TokenNumber:
+		value, err := strconv.ParseFloat(tok.Text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %s", tok)
+		}
+		return &NumberLit{node: node{tok.Pos}, Value: value}, nil
+
+	case TokenString:
+		return &StringLit{node: node{tok.Pos}, Value: tok.Text}, nil
+
+	case TokenName:
+		return &Name{node: node{tok.Pos}, Name: tok.Text}, nil
+
+	case TokenKeyword:
+		switch tok.Text {
+		case "true", "false":
+			return &BoolLit{node: node{tok.Pos}, Value: tok.Text == "true"}, nil
+		case "null":
+			return &NullLit{node: node{tok.Pos}}, nil
+		case "if":
+			return p.parseIf(tok)
+		case "for":
+			return p.parseFor(tok)
+		case "some", "every":
+			return p.parseQuantified(tok)
+		case "function":
+			return p.parseFunctionDef(tok)
+		}
+
+	case TokenOperator:
+		switch tok.Text {
+		case "?":
+			return &InputRef{node: node{tok.Pos}}, nil
+		case "(":
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if p.isOp("..") {
+				return p.parseRangeEnd(tok, false, expr)
+			}
+			if _, err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return expr, nil
+		case "[":
+			return p.parseListOrRange(tok)
+		case "]":
+			start, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOp("..") {
+				return nil, p.errorf(p.peek(), "expected \"..\", got %s", p.peek())
+			}
+			return p.parseRangeEnd(tok, false, start)
+		case "{":
+			return p.parseContext(tok)
+		}
+	}
+
+	return nil, p.errorf(tok, "unexpected %s", tok)
+}
+
+// parseListOrRange parses "[a, b, c]" or "[a..b]" after the opening bracket
+func (p *parser) parseListOrRange(open Token) (Node, error) {
+	list := &ListLit{node: node{open.Pos}}
+	if p.isOp("]") {
+		p.pos++
+		return list, nil
+	}
+
+	first, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("..") {
+		return p.parseRangeEnd(open, true, first)
+	}
+
+	list.Items = append(list.Items, first)
+	for p.isOp(",") {
+		p.pos++
+		item, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+	}
+	if _, err := p.expectOp("]"); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// parseRangeEnd parses ".. end" and the closing bracket of an interval
+func (p *parser) parseRangeEnd(open Token, startIncluded bool, start Node) (Node, error) {
+	p.pos++ // ..
+	p.inRangeEnd = true
+	end, err := p.parseExpression()
+	p.inRangeEnd = false
+	if err != nil {
+		return nil, err
+	}
+
+	closeTok := p.next()
+	if closeTok.Kind != TokenOperator || (closeTok.Text != "]" && closeTok.Text != "[" && closeTok.Text != ")") {
+		return nil, p.errorf(closeTok, "expected end of interval, got %s", closeTok)
+	}
+
+	return &RangeLit{
+		node:          node{open.Pos},
+		StartIncluded: startIncluded,
+		Start:         start,
+		End:           end,
+		EndIncluded:   closeTok.Text == "]",
+	}, nil
+}
+
+func (p *parser) parseContext(open Token) (Node, error) {
+	ctx := &ContextLit{node: node{open.Pos}}
+	if p.isOp("}") {
+		p.pos++
+		return ctx, nil
+	}
+
+	for {
+		keyTok := p.next()
+		if keyTok.Kind != TokenName && keyTok.Kind != TokenString {
+			return nil, p.errorf(keyTok, "expected context key, got %s", keyTok)
+		}
+		if _, err := p.expectOp(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		ctx.Entries = append(ctx.Entries, ContextEntry{Key: keyTok.Text, Value: value})
+
+		if p.isOp(",") {
+			p.pos++
+			continue
+		}
+		if _, err := p.expectOp("}"); err != nil {
+			return nil, err
+		}
+		return ctx, nil
+	}
+}
+
+func (p *parser) parseIf(tok Token) (Node, error) {
+	cond, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectKeyword("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectKeyword("else"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &If{node: node{tok.Pos}, Cond: cond, Then: then, Else: els}, nil
+}
+
+// parseIterations parses "x in domain, y in domain" of for and quantified expressions
+func (p *parser) parseIterations() ([]Iteration, error) {
+	var iterations []Iteration
+	for {
+		nameTok := p.next()
+		if nameTok.Kind != TokenName {
+			return nil, p.errorf(nameTok, "expected variable name, got %s", nameTok)
+		}
+		if _, err := p.expectKeyword("in"); err != nil {
+			return nil, err
+		}
+		domain, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		it := Iteration{Var: nameTok.Text, Domain: domain}
+		if p.isOp("..") {
+			p.pos++
+			if it.End, err = p.parseExpression(); err != nil {
+				return nil, err
+			}
+		}
+		iterations = append(iterations, it)
+
+		if !p.isOp(",") {
+			return iterations, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) parseFor(tok Token) (Node, error) {
+	iterations, err := p.parseIterations()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectKeyword("return"); err != nil {
+		return nil, err
+	}
+	ret, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &For{node: node{tok.Pos}, Iterations: iterations, Return: ret}, nil
+}
+
+func (p *parser) parseQuantified(tok Token) (Node, error) {
+	iterations, err := p.parseIterations()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectKeyword("satisfies"); err != nil {
+		return nil, err
+	}
+	cond, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &Quantified{node: node{tok.Pos}, Every: tok.Text == "every", Iterations: iterations, Satisfies: cond}, nil
+}
+
+func (p *parser) parseFunctionDef(tok Token) (Node, error) {
+	if _, err := p.expectOp("("); err != nil {
+		return nil, err
+	}
+
+	fn := &FunctionDef{node: node{tok.Pos}}
+	if !p.isOp(")") {
+		for {
+			nameTok := p.next()
+			if nameTok.Kind != TokenName {
+				return nil, p.errorf(nameTok, "expected parameter name, got %s", nameTok)
+			}
+			fn.Params = append(fn.Params, nameTok.Text)
+			if !p.isOp(",") {
+				break
+			}
+			p.pos++
+		}
+	}
+	if _, err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	fn.Body = body
+	return fn, nil
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) Token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() Token {
+	tok := p.tokens[p.pos]
+	if tok.Kind != TokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isOp(op string) bool {
+	tok := p.peek()
+	return tok.Kind == TokenOperator && tok.Text == op
+}
+
+func (p *parser) isKeyword(kw string) bool {
+	tok := p.peek()
+	return tok.Kind == TokenKeyword && tok.Text == kw
+}
+
+func (p *parser) expectOp(op string) (Token, error) {
+	tok := p.next()
+	if tok.Kind != TokenOperator || tok.Text != op {
+		return tok, p.errorf(tok, "expected %q, got %s", op, tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) expectKeyword(kw string) (Token, error) {
+	tok := p.next()
+	if tok.Kind != TokenKeyword || tok.Text != kw {
+		return tok, p.errorf(tok, "expected %q, got %s", kw, tok)
+	}
+	return tok, nil
+}
+
+// matchingClose returns the index of the bracket closing the one at index open
+func (p *parser) matchingClose(open int) int {
+	depth := 0
+	for i := open; i < len(p.tokens); i++ {
+		tok := p.tokens[i]
+		if tok.Kind != TokenOperator {
+			continue
+		}
+		switch tok.Text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// hasTopLevelComma reports whether a comma appears between from and to
+// outside of any nested brackets
+func (p *parser) hasTopLevelComma(from, to int) bool {
+	depth := 0
+	for i := from; i < to; i++ {
+		tok := p.tokens[i]
+		if tok.Kind != TokenOperator {
+			continue
+		}
+		switch tok.Text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case ",":
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *parser) errorf(tok Token, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: tok.Pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func isComparisonOp(op string) bool {
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,335 @@
+package feel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sexpr renders a parsed expression as an S-expression, so tests can
+// assert the shape of the tree
+func sexpr(n Node) string {
+	switch n := n.(type) {
+	case nil:
+		return "<nil>"
+	case *NumberLit:
+		return strconv.FormatFloat(n.Value, 'f', -1, 64)
+	case *StringLit:
+		return strconv.Quote(n.Value)
+	case *BoolLit:
+		return strconv.FormatBool(n.Value)
+	case *NullLit:
+		return "null"
+	case *Name:
+		return n.Name
+	case *InputRef:
+		return "?"
+	case *Binary:
+		return fmt.Sprintf("(%s %s %s)", n.Op, sexpr(n.Left), sexpr(n.Right))
+	case *Negation:
+		return fmt.Sprintf("(neg %s)", sexpr(n.Operand))
+	case *Between:
+		return fmt.Sprintf("(between %s %s %s)", sexpr(n.Value), sexpr(n.Low), sexpr(n.High))
+	case *In:
+		return fmt.Sprintf("(in %s %s)", sexpr(n.Value), testsString(n.Tests))
+	case *InstanceOf:
+		return fmt.Sprintf("(instance-of %s %s)", sexpr(n.Value), n.Type)
+	case *If:
+		return fmt.Sprintf("(if %s %s %s)", sexpr(n.Cond), sexpr(n.Then), sexpr(n.Else))
+	case *For:
+		return fmt.Sprintf("(for %s %s)", iterationsString(n.Iterations), sexpr(n.Return))
+	case *Quantified:
+		kw := "some"
+		if n.Every {
+			kw = "every"
+		}
+		return fmt.Sprintf("(%s %s %s)", kw, iterationsString(n.Iterations), sexpr(n.Satisfies))
+	case *Path:
+		return fmt.Sprintf("(. %s %s)", sexpr(n.Base), n.Name)
+	case *Filter:
+		return fmt.Sprintf("(filter %s %s)", sexpr(n.Base), sexpr(n.Expr))
+	case *Call:
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = sexpr(arg)
+			if n.ArgNames != nil {
+				args[i] = n.ArgNames[i] + ": " + args[i]
+			}
+		}
+		return fmt.Sprintf("(call %s [%s])", sexpr(n.Callee), strings.Join(args, ", "))
+	case *ListLit:
+		items := make([]string, len(n.Items))
+		for i, item := range n.Items {
+			items[i] = sexpr(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *ContextLit:
+		entries := make([]string, len(n.Entries))
+		for i, e := range n.Entries {
+			entries[i] = e.Key + ": " + sexpr(e.Value)
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case *RangeLit:
+		open, closing := "(", ")"
+		if n.StartIncluded {
+			open = "["
+		}
+		if n.EndIncluded {
+			closing = "]"
+		}
+		return open + sexpr(n.Start) + ".." + sexpr(n.End) + closing
+	case *FunctionDef:
+		return fmt.Sprintf("(function [%s] %s)", strings.Join(n.Params, ", "), sexpr(n.Body))
+	}
+	return fmt.Sprintf("<%T>", n)
+}
+
+func iterationsString(iterations []Iteration) string {
+	parts := make([]string, len(iterations))
+	for i, it := range iterations {
+		parts[i] = it.Var + " in " + sexpr(it.Domain)
+		if it.End != nil {
+			parts[i] += ".." + sexpr(it.End)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func testsString(t *UnaryTests) string {
+	if t.Any {
+		return "-"
+	}
+	parts := make([]string, len(t.Tests))
+	for i, test := range t.Tests {
+		switch test.Kind {
+		case TestComparison:
+			parts[i] = test.Op + " " + sexpr(test.Expr)
+		case TestRange:
+			parts[i] = sexpr(test.Range)
+		default:
+			parts[i] = sexpr(test.Expr)
+		}
+	}
+	s := strings.Join(parts, ", ")
+	if t.Negated {
+		return "not(" + s + ")"
+	}
+	return s
+}
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`Applicant Age > 18`, []string{"name:Applicant Age", "op:>", "number:18"}},
+		{`string length(x)`, []string{"name:string length", "op:(", "name:x", "op:)"}},
+		{`index of(l, 2)`, []string{"name:index of", "op:(", "name:l", "op:,", "number:2", "op:)"}},
+		{`index  of`, []string{"name:index of"}},
+		{`x instance of number`, []string{"name:x", "keyword:instance", "keyword:of", "name:number"}},
+		{`a and b`, []string{"name:a", "keyword:and", "name:b"}},
+		{"`my var`", []string{"name:my var"}},
+		{`[1..10]`, []string{"op:[", "number:1", "op:..", "number:10", "op:]"}},
+		{`.5 + 1.25`, []string{"number:.5", "op:+", "number:1.25"}},
+		{`"a\"b\n"`, []string{"string:a\"b\n"}},
+		{`x ** 2 >= y`, []string{"name:x", "op:**", "number:2", "op:>=", "name:y"}},
+	}
+
+	kinds := map[TokenKind]string{
+		TokenNumber: "number", TokenString: "string", TokenName: "name",
+		TokenKeyword: "keyword", TokenOperator: "op",
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			tokens, err := lex(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tok := range tokens {
+				if tok.Kind != TokenEOF {
+					got = append(got, kinds[tok.Kind]+":"+tok.Text)
+				}
+			}
+			if strings.Join(got, " | ") != strings.Join(tt.want, " | ") {
+				t.Errorf("tokens = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`1 + 2 * 3`, `(+ 1 (* 2 3))`},
+		{`(1 + 2) * 3`, `(* (+ 1 2) 3)`},
+		{`2 ** 3 ** 2`, `(** (** 2 3) 2)`},
+		{`-x + 1`, `(+ (neg x) 1)`},
+		{`a or b and c`, `(or a (and b c))`},
+		{`a + 1 > b`, `(> (+ a 1) b)`},
+		{`x between 1 and 10`, `(between x 1 10)`},
+		{`x in [1..10]`, `(in x [1..10])`},
+		{`x in (1, 2, 3)`, `(in x 1, 2, 3)`},
+		{`x in (< 5, > 10)`, `(in x < 5, > 10)`},
+		{`x instance of string`, `(instance-of x string)`},
+		{`if a > 1 then "big" else "small"`, `(if (> a 1) "big" "small")`},
+		{`for i in 1..3 return i * 2`, `(for [i in 1..3] (* i 2))`},
+		{`for x in l, y in m return x + y`, `(for [x in l, y in m] (+ x y))`},
+		{`some x in l satisfies x > 2`, `(some [x in l] (> x 2))`},
+		{`every x in l satisfies x > 2`, `(every [x in l] (> x 2))`},
+		{`applicant.address.city`, `(. (. applicant address) city)`},
+		{`l[item > 2]`, `(filter l (> item 2))`},
+		{`l[1].name`, `(. (filter l 1) name)`},
+		{`string length("abc")`, `(call string length ["abc"])`},
+		{`index of([1, 2], 2)`, `(call index of [[1, 2], 2])`},
+		{`substring(string: "abc", start position: 2)`, `(call substring [string: "abc", start position: 2])`},
+		{`lib.score(a)`, `(call (. lib score) [a])`},
+		{`{a: 1, "b c": [1, 2]}`, `{a: 1, b c: [1, 2]}`},
+		{`{}`, `{}`},
+		{`[]`, `[]`},
+		{`]0..1[`, `(0..1)`},
+		{`(0..1]`, `(0..1]`},
+		{`[a..b)`, `[a..b)`},
+		{`function(a, b) a + b`, `(function [a, b] (+ a b))`},
+		{`null`, `null`},
+		{`true != false`, `(!= true false)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			node, err := ParseExpression(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sexpr(node); got != tt.want {
+				t.Errorf("parsed %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`1 +`, `syntax error at position 3: unexpected end of expression`},
+		{`(1 + 2`, `syntax error at position 6: expected ")", got end of expression`},
+		{`1 2`, `syntax error at position 2: unexpected "2"`},
+		{`"abc`, `syntax error at position 0: unterminated string literal`},
+		{"`abc", `syntax error at position 0: unterminated quoted name`},
+		{`a # b`, `syntax error at position 2: unexpected character '#'`},
+		{`if a then b`, `syntax error at position 11: expected "else", got end of expression`},
+		{`x instance of 5`, `syntax error at position 14: expected type name, got "5"`},
+		{`[1..2`, `syntax error at position 5: expected end of interval, got end of expression`},
+		{`{1: 2}`, `syntax error at position 1: expected context key, got "1"`},
+		{`a.`, `syntax error at position 2: expected name after ".", got end of expression`},
+		{`for 1 in l return 1`, `syntax error at position 4: expected variable name, got "1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := ParseExpression(tt.src)
+			if err == nil {
+				t.Fatal("expected a syntax error")
+			}
+			if _, ok := err.(*SyntaxError); !ok {
+				t.Errorf("error is %T, want *SyntaxError", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("error = %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUnaryTests(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{``, `-`},
+		{`-`, `-`},
+		{` - `, `-`},
+		{`< 18`, `< 18`},
+		{`>= a + 1`, `>= (+ a 1)`},
+		{`"A", "B"`, `"A", "B"`},
+		{`[18..65]`, `[18..65]`},
+		{`]0..1[, > 5`, `(0..1), > 5`},
+		{`not("A", "B")`, `not("A", "B")`},
+		{`not(x) = y`, `(= (call not [x]) y)`},
+		{`? > 5`, `(> ? 5)`},
+		{`-5`, `(neg 5)`},
+		{`date("2024-01-01")`, `(call date ["2024-01-01"])`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			ut, err := ParseUnaryTests(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := testsString(ut); got != tt.want {
+				t.Errorf("parsed %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUnaryTestsErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`<`, `syntax error at position 1: unexpected end of expression`},
+		{`"A",`, `syntax error at position 4: unexpected end of expression`},
+		{`[1..5`, `syntax error at position 5: expected end of interval, got end of expression`},
+		{`< 5 6`, `syntax error at position 4: unexpected "6"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := ParseUnaryTests(tt.src)
+			if err == nil {
+				t.Fatal("expected a syntax error")
+			}
+			if err.Error() != tt.want {
+				t.Errorf("error = %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeNames(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`a + b * a`, []string{"a", "b", "a"}},
+		{`for x in l return x + y`, []string{"l", "y"}},
+		{`some x in l satisfies x > limit`, []string{"l", "limit"}},
+		{`function(a) a + b`, []string{"b"}},
+		{`l[item > min]`, []string{"l"}}, // predicates may name item fields
+		{`customer.age`, []string{"customer"}},
+		{`string length(s)`, []string{"string length", "s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			node, err := ParseExpression(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, name := range FreeNames(node) {
+				got = append(got, name.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("free names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -54,6 +54,41 @@ func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 		`CREATE INDEX IF NOT EXISTS idx_dmn_def_key ON dmn_definitions(key)`,
 		`CREATE INDEX IF NOT EXISTS idx_dmn_def_tenant ON dmn_definitions(tenant_id) WHERE tenant_id IS NOT NULL`,
 		`CREATE INDEX IF NOT EXISTS idx_dmn_def_key_version ON dmn_definitions(key, version DESC)`,
+		// Все decisions файла адресуются по своему ID
+		`ALTER TABLE dmn_definitions ADD COLUMN IF NOT EXISTS decision_ids TEXT[] NOT NULL DEFAULT '{}'`,
+		`UPDATE dmn_definitions SET decision_ids = ARRAY(
+			SELECT e->>'ID' FROM jsonb_array_elements(
+				COALESCE(parsed_model->'Decisions', '[]'::jsonb) || COALESCE(parsed_model->'DecisionServices', '[]'::jsonb)
+			) AS e
+			UNION SELECT key
+		)
+		WHERE decision_ids = '{}'`,
+		`CREATE INDEX IF NOT EXISTS idx_dmn_def_decision_ids ON dmn_definitions USING GIN (decision_ids)`,
+		// Namespace модели для разрешения DMN imports
+		`ALTER TABLE dmn_definitions ADD COLUMN IF NOT EXISTS namespace VARCHAR(1024)`,
+		`UPDATE dmn_definitions SET namespace = NULLIF(parsed_model->>'Namespace', '') WHERE namespace IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_dmn_def_namespace ON dmn_definitions(namespace, created_at DESC) WHERE namespace IS NOT NULL`,
+		// camunda:versionTag ключевого decision
+		`ALTER TABLE dmn_definitions ADD COLUMN IF NOT EXISTS version_tag VARCHAR(255)`,
+		`CREATE INDEX IF NOT EXISTS idx_dmn_def_version_tag ON dmn_definitions(key, version_tag) WHERE version_tag IS NOT NULL`,
+		// История вычислений
+		`CREATE TABLE IF NOT EXISTS dmn_evaluation_history (
+			id            UUID PRIMARY KEY,
+			definition_id VARCHAR(64) NOT NULL,
+			decision_key  VARCHAR(255) NOT NULL,
+			decision_name VARCHAR(255),
+			version       INT NOT NULL,
+			tenant_id     VARCHAR(64),
+			inputs        JSONB NOT NULL,
+			outputs       JSONB NOT NULL,
+			matched_rules JSONB NOT NULL,
+			duration_ns   BIGINT NOT NULL,
+			request_id    VARCHAR(255),
+			evaluated_at  TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_dmn_history_key ON dmn_evaluation_history(decision_key, evaluated_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_dmn_history_evaluated_at ON dmn_evaluation_history(evaluated_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_dmn_history_rules ON dmn_evaluation_history USING GIN (matched_rules jsonb_path_ops)`,
 	}
 
 	for _, migration := range migrations {
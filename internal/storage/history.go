@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// HistoryEntry is a recorded evaluation of a decision
+type HistoryEntry struct {
+	ID           string                   `json:"id"`
+	DefinitionID string                   `json:"definitionId"`
+	DecisionKey  string                   `json:"decisionKey"`
+	DecisionName string                   `json:"decisionName,omitempty"`
+	Version      int                      `json:"version"`
+	TenantID     string                   `json:"tenantId,omitempty"`
+	Inputs       map[string]interface{}   `json:"inputs"`
+	Outputs      []map[string]interface{} `json:"outputs"`
+	MatchedRules []HistoryRule            `json:"matchedRules"`
+	DurationNs   int64                    `json:"durationNs"`
+	RequestID    string                   `json:"requestId,omitempty"`
+	EvaluatedAt  time.Time                `json:"evaluatedAt"`
+}
+
+// HistoryRule is a rule that matched in a recorded evaluation
+type HistoryRule struct {
+	RuleID    string `json:"ruleId"`
+	RuleIndex int    `json:"ruleIndex"`
+}
+
+// HistoryFilter is used to query the evaluation history. Zero fields do
+// not filter
+type HistoryFilter struct {
+	DecisionKey string
+	Version     int
+	TenantID    string
+	RuleID      string    // evaluations in which the rule matched
+	From        time.Time // evaluated at or after
+	To          time.Time // evaluated before
+	Limit       int
+	Offset      int
+}
+
+// HistoryRepository is the interface for evaluation history storage
+type HistoryRepository interface {
+	// Record saves evaluations; IDs are assigned to entries without one
+	Record(ctx context.Context, entries []*HistoryEntry) error
+
+	// Query returns evaluations matching the filter, newest first
+	Query(ctx context.Context, filter *HistoryFilter) ([]*HistoryEntry, error)
+
+	// DeleteBefore removes evaluations older than the time and returns
+	// how many were removed
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryHistoryRepository is an in-memory implementation of HistoryRepository
+type MemoryHistoryRepository struct {
+	entries []*HistoryEntry // in order of recording
+	mu      sync.RWMutex
+}
+
+// NewMemoryHistoryRepository creates a new in-memory history repository
+func NewMemoryHistoryRepository() *MemoryHistoryRepository {
+	return &MemoryHistoryRepository{}
+}
+
+// Record saves evaluations
+func (r *MemoryHistoryRepository) Record(ctx context.Context, entries []*HistoryEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range entries {
+		if e.ID == "" {
+			e.ID = uuid.New().String()
+		}
+		r.entries = append(r.entries, e)
+	}
+	return nil
+}
+
+// Query returns evaluations matching the filter, newest first
+func (r *MemoryHistoryRepository) Query(ctx context.Context, filter *HistoryFilter) ([]*HistoryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if filter == nil {
+		filter = &HistoryFilter{}
+	}
+
+	result := []*HistoryEntry{}
+	skipped := 0
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		e := r.entries[i]
+		if !filter.matches(e) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		result = append(result, e)
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// DeleteBefore removes evaluations older than the time
+func (r *MemoryHistoryRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.entries[:0]
+	for _, e := range r.entries {
+		if !e.EvaluatedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	removed := int64(len(r.entries) - len(kept))
+	for i := len(kept); i < len(r.entries); i++ {
+		r.entries[i] = nil
+	}
+	r.entries = kept
+	return removed, nil
+}
+
+// matches reports whether an entry passes the filter
+func (f *HistoryFilter) matches(e *HistoryEntry) bool {
+	if e.TenantID != f.TenantID {
+		return false
+	}
+	if f.DecisionKey != "" && e.DecisionKey != f.DecisionKey {
+		return false
+	}
+	if f.Version > 0 && e.Version != f.Version {
+		return false
+	}
+	if !f.From.IsZero() && e.EvaluatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.EvaluatedAt.Before(f.To) {
+		return false
+	}
+	if f.RuleID != "" {
+		for _, rule := range e.MatchedRules {
+			if rule.RuleID == f.RuleID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresHistoryRepository реализует HistoryRepository с PostgreSQL
+type PostgresHistoryRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresHistoryRepository создаёт новый PostgreSQL репозиторий истории
+func NewPostgresHistoryRepository(pool *pgxpool.Pool) *PostgresHistoryRepository {
+	return &PostgresHistoryRepository{pool: pool}
+}
+
+// Record сохраняет вычисления одним batch-запросом
+func (r *PostgresHistoryRepository) Record(ctx context.Context, entries []*HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, e := range entries {
+		if e.ID == "" {
+			e.ID = uuid.New().String()
+		}
+
+		inputs, err := json.Marshal(e.Inputs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal inputs: %w", err)
+		}
+		outputs, err := json.Marshal(e.Outputs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal outputs: %w", err)
+		}
+		matchedRules, err := json.Marshal(historyRules(e.MatchedRules))
+		if err != nil {
+			return fmt.Errorf("failed to marshal matched rules: %w", err)
+		}
+
+		batch.Queue(`
+			INSERT INTO dmn_evaluation_history (id, definition_id, decision_key, decision_name, version, tenant_id, inputs, outputs, matched_rules, duration_ns, request_id, evaluated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, e.ID, e.DefinitionID, e.DecisionKey, e.DecisionName, e.Version, nullableString(e.TenantID), inputs, outputs, matchedRules, e.DurationNs, nullableString(e.RequestID), e.EvaluatedAt)
+	}
+
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert history: %w", err)
+	}
+	return nil
+}
+
+// Query возвращает вычисления по фильтру, новые первыми
+func (r *PostgresHistoryRepository) Query(ctx context.Context, filter *HistoryFilter) ([]*HistoryEntry, error) {
+	if filter == nil {
+		filter = &HistoryFilter{}
+	}
+
+	query, args, err := historyQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*HistoryEntry{}
+	for rows.Next() {
+		e, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// historyQuery строит запрос истории по фильтру
+func historyQuery(filter *HistoryFilter) (string, []interface{}, error) {
+	query := `
+		SELECT id, definition_id, decision_key, decision_name, version, tenant_id, inputs, outputs, matched_rules, duration_ns, request_id, evaluated_at
+		FROM dmn_evaluation_history
+		WHERE ((tenant_id = $1) OR ($1 IS NULL AND tenant_id IS NULL))
+	`
+	args := []interface{}{nullableString(filter.TenantID)}
+	argNum := 2
+
+	if filter.DecisionKey != "" {
+		query += fmt.Sprintf(" AND decision_key = $%d", argNum)
+		args = append(args, filter.DecisionKey)
+		argNum++
+	}
+	if filter.Version > 0 {
+		query += fmt.Sprintf(" AND version = $%d", argNum)
+		args = append(args, filter.Version)
+		argNum++
+	}
+	if filter.RuleID != "" {
+		// Использует GIN-индекс по matched_rules. Элемент содержит только
+		// ruleId: с ruleIndex он совпал бы лишь с правилом под индексом 0
+		rule, err := json.Marshal([]map[string]string{{"ruleId": filter.RuleID}})
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal rule filter: %w", err)
+		}
+		query += fmt.Sprintf(" AND matched_rules @> $%d::jsonb", argNum)
+		args = append(args, rule)
+		argNum++
+	}
+	if !filter.From.IsZero() {
+		query += fmt.Sprintf(" AND evaluated_at >= $%d", argNum)
+		args = append(args, filter.From)
+		argNum++
+	}
+	if !filter.To.IsZero() {
+		query += fmt.Sprintf(" AND evaluated_at < $%d", argNum)
+		args = append(args, filter.To)
+	}
+
+	query += " ORDER BY evaluated_at DESC"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", filter.Offset)
+	}
+	return query, args, nil
+}
+
+// DeleteBefore удаляет вычисления старше указанного времени
+func (r *PostgresHistoryRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM dmn_evaluation_history WHERE evaluated_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete history: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// scanHistoryEntry сканирует строку истории
+func scanHistoryEntry(rows pgx.Rows) (*HistoryEntry, error) {
+	var e HistoryEntry
+	var inputs, outputs, matchedRules []byte
+	var decisionName, tenantID, requestID *string
+
+	err := rows.Scan(
+		&e.ID,
+		&e.DefinitionID,
+		&e.DecisionKey,
+		&decisionName,
+		&e.Version,
+		&tenantID,
+		&inputs,
+		&outputs,
+		&matchedRules,
+		&e.DurationNs,
+		&requestID,
+		&e.EvaluatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan history entry: %w", err)
+	}
+
+	if decisionName != nil {
+		e.DecisionName = *decisionName
+	}
+	if tenantID != nil {
+		e.TenantID = *tenantID
+	}
+	if requestID != nil {
+		e.RequestID = *requestID
+	}
+
+	if err := json.Unmarshal(inputs, &e.Inputs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal inputs: %w", err)
+	}
+	if err := json.Unmarshal(outputs, &e.Outputs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outputs: %w", err)
+	}
+	if err := json.Unmarshal(matchedRules, &e.MatchedRules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal matched rules: %w", err)
+	}
+
+	return &e, nil
+}
+
+// historyRules заменяет nil на пустой список, чтобы в JSONB не попал null
+func historyRules(rules []HistoryRule) []HistoryRule {
+	if rules == nil {
+		return []HistoryRule{}
+	}
+	return rules
+}
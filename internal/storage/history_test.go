@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryRuleFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryHistoryRepository()
+	now := time.Now()
+	err := repo.Record(ctx, []*HistoryEntry{
+		{ID: "1", DecisionKey: "d", MatchedRules: []HistoryRule{{RuleID: "r0", RuleIndex: 0}}, EvaluatedAt: now},
+		{ID: "2", DecisionKey: "d", MatchedRules: []HistoryRule{{RuleID: "r1", RuleIndex: 1}, {RuleID: "r2", RuleIndex: 2}}, EvaluatedAt: now},
+		{ID: "3", DecisionKey: "d", EvaluatedAt: now},
+		{ID: "4", DecisionKey: "d", TenantID: "acme", MatchedRules: []HistoryRule{{RuleID: "r2", RuleIndex: 2}}, EvaluatedAt: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter HistoryFilter
+		want   string // IDs of the returned entries, newest first
+	}{
+		{HistoryFilter{}, "3,2,1"},
+		{HistoryFilter{RuleID: "r0"}, "1"},
+		{HistoryFilter{RuleID: "r2"}, "2"},
+		{HistoryFilter{RuleID: "r2", TenantID: "acme"}, "4"},
+		{HistoryFilter{RuleID: "r9"}, ""},
+	}
+	for _, tt := range tests {
+		entries, err := repo.Query(ctx, &tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%+v: entries %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryQueryRuleFilter(t *testing.T) {
+	query, args, err := historyQuery(&HistoryFilter{DecisionKey: "d", RuleID: "r2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "matched_rules @> $3::jsonb") {
+		t.Errorf("query has no rule filter:\n%s", query)
+	}
+	if len(args) != 3 {
+		t.Fatalf("args = %v", args)
+	}
+	// Only the rule ID is matched, so the rule may be at any index
+	if got, want := string(args[2].([]byte)), `[{"ruleId":"r2"}]`; got != want {
+		t.Errorf("rule filter = %s, want %s", got, want)
+	}
+}
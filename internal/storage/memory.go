@@ -64,7 +64,7 @@ func (r *MemoryRepository) GetByKey(ctx context.Context, key string, tenantID st
 	}
 
 	if latest == nil {
-		return nil, fmt.Errorf("definition not found: key=%s, tenantId=%s", key, tenantID)
+		return nil, fmt.Errorf("%w: key=%s, tenantId=%s", ErrNotFound, key, tenantID)
 	}
 
 	return latest, nil
@@ -78,12 +78,91 @@ func (r *MemoryRepository) GetByKeyAndVersion(ctx context.Context, key string, v
 	storageKey := makeStorageKey(tenantID, key, version)
 	def, ok := r.definitions[storageKey]
 	if !ok {
-		return nil, fmt.Errorf("definition not found: key=%s, version=%d, tenantId=%s", key, version, tenantID)
+		return nil, fmt.Errorf("%w: key=%s, version=%d, tenantId=%s", ErrNotFound, key, version, tenantID)
 	}
 
 	return def, nil
 }
 
+// GetByKeyAndVersionTag returns the latest version of a definition with the version tag
+func (r *MemoryRepository) GetByKeyAndVersionTag(ctx context.Context, key string, versionTag string, tenantID string) (*Definition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *Definition
+	for _, d := range r.definitions {
+		if d.Key == key && d.TenantID == tenantID && d.VersionTag == versionTag {
+			if latest == nil || d.Version > latest.Version {
+				latest = d
+			}
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("%w: key=%s, versionTag=%s, tenantId=%s", ErrNotFound, key, versionTag, tenantID)
+	}
+
+	return latest, nil
+}
+
+// GetByDecisionID returns the most recently deployed definition containing the decision
+func (r *MemoryRepository) GetByDecisionID(ctx context.Context, decisionID string, tenantID string) (*Definition, error) {
+	return r.findByDecisionID(decisionID, 0, tenantID)
+}
+
+// GetByDecisionIDAndVersion returns the definition version containing the decision
+func (r *MemoryRepository) GetByDecisionIDAndVersion(ctx context.Context, decisionID string, version int, tenantID string) (*Definition, error) {
+	return r.findByDecisionID(decisionID, version, tenantID)
+}
+
+// findByDecisionID returns the newest definition containing the decision;
+// a zero version matches any version
+func (r *MemoryRepository) findByDecisionID(decisionID string, version int, tenantID string) (*Definition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *Definition
+	for _, d := range r.definitions {
+		if d.TenantID != tenantID || (version > 0 && d.Version != version) {
+			continue
+		}
+		if !containsString(d.DecisionIDs, decisionID) {
+			continue
+		}
+		if latest == nil || d.CreatedAt.After(latest.CreatedAt) {
+			latest = d
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("%w: decisionId=%s, version=%d, tenantId=%s", ErrNotFound, decisionID, version, tenantID)
+	}
+
+	return latest, nil
+}
+
+// GetByNamespace returns the most recently deployed definition with the model namespace
+func (r *MemoryRepository) GetByNamespace(ctx context.Context, namespace string, tenantID string) (*Definition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *Definition
+	for _, d := range r.definitions {
+		if d.TenantID != tenantID || d.Namespace != namespace {
+			continue
+		}
+		if latest == nil || d.CreatedAt.After(latest.CreatedAt) {
+			latest = d
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("%w: namespace=%s, tenantId=%s", ErrNotFound, namespace, tenantID)
+	}
+
+	return latest, nil
+}
+
 // List returns a list of definitions matching the filter
 func (r *MemoryRepository) List(ctx context.Context, filter *ListFilter) ([]*Definition, error) {
 	r.mu.RLock()
@@ -98,6 +177,9 @@ func (r *MemoryRepository) List(ctx context.Context, filter *ListFilter) ([]*Def
 			if filter.Key != "" && d.Key != filter.Key {
 				continue
 			}
+			if filter.VersionTag != "" && d.VersionTag != filter.VersionTag {
+				continue
+			}
 			if filter.TenantID != "" && d.TenantID != filter.TenantID {
 				continue
 			}
@@ -148,7 +230,7 @@ func (r *MemoryRepository) Delete(ctx context.Context, key string, tenantID stri
 	}
 
 	if len(keysToDelete) == 0 {
-		return fmt.Errorf("definition not found: key=%s, tenantId=%s", key, tenantID)
+		return fmt.Errorf("%w: key=%s, tenantId=%s", ErrNotFound, key, tenantID)
 	}
 
 	for _, k := range keysToDelete {
@@ -161,3 +243,12 @@ func (r *MemoryRepository) Delete(ctx context.Context, key string, tenantID stri
 func makeStorageKey(tenantID, key string, version int) string {
 	return fmt.Sprintf("%s:%s:%d", tenantID, key, version)
 }
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
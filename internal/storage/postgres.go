@@ -55,9 +55,9 @@ func (r *PostgresRepository) Deploy(ctx context.Context, def *Definition) error
 
 	// Сохраняем
 	_, err = r.pool.Exec(ctx, `
-		INSERT INTO dmn_definitions (id, key, version, name, source, parsed_model, checksum, tenant_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, def.ID, def.Key, def.Version, def.Name, def.Source, parsedJSON, def.Checksum, nullableString(def.TenantID), def.CreatedAt)
+		INSERT INTO dmn_definitions (id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`, def.ID, def.Key, def.Version, def.Name, def.Source, parsedJSON, def.Checksum, decisionIDs(def), nullableString(def.Namespace), nullableString(def.VersionTag), nullableString(def.TenantID), def.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to insert definition: %w", err)
 	}
@@ -68,7 +68,7 @@ func (r *PostgresRepository) Deploy(ctx context.Context, def *Definition) error
 // GetByKey возвращает последнюю версию definition
 func (r *PostgresRepository) GetByKey(ctx context.Context, key string, tenantID string) (*Definition, error) {
 	row := r.pool.QueryRow(ctx, `
-		SELECT id, key, version, name, source, parsed_model, checksum, tenant_id, created_at
+		SELECT id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at
 		FROM dmn_definitions
 		WHERE key = $1 AND (
 			(tenant_id = $2) OR 
@@ -85,7 +85,7 @@ func (r *PostgresRepository) GetByKey(ctx context.Context, key string, tenantID
 // GetByKeyAndVersion возвращает конкретную версию definition
 func (r *PostgresRepository) GetByKeyAndVersion(ctx context.Context, key string, version int, tenantID string) (*Definition, error) {
 	row := r.pool.QueryRow(ctx, `
-		SELECT id, key, version, name, source, parsed_model, checksum, tenant_id, created_at
+		SELECT id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at
 		FROM dmn_definitions
 		WHERE key = $1 AND version = $2 AND (
 			(tenant_id = $3) OR 
@@ -97,12 +97,80 @@ func (r *PostgresRepository) GetByKeyAndVersion(ctx context.Context, key string,
 	return r.scanDefinition(row)
 }
 
+// GetByKeyAndVersionTag возвращает последнюю версию definition с указанным versionTag
+func (r *PostgresRepository) GetByKeyAndVersionTag(ctx context.Context, key string, versionTag string, tenantID string) (*Definition, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at
+		FROM dmn_definitions
+		WHERE key = $1 AND version_tag = $2 AND (
+			(tenant_id = $3) OR 
+			($3 IS NULL AND tenant_id IS NULL) OR
+			($3 = '' AND tenant_id IS NULL)
+		)
+		ORDER BY version DESC
+		LIMIT 1
+	`, key, versionTag, nullableString(tenantID))
+
+	return r.scanDefinition(row)
+}
+
+// GetByDecisionID возвращает последнюю задеплоенную definition, содержащую decision
+func (r *PostgresRepository) GetByDecisionID(ctx context.Context, decisionID string, tenantID string) (*Definition, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at
+		FROM dmn_definitions
+		WHERE $1 = ANY(decision_ids) AND (
+			(tenant_id = $2) OR 
+			($2 IS NULL AND tenant_id IS NULL) OR
+			($2 = '' AND tenant_id IS NULL)
+		)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, decisionID, nullableString(tenantID))
+
+	return r.scanDefinition(row)
+}
+
+// GetByDecisionIDAndVersion возвращает версию definition, содержащую decision
+func (r *PostgresRepository) GetByDecisionIDAndVersion(ctx context.Context, decisionID string, version int, tenantID string) (*Definition, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at
+		FROM dmn_definitions
+		WHERE $1 = ANY(decision_ids) AND version = $2 AND (
+			(tenant_id = $3) OR 
+			($3 IS NULL AND tenant_id IS NULL) OR
+			($3 = '' AND tenant_id IS NULL)
+		)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, decisionID, version, nullableString(tenantID))
+
+	return r.scanDefinition(row)
+}
+
+// GetByNamespace возвращает последнюю задеплоенную definition с указанным namespace
+func (r *PostgresRepository) GetByNamespace(ctx context.Context, namespace string, tenantID string) (*Definition, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at
+		FROM dmn_definitions
+		WHERE namespace = $1 AND (
+			(tenant_id = $2) OR 
+			($2 IS NULL AND tenant_id IS NULL) OR
+			($2 = '' AND tenant_id IS NULL)
+		)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, namespace, nullableString(tenantID))
+
+	return r.scanDefinition(row)
+}
+
 // List возвращает список definitions
 func (r *PostgresRepository) List(ctx context.Context, filter *ListFilter) ([]*Definition, error) {
 	// Строим запрос с latest версией для каждого key
 	query := `
 		SELECT DISTINCT ON (key, tenant_id) 
-			id, key, version, name, source, parsed_model, checksum, tenant_id, created_at
+			id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at
 		FROM dmn_definitions
 		WHERE 1=1
 	`
@@ -115,6 +183,11 @@ func (r *PostgresRepository) List(ctx context.Context, filter *ListFilter) ([]*D
 			args = append(args, filter.Key)
 			argNum++
 		}
+		if filter.VersionTag != "" {
+			query += fmt.Sprintf(" AND version_tag = $%d", argNum)
+			args = append(args, filter.VersionTag)
+			argNum++
+		}
 		if filter.TenantID != "" {
 			query += fmt.Sprintf(" AND tenant_id = $%d", argNum)
 			args = append(args, filter.TenantID)
@@ -168,7 +241,7 @@ func (r *PostgresRepository) Delete(ctx context.Context, key string, tenantID st
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("definition not found: key=%s, tenantId=%s", key, tenantID)
+		return fmt.Errorf("%w: key=%s, tenantId=%s", ErrNotFound, key, tenantID)
 	}
 
 	return nil
@@ -177,7 +250,7 @@ func (r *PostgresRepository) Delete(ctx context.Context, key string, tenantID st
 // GetAllVersions возвращает все версии definition
 func (r *PostgresRepository) GetAllVersions(ctx context.Context, key string, tenantID string) ([]*Definition, error) {
 	rows, err := r.pool.Query(ctx, `
-		SELECT id, key, version, name, source, parsed_model, checksum, tenant_id, created_at
+		SELECT id, key, version, name, source, parsed_model, checksum, decision_ids, namespace, version_tag, tenant_id, created_at
 		FROM dmn_definitions
 		WHERE key = $1 AND (tenant_id = $2 OR ($2 = '' AND tenant_id IS NULL))
 		ORDER BY version DESC
@@ -203,7 +276,7 @@ func (r *PostgresRepository) GetAllVersions(ctx context.Context, key string, ten
 func (r *PostgresRepository) scanDefinition(row pgx.Row) (*Definition, error) {
 	var def Definition
 	var parsedJSON []byte
-	var tenantID *string
+	var namespace, versionTag, tenantID *string
 
 	err := row.Scan(
 		&def.ID,
@@ -213,17 +286,26 @@ func (r *PostgresRepository) scanDefinition(row pgx.Row) (*Definition, error) {
 		&def.Source,
 		&parsedJSON,
 		&def.Checksum,
+		&def.DecisionIDs,
+		&namespace,
+		&versionTag,
 		&tenantID,
 		&def.CreatedAt,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, fmt.Errorf("definition not found")
+		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan definition: %w", err)
 	}
 
+	if namespace != nil {
+		def.Namespace = *namespace
+	}
+	if versionTag != nil {
+		def.VersionTag = *versionTag
+	}
 	if tenantID != nil {
 		def.TenantID = *tenantID
 	}
@@ -242,7 +324,7 @@ func (r *PostgresRepository) scanDefinition(row pgx.Row) (*Definition, error) {
 func (r *PostgresRepository) scanDefinitionFromRows(rows pgx.Rows) (*Definition, error) {
 	var def Definition
 	var parsedJSON []byte
-	var tenantID *string
+	var namespace, versionTag, tenantID *string
 
 	err := rows.Scan(
 		&def.ID,
@@ -252,6 +334,9 @@ func (r *PostgresRepository) scanDefinitionFromRows(rows pgx.Rows) (*Definition,
 		&def.Source,
 		&parsedJSON,
 		&def.Checksum,
+		&def.DecisionIDs,
+		&namespace,
+		&versionTag,
 		&tenantID,
 		&def.CreatedAt,
 	)
@@ -259,6 +344,12 @@ func (r *PostgresRepository) scanDefinitionFromRows(rows pgx.Rows) (*Definition,
 		return nil, fmt.Errorf("failed to scan definition: %w", err)
 	}
 
+	if namespace != nil {
+		def.Namespace = *namespace
+	}
+	if versionTag != nil {
+		def.VersionTag = *versionTag
+	}
 	if tenantID != nil {
 		def.TenantID = *tenantID
 	}
@@ -272,6 +363,14 @@ func (r *PostgresRepository) scanDefinitionFromRows(rows pgx.Rows) (*Definition,
 	return &def, nil
 }
 
+// decisionIDs возвращает ID decisions для записи; key всегда входит в список
+func decisionIDs(def *Definition) []string {
+	if len(def.DecisionIDs) == 0 {
+		return []string{def.Key}
+	}
+	return def.DecisionIDs
+}
+
 func nullableString(s string) interface{} {
 	if s == "" {
 		return nil
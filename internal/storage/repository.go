@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/konstantin/dmn-engine-go/internal/dmn"
@@ -13,19 +14,26 @@ type Definition struct {
 	Key         string           `json:"key"`
 	Version     int              `json:"version"`
 	Name        string           `json:"name"`
-	Source      string           `json:"source"`      // Original XML
-	ParsedModel *dmn.Definitions `json:"parsedModel"` // Parsed DMN model
-	Checksum    string           `json:"checksum"`    // SHA256 of source
+	Source      string           `json:"source"`               // Original XML
+	ParsedModel *dmn.Definitions `json:"parsedModel"`          // Parsed DMN model
+	Checksum    string           `json:"checksum"`             // SHA256 of source
+	DecisionIDs []string         `json:"decisionIds"`          // IDs of all decisions and decision services in the model
+	Namespace   string           `json:"namespace,omitempty"`  // Namespace of the model, used to resolve imports
+	VersionTag  string           `json:"versionTag,omitempty"` // camunda:versionTag of the key decision
 	TenantID    string           `json:"tenantId,omitempty"`
 	CreatedAt   time.Time        `json:"createdAt"`
 }
 
+// ErrNotFound is returned when a definition or decision does not exist
+var ErrNotFound = errors.New("definition not found")
+
 // ListFilter is used to filter definitions
 type ListFilter struct {
-	Key      string
-	TenantID string
-	Limit    int
-	Offset   int
+	Key        string
+	VersionTag string
+	TenantID   string
+	Limit      int
+	Offset     int
 }
 
 // DefinitionRepository is the interface for definition storage
@@ -39,10 +47,21 @@ type DefinitionRepository interface {
 	// GetByKeyAndVersion returns a specific version of a definition
 	GetByKeyAndVersion(ctx context.Context, key string, version int, tenantID string) (*Definition, error)
 
+	// GetByKeyAndVersionTag returns the latest version of a definition with the version tag
+	GetByKeyAndVersionTag(ctx context.Context, key string, versionTag string, tenantID string) (*Definition, error)
+
+	// GetByDecisionID returns the most recently deployed definition containing the decision
+	GetByDecisionID(ctx context.Context, decisionID string, tenantID string) (*Definition, error)
+
+	// GetByDecisionIDAndVersion returns the definition version containing the decision
+	GetByDecisionIDAndVersion(ctx context.Context, decisionID string, version int, tenantID string) (*Definition, error)
+
+	// GetByNamespace returns the most recently deployed definition with the model namespace
+	GetByNamespace(ctx context.Context, namespace string, tenantID string) (*Definition, error)
+
 	// List returns a list of definitions matching the filter
 	List(ctx context.Context, filter *ListFilter) ([]*Definition, error)
 
 	// Delete deletes all versions of a definition
 	Delete(ctx context.Context, key string, tenantID string) error
 }
-
@@ -0,0 +1,105 @@
+// Package dmn parses, validates and evaluates DMN models in-process. It is
+// the library form of the engine behind the HTTP server: models come from
+// a Source the caller provides, such as a MemorySource, and no database or
+// HTTP stack is involved.
+//
+//	defs, err := dmn.Parse(xml)
+//	source := dmn.NewMemorySource()
+//	model := source.Add(dmn.NewModel(defs, ""))
+//	engine := dmn.New(source)
+//	if err := engine.Compile(ctx, model); err != nil { ... }
+//	result, err := engine.Evaluate(ctx, &dmn.EvaluateRequest{
+//		DecisionKey: "eligibility",
+//		Variables:   map[string]interface{}{"age": 25},
+//	})
+package dmn
+
+import (
+	"bytes"
+	"fmt"
+
+	idmn "github.com/konstantin/dmn-engine-go/internal/dmn"
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+)
+
+// Parsed models and parse errors
+type (
+	Definitions  = idmn.Definitions
+	ParseOptions = idmn.ParseOptions
+	Limits       = idmn.Limits
+	LimitError   = idmn.LimitError
+	SchemaError  = idmn.SchemaError
+)
+
+// Validation findings
+type (
+	ValidationError = idmn.ValidationError
+	Severity        = idmn.Severity
+	ImportResolver  = idmn.ImportResolver
+)
+
+// Severities of validation findings
+const (
+	SeverityError   = idmn.SeverityError
+	SeverityWarning = idmn.SeverityWarning
+	SeverityInfo    = idmn.SeverityInfo
+)
+
+// Parse parses DMN XML of any supported DMN version
+func Parse(data []byte) (*Definitions, error) {
+	return ParseWithOptions(data, ParseOptions{})
+}
+
+// ParseWithOptions parses DMN XML, optionally checking it against the DMN
+// schema and within size limits
+func ParseWithOptions(data []byte, opts ParseOptions) (*Definitions, error) {
+	return idmn.NewParserWithOptions(opts).Parse(bytes.NewReader(data))
+}
+
+// ValidateOptions configures Validate
+type ValidateOptions struct {
+	// Functions are the Go functions the model may call besides the
+	// built-ins, usually FunctionRegistry.Functions of the tenant
+	Functions map[string]*feel.Function
+
+	// HitPolicies are custom hit policies decision tables may use, usually
+	// Engine.HitPolicies
+	HitPolicies []string
+
+	// Imports resolves imported namespaces to their models. Imports are
+	// not checked when it is nil
+	Imports ImportResolver
+}
+
+// Validate runs the deploy checks and lint rules on a parsed model. The
+// findings include warnings and infos; see Blocking
+func Validate(defs *Definitions, opts ValidateOptions) []ValidationError {
+	validator := idmn.NewValidatorWithOptions(idmn.ValidatorOptions{
+		Functions:   opts.Functions,
+		HitPolicies: opts.HitPolicies,
+	})
+	findings := validator.Validate(defs)
+	if opts.Imports != nil {
+		findings = append(findings, validator.ValidateImports(defs, opts.Imports)...)
+	}
+	return findings
+}
+
+// Blocking returns the findings that prevent deployment: all errors, and
+// warnings or infos whose code is listed in blockingCodes
+func Blocking(findings []ValidationError, blockingCodes []string) []ValidationError {
+	return idmn.Blocking(findings, blockingCodes)
+}
+
+// ValidationFailedError is returned when a model has blocking findings
+type ValidationFailedError struct {
+	Findings []ValidationError // all findings, blocking or not
+}
+
+func (e *ValidationFailedError) Error() string {
+	blocking := Blocking(e.Findings, nil)
+	if len(blocking) == 0 {
+		return "DMN validation failed"
+	}
+	return fmt.Sprintf("DMN validation failed: %v (%d blocking findings)", blocking[0], len(blocking))
+}
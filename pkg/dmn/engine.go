@@ -0,0 +1,194 @@
+package dmn
+
+import (
+	"context"
+	"time"
+
+	"github.com/konstantin/dmn-engine-go/internal/engine"
+	"github.com/konstantin/dmn-engine-go/internal/feel"
+)
+
+// Evaluation requests and results
+type (
+	EvaluateRequest = engine.EvaluateRequest
+	EvaluateResult  = engine.EvaluateResult
+	RuleMatch       = engine.RuleMatch
+	RuleAnnotation  = engine.RuleAnnotation
+	BatchRequest    = engine.BatchRequest
+	BatchItem       = engine.BatchItem
+	BatchResult     = engine.BatchResult
+	CacheStats      = engine.CacheStats
+)
+
+// Explain traces, returned when EvaluateRequest.Explain is set
+type (
+	Trace           = engine.Trace
+	DecisionTrace   = engine.DecisionTrace
+	InputTrace      = engine.InputTrace
+	RuleTrace       = engine.RuleTrace
+	EntryTrace      = engine.EntryTrace
+	HitPolicyTrace  = engine.HitPolicyTrace
+	EvaluationError = engine.EvaluationError
+)
+
+// ImportError is returned when a model imported by the evaluated one is
+// not found or cannot be read
+type ImportError = engine.ImportError
+
+// Go functions callable from FEEL
+type (
+	FunctionRegistry = engine.FunctionRegistry
+	Function         = feel.Function
+	Param            = feel.Param
+)
+
+// Custom hit policies, registered with Engine.RegisterHitPolicy
+type (
+	HitPolicyStrategy = engine.HitPolicyStrategy
+	HitPolicyTable    = engine.HitPolicyTable
+	OutputColumn      = engine.OutputColumn
+	MatchedRule       = engine.MatchedRule
+	HitPolicyResult   = engine.HitPolicyResult
+)
+
+// NewFunctionRegistry creates an empty registry, to be shared by engines
+// through Options.Functions
+func NewFunctionRegistry() *FunctionRegistry {
+	return engine.NewFunctionRegistry()
+}
+
+// ErrTimeout is returned, together with context.DeadlineExceeded, when an
+// evaluation does not finish before its deadline
+var ErrTimeout = engine.ErrTimeout
+
+// DefaultCacheSize is the number of compiled models kept by New
+const DefaultCacheSize = engine.DefaultCacheSize
+
+// Options configures an Engine
+type Options struct {
+	// CacheSize is the number of compiled models kept in memory. Zero
+	// disables the cache
+	CacheSize int
+
+	// LatestTTL bounds how long a resolution of the latest version is
+	// trusted; it covers changes to the source the engine is not told
+	// about. Zero means no expiry
+	LatestTTL time.Duration
+
+	// Timeout bounds every evaluation, including each item of a batch.
+	// Zero means no limit beyond the caller's context
+	Timeout time.Duration
+
+	// DisableRuleIndex tests every rule of a decision table in order
+	// instead of looking up candidate rules in the table's index
+	DisableRuleIndex bool
+
+	// Functions are the Go functions FEEL expressions may call. Nil gives
+	// the engine an empty registry of its own
+	Functions *FunctionRegistry
+}
+
+// Engine evaluates decisions of the models of a Source. Compiled models are
+// cached, so an engine is meant to live as long as its source. An Engine is
+// safe for concurrent use
+type Engine struct {
+	source Source
+	engine *engine.Engine
+}
+
+// New creates an engine with a cache of DefaultCacheSize compiled models
+func New(source Source) *Engine {
+	return NewWithOptions(source, Options{CacheSize: DefaultCacheSize})
+}
+
+// NewWithOptions creates an engine with the given options
+func NewWithOptions(source Source, opts Options) *Engine {
+	return &Engine{
+		source: source,
+		engine: engine.NewEngineWithOptions(sourceAdapter{source: source}, engine.Options{
+			CacheSize:        opts.CacheSize,
+			LatestTTL:        opts.LatestTTL,
+			Timeout:          opts.Timeout,
+			DisableRuleIndex: opts.DisableRuleIndex,
+			Functions:        opts.Functions,
+		}),
+	}
+}
+
+// Evaluate evaluates a decision or decision service. Evaluation stops when
+// ctx is done or the engine's timeout is exceeded
+func (e *Engine) Evaluate(ctx context.Context, req *EvaluateRequest) (*EvaluateResult, error) {
+	return e.engine.Evaluate(ctx, req)
+}
+
+// EvaluateBatch evaluates one decision for every item of the request on a
+// bounded pool of workers. Failed items do not fail the batch
+func (e *Engine) EvaluateBatch(ctx context.Context, req *BatchRequest) (*BatchResult, error) {
+	return e.engine.EvaluateBatch(ctx, req)
+}
+
+// Compile validates a model of the engine's source against the engine's
+// functions and the models it imports, then parses its FEEL into the cache
+// so its first evaluation does not pay for it. A model with blocking
+// findings fails with a *ValidationFailedError
+func (e *Engine) Compile(ctx context.Context, model *Model) error {
+	findings := Validate(model.Definitions, ValidateOptions{
+		Functions:   e.engine.Functions().Functions(model.TenantID),
+		HitPolicies: e.engine.HitPolicies(),
+		Imports: func(namespace string) (*Definitions, error) {
+			imported, err := e.source.Namespace(ctx, model.TenantID, namespace)
+			if err != nil {
+				return nil, err
+			}
+			return imported.Definitions, nil
+		},
+	})
+	if len(Blocking(findings, nil)) > 0 {
+		return &ValidationFailedError{Findings: findings}
+	}
+
+	e.CompileValidated(model)
+	return nil
+}
+
+// CompileValidated parses the FEEL of a model into the cache without
+// validating it, for callers that have validated the model already, such
+// as a server that validates documents before deploying them
+func (e *Engine) CompileValidated(model *Model) {
+	e.engine.Compile(definition(model))
+}
+
+// Functions returns the registry of Go functions callable from FEEL
+func (e *Engine) Functions() *FunctionRegistry {
+	return e.engine.Functions()
+}
+
+// RegisterHitPolicy adds a hit policy that decision tables may name in
+// their hitPolicy attribute, or replaces a custom one registered before.
+// Built-in hit policies cannot be replaced. Register custom hit policies
+// before compiling models that use them
+func (e *Engine) RegisterHitPolicy(name string, strategy HitPolicyStrategy) error {
+	return e.engine.RegisterHitPolicy(name, strategy)
+}
+
+// HitPolicies returns the names of the registered custom hit policies
+func (e *Engine) HitPolicies() []string {
+	return e.engine.HitPolicies()
+}
+
+// Deployed tells the engine that a model version was added to its source
+// for the tenant, so the latest versions are resolved again
+func (e *Engine) Deployed(tenantID string) {
+	e.engine.DefinitionDeployed(tenantID)
+}
+
+// Deleted tells the engine that all versions of a model were removed from
+// its source
+func (e *Engine) Deleted(tenantID, key string) {
+	e.engine.DefinitionDeleted(tenantID, key)
+}
+
+// CacheStats returns statistics of the compiled-model cache
+func (e *Engine) CacheStats() CacheStats {
+	return e.engine.CacheStats()
+}
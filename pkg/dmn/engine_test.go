@@ -0,0 +1,254 @@
+package dmn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// literalModel is a model in namespace ns whose decision id evaluates expr
+func literalModel(ns, id, expr string) string {
+	return `<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="defs_` + id + `" name="` + id + `" namespace="` + ns + `">
+  <inputData id="in_x" name="x"><variable name="x"/></inputData>
+  <decision id="` + id + `" name="` + id + `">
+    <variable name="` + id + `"/>
+    <informationRequirement><requiredInput href="#in_x"/></informationRequirement>
+    <literalExpression><text>` + expr + `</text></literalExpression>
+  </decision>
+</definitions>`
+}
+
+// addModel parses a model and adds it to the source for the tenant
+func addModel(t *testing.T, source *MemorySource, tenantID, xml string) *Model {
+	t.Helper()
+	defs, err := Parse([]byte(xml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := NewModel(defs, "")
+	model.TenantID = tenantID
+	return source.Add(model)
+}
+
+// evaluate evaluates a decision with x set and returns its value
+func evaluate(e *Engine, tenantID, id string, version *int, x interface{}) (interface{}, error) {
+	res, err := e.Evaluate(context.Background(), &EvaluateRequest{
+		DecisionKey: id,
+		Version:     version,
+		TenantID:    tenantID,
+		Variables:   map[string]interface{}{"x": x},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Outputs[0][id], nil
+}
+
+func TestParseErrors(t *testing.T) {
+	valid := literalModel("http://example.org/a", "a", "x + 1")
+
+	if _, err := Parse([]byte("<definitions")); err == nil {
+		t.Error("malformed XML parsed")
+	}
+
+	unknown := strings.Replace(valid, `<variable name="a"/>`, `<variable name="a"/><colour/>`, 1)
+	if _, err := Parse([]byte(unknown)); err != nil {
+		t.Errorf("unknown element is not ignored: %v", err)
+	}
+	var schemaErr *SchemaError
+	if _, err := ParseWithOptions([]byte(unknown), ParseOptions{Strict: true}); !errors.As(err, &schemaErr) {
+		t.Errorf("strict parse error = %v, want a *SchemaError", err)
+	}
+
+	var limitErr *LimitError
+	if _, err := ParseWithOptions([]byte(valid), ParseOptions{Limits: Limits{MaxDecisions: 1}}); err != nil {
+		t.Errorf("model at the limit: %v", err)
+	}
+	if _, err := ParseWithOptions([]byte(valid), ParseOptions{Limits: Limits{MaxBytes: 10}}); !errors.As(err, &limitErr) {
+		t.Errorf("limit error = %v, want a *LimitError", err)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	ctx := context.Background()
+	source := NewMemorySource()
+	model := addModel(t, source, "", literalModel("http://example.org/a", "a", "double(x)"))
+	e := New(source)
+
+	// double is neither declared nor registered
+	err := e.Compile(ctx, model)
+	var failed *ValidationFailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("error = %v, want a *ValidationFailedError", err)
+	}
+	if blocking := Blocking(failed.Findings, nil); len(blocking) != 1 || blocking[0].Code != "unknown-name" {
+		t.Errorf("blocking findings = %+v", blocking)
+	}
+	if !strings.HasPrefix(err.Error(), "DMN validation failed: ") {
+		t.Errorf("error = %q", err)
+	}
+
+	err = e.Functions().Register("", "double", []Param{{Name: "n", Type: "number"}}, func(args []interface{}) (interface{}, error) {
+		return args[0].(float64) * 2, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Compile(ctx, model); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := evaluate(e, "", "a", nil, 21); err != nil || got != 42.0 {
+		t.Errorf("a = %v, %v, want 42", got, err)
+	}
+	if stats := e.CacheStats(); stats.Size != 1 || stats.Misses != 1 {
+		t.Errorf("cache stats = %+v, want the compiled model resolved once", stats)
+	}
+}
+
+func TestCompileValidated(t *testing.T) {
+	source := NewMemorySource()
+	model := addModel(t, source, "", literalModel("http://example.org/a", "a", "double(x)"))
+	e := New(source)
+
+	// Compiling skips validation, so the unknown function is only reported
+	// when the decision is evaluated
+	e.CompileValidated(model)
+	if stats := e.CacheStats(); stats.Size != 1 {
+		t.Errorf("cache stats = %+v, want the model cached", stats)
+	}
+	if _, err := evaluate(e, "", "a", nil, 21); err == nil || !strings.Contains(err.Error(), "double") {
+		t.Errorf("error = %v, want one naming double", err)
+	}
+}
+
+func TestMemorySourceVersions(t *testing.T) {
+	source := NewMemorySource()
+	e := New(source)
+
+	v1 := addModel(t, source, "", literalModel("http://example.org/a", "a", "x + 1"))
+	if v1.Version != 1 || v1.ID == "" {
+		t.Fatalf("first model = %+v", v1)
+	}
+	if got, _ := evaluate(e, "", "a", nil, 1); got != 2.0 {
+		t.Fatalf("a = %v, want 2", got)
+	}
+
+	v2 := addModel(t, source, "", literalModel("http://example.org/a", "a", "x + 2"))
+	if v2.Version != 2 {
+		t.Fatalf("second model has version %d", v2.Version)
+	}
+	e.Deployed("")
+
+	version := 1
+	for _, tt := range []struct {
+		version *int
+		want    float64
+	}{{nil, 3}, {&version, 2}} {
+		if got, err := evaluate(e, "", "a", tt.version, 1); err != nil || got != tt.want {
+			t.Errorf("a (version %v) = %v, %v, want %v", tt.version, got, err, tt.want)
+		}
+	}
+
+	if err := source.Remove("", "a"); err != nil {
+		t.Fatal(err)
+	}
+	e.Deleted("", "a")
+	if _, err := evaluate(e, "", "a", nil, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error after removal = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEngineTenants(t *testing.T) {
+	source := NewMemorySource()
+	addModel(t, source, "acme", literalModel("http://example.org/a", "a", "x + 1"))
+	addModel(t, source, "beta", literalModel("http://example.org/a", "a", "x + 2"))
+	e := New(source)
+
+	for tenantID, want := range map[string]interface{}{"acme": 2.0, "beta": 3.0} {
+		if got, err := evaluate(e, tenantID, "a", nil, 1); err != nil || got != want {
+			t.Errorf("%s: a = %v, %v, want %v", tenantID, got, err, want)
+		}
+	}
+	if _, err := evaluate(e, "", "a", nil, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("default tenant sees another tenant's model: %v", err)
+	}
+}
+
+// mapSource is a Source of one version per decision and namespace, as an
+// application may implement over its own storage
+type mapSource map[string]*Model
+
+func (s mapSource) Decision(ctx context.Context, tenantID, decisionID string, version int) (*Model, error) {
+	if m, ok := s["decision:"+decisionID]; ok && (version == 0 || version == m.Version) {
+		return m, nil
+	}
+	return nil, fmt.Errorf("decision %s: %w", decisionID, ErrNotFound)
+}
+
+func (s mapSource) Namespace(ctx context.Context, tenantID, namespace string) (*Model, error) {
+	if m, ok := s["namespace:"+namespace]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("namespace %s: %w", namespace, ErrNotFound)
+}
+
+func TestCustomSource(t *testing.T) {
+	source := mapSource{}
+	add := func(xml string) {
+		defs, err := Parse([]byte(xml))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := NewModel(defs, "")
+		m.ID, m.Version = m.Key, 1
+		source["decision:"+m.Key] = m
+		source["namespace:"+m.Namespace] = m
+	}
+	add(literalModel("http://example.org/lib", "base", "x * 10"))
+	add(`<definitions xmlns="https://www.omg.org/spec/DMN/20191111/MODEL/" id="main" name="Main" namespace="http://example.org/main">
+  <import namespace="http://example.org/lib" name="lib" importType="https://www.omg.org/spec/DMN/20191111/MODEL/"/>
+  <decision id="total" name="total">
+    <variable name="total"/>
+    <informationRequirement><requiredDecision href="http://example.org/lib#base"/></informationRequirement>
+    <literalExpression><text>lib.base + 1</text></literalExpression>
+  </decision>
+</definitions>`)
+
+	e := NewWithOptions(source, Options{})
+	if got, err := evaluate(e, "", "total", nil, 2); err != nil || got != 21.0 {
+		t.Errorf("total = %v, %v, want 21", got, err)
+	}
+
+	delete(source, "namespace:http://example.org/lib")
+	_, err := evaluate(e, "", "total", nil, 2)
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want a missing import", err)
+	}
+}
+
+func TestEvaluateBatch(t *testing.T) {
+	source := NewMemorySource()
+	addModel(t, source, "", literalModel("http://example.org/a", "a", "10 / x"))
+	e := New(source)
+
+	res, err := e.EvaluateBatch(context.Background(), &BatchRequest{
+		DecisionKey: "a",
+		Items:       []map[string]interface{}{{"x": 2}, {"x": "two"}, {"x": 5}},
+		Workers:     2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Version != 1 || len(res.Items) != 3 {
+		t.Fatalf("result = %+v", res)
+	}
+	for i, want := range []interface{}{5.0, nil, 2.0} {
+		item := res.Items[i]
+		if item.Index != i || item.Err != nil || item.Result.Outputs[0]["a"] != want {
+			t.Errorf("item %d = %+v, want %v", i, item, want)
+		}
+	}
+}
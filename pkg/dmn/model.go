@@ -0,0 +1,174 @@
+package dmn
+
+import (
+	"context"
+
+	"github.com/konstantin/dmn-engine-go/internal/storage"
+)
+
+// ErrNotFound is returned by a Source, and passed on by evaluation, when no
+// model contains the decision or namespace
+var ErrNotFound = storage.ErrNotFound
+
+// Model is a version of a DMN model as a Source holds it
+type Model struct {
+	ID          string // unique per version, reported as the definition ID of results
+	Key         string // identifies the model across versions
+	Name        string
+	Version     int    // starting at 1 for each key and tenant
+	Namespace   string // namespace other models import it by
+	TenantID    string
+	DecisionIDs []string // decisions and decision services the model is addressable by
+	Definitions *Definitions
+}
+
+// NewModel describes parsed definitions the way the server deploys them:
+// the key is the first decision, and every decision and decision service
+// is addressable by its ID. The name defaults to the model's name. ID and
+// Version are left to the Source
+func NewModel(defs *Definitions, name string) *Model {
+	key := ""
+	if len(defs.Decisions) > 0 {
+		key = defs.Decisions[0].ID
+	} else {
+		key = defs.ID
+	}
+	if name == "" {
+		name = defs.Name
+	}
+	if name == "" {
+		name = key
+	}
+
+	// Every decision and decision service is addressable by its own ID
+	decisionIDs := make([]string, 0, len(defs.Decisions)+len(defs.DecisionServices))
+	for _, d := range defs.Decisions {
+		decisionIDs = append(decisionIDs, d.ID)
+	}
+	for _, svc := range defs.DecisionServices {
+		decisionIDs = append(decisionIDs, svc.ID)
+	}
+
+	return &Model{
+		Key:         key,
+		Name:        name,
+		Namespace:   defs.Namespace,
+		DecisionIDs: decisionIDs,
+		Definitions: defs,
+	}
+}
+
+// Source provides the models an Engine evaluates. Implementations return
+// an error wrapping ErrNotFound for unknown decisions and namespaces, and
+// must not modify a model once it has been returned
+type Source interface {
+	// Decision returns the version of the model containing the decision or
+	// decision service, the latest one when version is 0
+	Decision(ctx context.Context, tenantID, decisionID string, version int) (*Model, error)
+
+	// Namespace returns the latest version of the model with the namespace;
+	// imports are resolved through it
+	Namespace(ctx context.Context, tenantID, namespace string) (*Model, error)
+}
+
+// MemorySource is a Source holding models in memory. It is safe for
+// concurrent use
+type MemorySource struct {
+	repo *storage.MemoryRepository
+}
+
+// NewMemorySource creates an empty MemorySource
+func NewMemorySource() *MemorySource {
+	return &MemorySource{repo: storage.NewMemoryRepository()}
+}
+
+// Add stores the model as the next version of its key for its tenant and
+// returns it with ID and Version set. Engines reading from the source must
+// be told with Engine.Deployed
+func (s *MemorySource) Add(model *Model) *Model {
+	def := definition(model)
+	s.repo.Deploy(context.Background(), def) // cannot fail in memory
+	return fromDefinition(def)
+}
+
+// Remove deletes all versions of a model. Engines reading from the source
+// must be told with Engine.Deleted
+func (s *MemorySource) Remove(tenantID, key string) error {
+	return s.repo.Delete(context.Background(), key, tenantID)
+}
+
+// Decision implements Source
+func (s *MemorySource) Decision(ctx context.Context, tenantID, decisionID string, version int) (*Model, error) {
+	var def *storage.Definition
+	var err error
+	if version > 0 {
+		def, err = s.repo.GetByDecisionIDAndVersion(ctx, decisionID, version, tenantID)
+	} else {
+		def, err = s.repo.GetByDecisionID(ctx, decisionID, tenantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromDefinition(def), nil
+}
+
+// Namespace implements Source
+func (s *MemorySource) Namespace(ctx context.Context, tenantID, namespace string) (*Model, error) {
+	def, err := s.repo.GetByNamespace(ctx, namespace, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return fromDefinition(def), nil
+}
+
+// definition converts a model to the stored form the engine works on
+func definition(m *Model) *storage.Definition {
+	return &storage.Definition{
+		ID:          m.ID,
+		Key:         m.Key,
+		Version:     m.Version,
+		Name:        m.Name,
+		ParsedModel: m.Definitions,
+		DecisionIDs: m.DecisionIDs,
+		Namespace:   m.Namespace,
+		TenantID:    m.TenantID,
+	}
+}
+
+func fromDefinition(def *storage.Definition) *Model {
+	return &Model{
+		ID:          def.ID,
+		Key:         def.Key,
+		Name:        def.Name,
+		Version:     def.Version,
+		Namespace:   def.Namespace,
+		TenantID:    def.TenantID,
+		DecisionIDs: def.DecisionIDs,
+		Definitions: def.ParsedModel,
+	}
+}
+
+// sourceAdapter presents a Source as the repository the internal engine
+// reads from
+type sourceAdapter struct {
+	source Source
+}
+
+func (a sourceAdapter) GetByDecisionID(ctx context.Context, decisionID string, tenantID string) (*storage.Definition, error) {
+	return a.convert(a.source.Decision(ctx, tenantID, decisionID, 0))
+}
+
+func (a sourceAdapter) GetByDecisionIDAndVersion(ctx context.Context, decisionID string, version int, tenantID string) (*storage.Definition, error) {
+	return a.convert(a.source.Decision(ctx, tenantID, decisionID, version))
+}
+
+func (a sourceAdapter) GetByNamespace(ctx context.Context, namespace string, tenantID string) (*storage.Definition, error) {
+	return a.convert(a.source.Namespace(ctx, tenantID, namespace))
+}
+
+func (a sourceAdapter) convert(m *Model, err error) (*storage.Definition, error) {
+	if err != nil {
+		return nil, err
+	}
+	return definition(m), nil
+}
@@ -0,0 +1,104 @@
+package dmn
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseFile(t *testing.T, path string) *Definitions {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse %s: %v", path, err)
+	}
+	return defs
+}
+
+func TestNewModel(t *testing.T) {
+	tests := []struct {
+		file     string
+		name     string
+		wantKey  string
+		wantName string
+		wantNS   string
+		wantIDs  []string
+	}{
+		{
+			file:     "../../testdata/dmn/simple_decision.dmn",
+			wantKey:  "eligibility",
+			wantName: "Simple Decision",
+			wantNS:   "http://example.org/dmn",
+			wantIDs:  []string{"eligibility"},
+		},
+		{
+			// The key stays the first decision; the service is addressable
+			// by its own ID
+			file:     "../../testdata/dmn/loan_approval.dmn",
+			name:     "Loans",
+			wantKey:  "debtRatio",
+			wantName: "Loans",
+			wantNS:   "http://example.org/dmn/loan",
+			wantIDs:  []string{"debtRatio", "riskLevel", "loanService"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			m := NewModel(parseFile(t, tt.file), tt.name)
+			if m.Key != tt.wantKey {
+				t.Errorf("key = %q, want %q", m.Key, tt.wantKey)
+			}
+			if m.Name != tt.wantName {
+				t.Errorf("name = %q, want %q", m.Name, tt.wantName)
+			}
+			if m.Namespace != tt.wantNS {
+				t.Errorf("namespace = %q, want %q", m.Namespace, tt.wantNS)
+			}
+			if !reflect.DeepEqual(m.DecisionIDs, tt.wantIDs) {
+				t.Errorf("decision IDs = %v, want %v", m.DecisionIDs, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestEvaluateServiceDefaulted(t *testing.T) {
+	ctx := context.Background()
+	source := NewMemorySource()
+	model := source.Add(NewModel(parseFile(t, "../../testdata/dmn/loan_approval.dmn"), ""))
+	engine := New(source)
+	if err := engine.Compile(ctx, model); err != nil {
+		t.Fatal(err)
+	}
+
+	// A debt ratio of 10 matches no rule of riskLevel, which defaults
+	res, err := engine.Evaluate(ctx, &EvaluateRequest{
+		DecisionKey: "loanService",
+		Variables:   map[string]interface{}{"income": 1000, "amount": 10000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Defaulted {
+		t.Error("result is not defaulted")
+	}
+	want := []map[string]interface{}{{"riskLevel": "HIGH"}}
+	if !reflect.DeepEqual(res.Outputs, want) {
+		t.Errorf("outputs = %v, want %v", res.Outputs, want)
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"matchedRules":[]`) {
+		t.Errorf("matchedRules is not an empty list: %s", data)
+	}
+}